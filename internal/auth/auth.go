@@ -0,0 +1,49 @@
+package auth
+
+const (
+	RoleReadOnly = "read-only"
+	RoleMember   = "member"
+	RoleAdmin    = "admin"
+)
+
+// roles ordered from least to most privileged
+var roleOrder = []string{RoleReadOnly, RoleMember, RoleAdmin}
+
+func rank(role string) int {
+	for i, r := range roleOrder {
+		if r == role {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func IsValidRole(role string) bool {
+	return rank(role) >= 0
+}
+
+func Allows(have, need string) bool {
+	// a role is allowed to do everything the roles below it can do
+
+	haveRank := rank(have)
+	return haveRank >= 0 && haveRank >= rank(need)
+}
+
+func Promote(role string) (string, bool) {
+	r := rank(role)
+	if r < 0 || r == len(roleOrder)-1 {
+		return role, false
+	}
+
+	return roleOrder[r+1], true
+}
+
+func Demote(role string) (string, bool) {
+	r := rank(role)
+	if r <= 0 {
+		return role, false
+	}
+
+	return roleOrder[r-1], true
+}
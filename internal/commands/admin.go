@@ -0,0 +1,153 @@
+package commands
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"blog-aggregator/internal/auth"
+	"blog-aggregator/internal/database"
+	"blog-aggregator/internal/state"
+)
+
+func HandlerPromoteUser(s *state.State, cmd Command, user database.User) error {
+	// usage: promote <username> [role]
+	// without a role the user moves up one level (read-only -> member -> admin)
+
+	target, err := getTargetUser(s, cmd)
+	if err != nil {
+		return err
+	}
+
+	newRole, ok := auth.Promote(target.Role)
+	if len(cmd.Args) > 1 {
+		newRole = cmd.Args[1]
+		if !auth.IsValidRole(newRole) {
+			return fmt.Errorf("unknown role %s, expected one of %s, %s, %s", newRole, auth.RoleReadOnly, auth.RoleMember, auth.RoleAdmin)
+		}
+		ok = auth.Allows(newRole, target.Role) && newRole != target.Role
+	}
+	if !ok {
+		return fmt.Errorf("user %s is already %s", target.Name, target.Role)
+	}
+
+	return setRole(s, target, newRole)
+}
+
+func HandlerDemoteUser(s *state.State, cmd Command, user database.User) error {
+	// usage: demote <username>
+	// moves the user down one level (admin -> member -> read-only)
+
+	target, err := getTargetUser(s, cmd)
+	if err != nil {
+		return err
+	}
+
+	newRole, ok := auth.Demote(target.Role)
+	if !ok {
+		return fmt.Errorf("user %s is already %s", target.Name, target.Role)
+	}
+
+	if target.Role == auth.RoleAdmin {
+		if err := ensureAnotherAdmin(s, target); err != nil {
+			return err
+		}
+	}
+
+	return setRole(s, target, newRole)
+}
+
+func HandlerDisableUser(s *state.State, cmd Command, user database.User) error {
+	target, err := getTargetUser(s, cmd)
+	if err != nil {
+		return err
+	}
+
+	if target.ID == user.ID {
+		return errors.New("you cannot disable yourself")
+	}
+
+	if target.DisabledAt.Valid {
+		return fmt.Errorf("user %s is already disabled", target.Name)
+	}
+
+	if target.Role == auth.RoleAdmin {
+		if err := ensureAnotherAdmin(s, target); err != nil {
+			return err
+		}
+	}
+
+	if err := s.DB.DisableUser(context.Background(), target.ID); err != nil {
+		return fmt.Errorf("failed to disable user %s: %w", target.Name, err)
+	}
+
+	fmt.Printf("user %s disabled\n", target.Name)
+
+	return nil
+}
+
+func HandlerEnableUser(s *state.State, cmd Command, user database.User) error {
+	target, err := getTargetUser(s, cmd)
+	if err != nil {
+		return err
+	}
+
+	if !target.DisabledAt.Valid {
+		return fmt.Errorf("user %s is not disabled", target.Name)
+	}
+
+	if err := s.DB.EnableUser(context.Background(), target.ID); err != nil {
+		return fmt.Errorf("failed to enable user %s: %w", target.Name, err)
+	}
+
+	fmt.Printf("user %s enabled\n", target.Name)
+
+	return nil
+}
+
+func getTargetUser(s *state.State, cmd Command) (database.User, error) {
+	if len(cmd.Args) == 0 {
+		return database.User{}, errors.New("username argument is required")
+	}
+
+	username := cmd.Args[0]
+
+	target, err := s.DB.GetUserByName(context.Background(), username)
+	if err == sql.ErrNoRows {
+		return database.User{}, fmt.Errorf("user %s does not exist", username)
+	} else if err != nil {
+		return database.User{}, fmt.Errorf("failed to get user %s: %w", username, err)
+	}
+
+	return target, nil
+}
+
+func ensureAnotherAdmin(s *state.State, target database.User) error {
+	// stops the last active admin from being demoted or disabled
+
+	admins, err := s.DB.CountActiveAdmins(context.Background())
+	if err != nil {
+		return fmt.Errorf("failed to count admins: %w", err)
+	}
+
+	if admins <= 1 && !target.DisabledAt.Valid {
+		return fmt.Errorf("user %s is the last active admin", target.Name)
+	}
+
+	return nil
+}
+
+func setRole(s *state.State, target database.User, role string) error {
+	_, err := s.DB.SetUserRole(context.Background(), database.SetUserRoleParams{
+		ID:   target.ID,
+		Role: role,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to set role for user %s: %w", target.Name, err)
+	}
+
+	fmt.Printf("user %s is now %s\n", target.Name, role)
+
+	return nil
+}
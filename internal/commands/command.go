@@ -4,11 +4,21 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"flag"
 	"fmt"
+	"os"
+	"os/signal"
 	"strconv"
+	"strings"
 	"time"
 
+	"blog-aggregator/internal/auth"
 	"blog-aggregator/internal/database"
+	"blog-aggregator/internal/fetch"
+	"blog-aggregator/internal/imagecache"
+	"blog-aggregator/internal/render"
+	"blog-aggregator/internal/scheduler"
+	"blog-aggregator/internal/search"
 	"blog-aggregator/internal/state"
 	"blog-aggregator/internal/utils"
 
@@ -31,11 +41,15 @@ func HandlerLogin(s *state.State, cmd Command) error {
 
 	username := cmd.Args[0]
 
-	_, err := s.DB.GetUserByName(context.Background(), username)
+	user, err := s.DB.GetUserByName(context.Background(), username)
 	if err != nil {
 		return fmt.Errorf("user %s does not exist", username)
 	}
 
+	if user.DisabledAt.Valid {
+		return fmt.Errorf("user %s is disabled", username)
+	}
+
 	if err := s.Config.SetUser(username); err != nil {
 		return fmt.Errorf("failed to set user %s: %w", username, err)
 	}
@@ -58,11 +72,23 @@ func HandlerRegister(s *state.State, cmd Command) error {
 	} else if err != sql.ErrNoRows {
 		return err
 	} else {
-		_, err := s.DB.CreateUser(context.Background(), database.CreateUserParams{
+		// the very first user to register becomes the admin
+		userCount, err := s.DB.CountUsers(context.Background())
+		if err != nil {
+			return err
+		}
+
+		role := auth.RoleMember
+		if userCount == 0 {
+			role = auth.RoleAdmin
+		}
+
+		_, err = s.DB.CreateUser(context.Background(), database.CreateUserParams{
 			ID:        uuid.NewString(),
 			CreatedAt: time.Now(),
 			UpdatedAt: time.Now(),
 			Name:      username,
+			Role:      role,
 		})
 
 		if err != nil {
@@ -79,32 +105,23 @@ func HandlerRegister(s *state.State, cmd Command) error {
 	return nil
 }
 
-func HandlerResetUsers(s *state.State, cmd Command) error {
-	err := s.DB.ResetUserTable(context.Background())
-
-	if err != nil {
-		return fmt.Errorf("failed to reset users table: %w", err)
-	}
-
-	fmt.Println("users table reset successfully")
-
-	return nil
-}
-
-func HandlerGetUsers(s *state.State, cmd Command) error{
+func HandlerGetUsers(s *state.State, cmd Command, currentUser database.User) error {
 	users, err := s.DB.GetUsers(context.Background())
 
 	if err != nil {
 		return fmt.Errorf("failed to get users: %w", err)
 	}
 
-	currentUser := s.Config.CurrentUserName
-
 	for _, user := range users {
-		if user.Name == currentUser {
-			fmt.Printf("* %s (current)\n", user.Name)
+		details := user.Role
+		if user.DisabledAt.Valid {
+			details += ", disabled"
+		}
+
+		if user.ID == currentUser.ID {
+			fmt.Printf("* %s (%s) (current)\n", user.Name, details)
 		} else {
-			fmt.Printf("* %s\n", user.Name)
+			fmt.Printf("* %s (%s)\n", user.Name, details)
 		}
 	}
 
@@ -112,29 +129,47 @@ func HandlerGetUsers(s *state.State, cmd Command) error{
 }
 
 func HandlerAgg(s *state.State, cmd Command) error {
-	if len(cmd.Args) < 1 {
-		return errors.New("duration argument is required (e.g., '1m', '30s')")
+	// usage: agg [duration]
+	// the duration is how often feeds are scraped; the other jobs keep their own schedules
+
+	scrapeSpec := fmt.Sprintf("@every %s", defaultScrapeInterval)
+	if spec, ok := s.Config.Jobs["scrape"]; ok {
+		scrapeSpec = spec
 	}
 
-	duration := cmd.Args[0]
-	timeBetweenRequests, err := time.ParseDuration(duration)
+	if len(cmd.Args) > 0 {
+		timeBetweenRequests, err := time.ParseDuration(cmd.Args[0])
+		if err != nil {
+			return fmt.Errorf("invalid duration format: %w", err)
+		}
+		scrapeSpec = fmt.Sprintf("@every %s", timeBetweenRequests)
+	}
+
+	fetchOptions, err := fetch.OptionsFromConfig(s.Config.HTTP)
 	if err != nil {
-		return fmt.Errorf("invalid duration format: %w", err)
+		return err
 	}
 
-	fmt.Printf("collecting feeds every %s\nPress Ctrl+C to stop gracefully...\n\n", timeBetweenRequests)
-	fmt.Println()
+	intervals, err := utils.FetchIntervalsFromConfig(s.Config.FetchInterval)
+	if err != nil {
+		return err
+	}
 
-	ticker := time.NewTicker(timeBetweenRequests)
-	defer ticker.Stop()
-	for ; ; <-ticker.C {
-		err := utils.ScrapeFeeds(context.Background(), s.DB)
-		if err != nil {
-			fmt.Printf("error fetching feeds: %v\n", err)
-			// continue the loop instead of returning
-		}
+	jobs := aggJobs(s, scrapeSpec, fetch.New(fetchOptions), intervals)
+	sched, err := scheduler.New(s.DB, jobs)
+	if err != nil {
+		return err
+	}
+
+	for _, job := range jobs {
+		fmt.Printf("%-12s %s\n", job.Name, job.Spec)
 	}
+	fmt.Printf("\nPress Ctrl+C to stop gracefully...\n\n")
 
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	return sched.Run(ctx)
 }
 
 func HandlerAddFeed(s *state.State, cmd Command, user database.User) error {
@@ -151,7 +186,7 @@ func HandlerAddFeed(s *state.State, cmd Command, user database.User) error {
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
 		Name:      feedName,
-		UserID:    user.ID,
+		UserID:    sql.NullString{String: user.ID, Valid: true},
 		Url:       feedURL,
 	})
 
@@ -160,11 +195,11 @@ func HandlerAddFeed(s *state.State, cmd Command, user database.User) error {
 	}
 
 	_, err = s.DB.CreateFeedFollow(context.Background(), database.CreateFeedFollowParams{
-		ID: uuid.NewString(),
+		ID:        uuid.NewString(),
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
-		UserID: user.ID,
-		FeedID: feed.ID,
+		UserID:    user.ID,
+		FeedID:    feed.ID,
 	})
 
 	if err != nil {
@@ -176,7 +211,7 @@ func HandlerAddFeed(s *state.State, cmd Command, user database.User) error {
 	return nil
 }
 
-func HandlerListFeeds(s *state.State, cmd Command) error {
+func HandlerListFeeds(s *state.State, cmd Command, user database.User) error {
 	feeds, err := s.DB.GetFeeds(context.Background())
 	if err != nil {
 		return fmt.Errorf("failed to get feeds: %w", err)
@@ -187,9 +222,21 @@ func HandlerListFeeds(s *state.State, cmd Command) error {
 		if feed.UserName.Valid {
 			username = feed.UserName.String
 		}
+		fmt.Println("Feed ID: ", shortID(feed.ID))
 		fmt.Println("Feed Name: ", feed.Name)
 		fmt.Println("Feed URL: ", feed.Url)
 		fmt.Println("Author: ", username)
+		if feed.DeadAt.Valid {
+			fmt.Println("Status: gone since", feed.DeadAt.Time.Format(time.DateOnly), "(HTTP 410)")
+		} else if feed.Paused {
+			fmt.Println("Status: paused")
+		}
+		if feed.NextFetchAt.Valid && !feed.Paused && !feed.DeadAt.Valid {
+			fmt.Println("Next fetch: ", feed.NextFetchAt.Time.Local().Format(time.DateTime))
+		}
+		for _, warning := range feed.ParseWarnings {
+			fmt.Println("Warning: ", warning)
+		}
 		fmt.Println("-----")
 	}
 
@@ -197,20 +244,31 @@ func HandlerListFeeds(s *state.State, cmd Command) error {
 }
 
 func HandlerFollowFeed(s *state.State, cmd Command, user database.User) error {
-	// takes a single url arg and creates a new feed follow record for the current user.
+	// usage: follow <feed> or follow --all <pattern>
+	// the feed can be given by URL, name, ID prefix or part of its name or URL
 
-	if len(cmd.Args) < 1 {
-		return errors.New("feed URL argument is required")
+	flags := flag.NewFlagSet(cmd.Name, flag.ContinueOnError)
+	all := flags.Bool("all", false, "follow every feed matching the pattern")
+
+	args, err := parseFlags(flags, cmd.Args)
+	if err != nil {
+		return err
 	}
 
-	feedURL := cmd.Args[0]
+	if len(args) < 1 {
+		return errors.New("feed argument is required")
+	}
+
+	if *all {
+		return followMatchingFeeds(s, user, args[0])
+	}
 
-	feed, err := s.DB.GetFeedByURL(context.Background(), feedURL)
+	feed, err := resolveFeed(s, args[0])
 	if err != nil {
-		return fmt.Errorf("failed to get feed by URL %s: %w", feedURL, err)
+		return err
 	}
 
-	_, err = s.DB.CreateFeedFollow(context.Background(), database.CreateFeedFollowParams{
+	followed, err := s.DB.FollowFeedIfNotFollowing(context.Background(), database.FollowFeedIfNotFollowingParams{
 		ID:        uuid.NewString(),
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
@@ -218,66 +276,253 @@ func HandlerFollowFeed(s *state.State, cmd Command, user database.User) error {
 		FeedID:    feed.ID,
 	})
 	if err != nil {
-		return fmt.Errorf("failed to create feed follow for user %s and feed %s: %w", user.Name, feedURL, err)
+		return fmt.Errorf("failed to create feed follow for user %s and feed %s: %w", user.Name, feed.Url, err)
+	}
+
+	if followed == 0 {
+		return fmt.Errorf("user %s is already following feed %s", user.Name, feed.Name)
 	}
 
-	fmt.Printf("user %s is now following feed %s\n", user.Name, feedURL)
+	fmt.Printf("user %s is now following feed %s\n", user.Name, feed.Name)
+
+	return nil
+}
+
+func followMatchingFeeds(s *state.State, user database.User, pattern string) error {
+	feeds, err := matchFeeds(s, pattern)
+	if err != nil {
+		return err
+	}
+
+	if len(feeds) == 0 {
+		return fmt.Errorf("no feed matches %s", pattern)
+	}
+
+	var followedCount int64
+	err = s.WithTx(context.Background(), func(q *database.Queries) error {
+		for _, feed := range feeds {
+			followed, err := q.FollowFeedIfNotFollowing(context.Background(), database.FollowFeedIfNotFollowingParams{
+				ID:        uuid.NewString(),
+				CreatedAt: time.Now(),
+				UpdatedAt: time.Now(),
+				UserID:    user.ID,
+				FeedID:    feed.ID,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to create feed follow for user %s and feed %s: %w", user.Name, feed.Url, err)
+			}
+
+			if followed > 0 {
+				fmt.Printf("following %s\n", feed.Name)
+			}
+			followedCount += followed
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("user %s is now following %d more feeds matching %s\n", user.Name, followedCount, pattern)
 
 	return nil
 }
 
 func HandlerListFollowedFeeds(s *state.State, cmd Command, user database.User) error {
+	// prints followed feeds grouped by folder, feeds in several folders show up under each
 
-	followedFeeds, err := s.DB.GetFeedFollowsForUser(context.Background(), user.ID)
+	followedFeeds, err := s.DB.GetFollowedFeedsByFolder(context.Background(), user.ID)
 	if err != nil {
 		return fmt.Errorf("failed to get followed feeds for user %s: %w", user.Name, err)
 	}
 
-	for _, feed := range followedFeeds {
-		fmt.Println(feed.FeedName)
+	currentFolder := ""
+	for i, feed := range followedFeeds {
+		folder := "(no folder)"
+		if feed.FolderName.Valid {
+			folder = feed.FolderName.String
+		}
+
+		if i == 0 || folder != currentFolder {
+			fmt.Println(folder)
+			currentFolder = folder
+		}
+
+		if feed.Priority != 0 {
+			fmt.Printf("  %s (%d unread, priority %d)\n", feed.FeedName, feed.UnreadCount, feed.Priority)
+		} else {
+			fmt.Printf("  %s (%d unread)\n", feed.FeedName, feed.UnreadCount)
+		}
+	}
+
+	searches, err := s.DB.GetSavedSearchesForUser(context.Background(), user.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get saved searches for user %s: %w", user.Name, err)
+	}
+
+	if len(searches) > 0 {
+		fmt.Println("saved searches")
+	}
+
+	for _, savedSearch := range searches {
+		filters := searchFilters(user, savedSearch)
+		filters.UnreadOnly = true
+
+		newMatches, err := s.DB.CountPostsForUser(context.Background(), filters)
+		if err != nil {
+			return fmt.Errorf("failed to count matches for saved search %s: %w", savedSearch.Name, err)
+		}
+
+		fmt.Printf("  %s (%d new)\n", savedSearch.Name, newMatches)
 	}
 
 	return nil
 }
 
 func HandlerUnfollowFeed(s *state.State, cmd Command, user database.User) error {
-	if len(cmd.Args) < 1 {
-		return errors.New("feed URL argument is required")
+	// usage: unfollow <feed> or unfollow --all <pattern>
+
+	flags := flag.NewFlagSet(cmd.Name, flag.ContinueOnError)
+	all := flags.Bool("all", false, "unfollow every feed matching the pattern")
+
+	args, err := parseFlags(flags, cmd.Args)
+	if err != nil {
+		return err
 	}
 
-	feedURL := cmd.Args[0]
+	if len(args) < 1 {
+		return errors.New("feed argument is required")
+	}
+
+	if *all {
+		return unfollowMatchingFeeds(s, user, args[0])
+	}
 
-	feed, err := s.DB.GetFeedByURL(context.Background(), feedURL)
+	feed, err := resolveFeed(s, args[0])
 	if err != nil {
-		return fmt.Errorf("failed to get feed by URL %s: %w", feedURL, err)
+		return err
 	}
 
-	err = s.DB.UnfollowFeed(context.Background(), database.UnfollowFeedParams{
+	unfollowed, err := s.DB.UnfollowFeed(context.Background(), database.UnfollowFeedParams{
 		UserID: user.ID,
 		FeedID: feed.ID,
 	})
 	if err != nil {
-		return fmt.Errorf("failed to unfollow feed %s for user %s: %w", feedURL, user.Name, err)
+		return fmt.Errorf("failed to unfollow feed %s for user %s: %w", feed.Url, user.Name, err)
+	}
+
+	if unfollowed == 0 {
+		return fmt.Errorf("user %s is not following feed %s", user.Name, feed.Name)
 	}
 
 	fmt.Println("feed unfollowed.")
 	return nil
 }
 
+func unfollowMatchingFeeds(s *state.State, user database.User, pattern string) error {
+	feeds, err := matchFeeds(s, pattern)
+	if err != nil {
+		return err
+	}
+
+	var unfollowedCount int64
+	err = s.WithTx(context.Background(), func(q *database.Queries) error {
+		for _, feed := range feeds {
+			unfollowed, err := q.UnfollowFeed(context.Background(), database.UnfollowFeedParams{
+				UserID: user.ID,
+				FeedID: feed.ID,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to unfollow feed %s for user %s: %w", feed.Url, user.Name, err)
+			}
+
+			if unfollowed > 0 {
+				fmt.Printf("unfollowed %s\n", feed.Name)
+			}
+			unfollowedCount += unfollowed
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("user %s unfollowed %d feeds matching %s\n", user.Name, unfollowedCount, pattern)
+
+	return nil
+}
+
 func HandlerBrowse(s *state.State, cmd Command, user database.User) error {
+	// usage: browse [limit] [--folder <name> | --search <saved search>] [--author <name>] [--category <name>]
+	//               [--unread] [--media] [--full [--width n]]
+	// posts that are shown get marked as read
+
+	flags := flag.NewFlagSet(cmd.Name, flag.ContinueOnError)
+	folder := flags.String("folder", "", "only show posts from feeds in this folder")
+	searchName := flags.String("search", "", "only show posts matching this saved search")
+	author := flags.String("author", "", "only show posts by an author whose name contains this")
+	category := flags.String("category", "", "only show posts in this category")
+	unreadOnly := flags.Bool("unread", false, "only show posts you haven't read yet")
+	media := flags.Bool("media", false, "only show posts with media attached, like podcast episodes, and list the media")
+	full := flags.Bool("full", false, "show the article, or the description if there is none")
+	width := flags.Int("width", render.TerminalWidth(), "wrap --full text to this many columns")
+
+	args, err := parseFlags(flags, cmd.Args)
+	if err != nil {
+		return err
+	}
+
 	limit := 2
 
-	if len(cmd.Args) > 0 {
-		parsedLimit, err := strconv.Atoi(cmd.Args[0])
+	if len(args) > 0 {
+		parsedLimit, err := strconv.Atoi(args[0])
 		if err != nil || parsedLimit <= 0 {
 			return fmt.Errorf("invalid limit value, please use a positive number")
 		}
 		limit = parsedLimit
 	}
 
+	if *folder != "" && *searchName != "" {
+		return errors.New("--folder and --search cannot be combined")
+	}
+
+	// a nil slice would be sent as NULL and match nothing
+	filters := database.CountPostsForUserParams{UserID: user.ID, Patterns: []string{}}
+
+	if *folder != "" {
+		folder, err := getFolder(s, user, *folder)
+		if err != nil {
+			return err
+		}
+		filters.FolderID = sql.NullString{String: folder.ID, Valid: true}
+	}
+
+	if *searchName != "" {
+		savedSearch, err := getSavedSearch(s, user, *searchName)
+		if err != nil {
+			return err
+		}
+		filters = searchFilters(user, savedSearch)
+	}
+
+	if *author != "" {
+		filters.Author = search.AuthorPattern(*author)
+	}
+	if *category != "" {
+		filters.Category = sql.NullString{String: *category, Valid: true}
+	}
+
 	posts, err := s.DB.GetPostsForUser(context.Background(), database.GetPostsForUserParams{
-		UserID: user.ID,
-		Limit:  int32(limit),
+		UserID:         filters.UserID,
+		FeedID:         filters.FeedID,
+		FolderID:       filters.FolderID,
+		PublishedAfter: filters.PublishedAfter,
+		Patterns:       filters.Patterns,
+		Author:         filters.Author,
+		Category:       filters.Category,
+		MediaOnly:      *media,
+		UnreadOnly:     *unreadOnly,
+		MaxPosts:       int32(limit),
 	})
 	if err != nil {
 		return fmt.Errorf("failed to get posts for user %s: %w", user.Name, err)
@@ -290,11 +535,102 @@ func HandlerBrowse(s *state.State, cmd Command, user database.User) error {
 			fmt.Printf("Published At: %s\n", post.PublishedAt.Time.Format(time.RFC1123))
 		}
 		fmt.Printf("Feed: %s\n", post.FeedName)
+		if len(post.Authors) > 0 {
+			fmt.Printf("Authors: %s\n", strings.Join(post.Authors, ", "))
+		}
+		if len(post.Categories) > 0 {
+			fmt.Printf("Categories: %s\n", strings.Join(post.Categories, ", "))
+		}
+		if post.CommentsUrl.Valid {
+			fmt.Printf("Comments: %s\n", post.CommentsUrl.String)
+		}
+		if post.ImageUrl.Valid {
+			cached := ""
+			if s.Config.ImageCacheDir != "" {
+				if path, ok := imagecache.Lookup(s.Config.ImageCacheDir, post.ImageUrl.String); ok {
+					cached = " (cached at " + path + ")"
+				}
+			}
+			fmt.Printf("Image: %s%s\n", post.ImageUrl.String, cached)
+		}
+		if post.StarredAt.Valid {
+			fmt.Println("Starred")
+		}
+
+		tags, err := s.DB.GetPostTags(context.Background(), database.GetPostTagsParams{
+			UserID: user.ID,
+			PostID: post.ID,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to get tags for post %s: %w", post.Url, err)
+		}
+		if len(tags) > 0 {
+			fmt.Printf("Tags: %s\n", strings.Join(tags, ", "))
+		}
+		if *media {
+			if err := printMedia(s, post); err != nil {
+				return err
+			}
+		}
+		if *full {
+			if post.ContentHtml.Valid {
+				fmt.Printf("\n%s\n\n", render.Text(post.ContentHtml.String, *width))
+			} else if post.Description.Valid {
+				fmt.Printf("\n%s\n\n", render.Text(post.Description.String, *width))
+			}
+		}
 		fmt.Println("-----")
+
+		err = s.DB.MarkPostRead(context.Background(), database.MarkPostReadParams{
+			UserID: user.ID,
+			PostID: post.ID,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to mark post %s as read: %w", post.Url, err)
+		}
 	}
 	return nil
 }
 
+func printMedia(s *state.State, post database.GetPostsForUserRow) error {
+	// the podcast details of a post and its enclosures
+
+	var episode []string
+	if post.Season.Valid {
+		episode = append(episode, fmt.Sprintf("season %d", post.Season.Int32))
+	}
+	if post.Episode.Valid {
+		episode = append(episode, fmt.Sprintf("episode %d", post.Episode.Int32))
+	}
+	if post.DurationSeconds.Valid {
+		episode = append(episode, (time.Duration(post.DurationSeconds.Int32) * time.Second).String())
+	}
+	if len(episode) > 0 {
+		fmt.Printf("Episode: %s\n", strings.Join(episode, ", "))
+	}
+	enclosures, err := s.DB.GetEnclosuresForPost(context.Background(), post.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get media of post %s: %w", post.Url, err)
+	}
+
+	for _, enclosure := range enclosures {
+		var details []string
+		if enclosure.MimeType.Valid {
+			details = append(details, enclosure.MimeType.String)
+		}
+		if enclosure.Length.Valid {
+			details = append(details, formatSize(enclosure.Length.Int64))
+		}
+
+		if len(details) > 0 {
+			fmt.Printf("Media: %s (%s)\n", enclosure.Url, strings.Join(details, ", "))
+		} else {
+			fmt.Printf("Media: %s\n", enclosure.Url)
+		}
+	}
+
+	return nil
+}
 
 func (c *Commands) Run(s *state.State, cmd Command) error {
 	// runs a given command with the proivided state IF it exists
@@ -304,9 +640,8 @@ func (c *Commands) Run(s *state.State, cmd Command) error {
 	}
 
 	return fmt.Errorf("Command %s not found", cmd.Name)
-	
-}
 
+}
 
 func (c *Commands) Register(name string, f func(*state.State, Command) error) {
 	// registers a new handler function for a command name
@@ -316,4 +651,4 @@ func (c *Commands) Register(name string, f func(*state.State, Command) error) {
 	}
 
 	c.handlers[name] = f
-}
\ No newline at end of file
+}
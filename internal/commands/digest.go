@@ -0,0 +1,329 @@
+package commands
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"blog-aggregator/internal/database"
+	"blog-aggregator/internal/digest"
+	"blog-aggregator/internal/state"
+)
+
+func HandlerDigest(s *state.State, cmd Command, user database.User) error {
+	// usage: digest <preview|send|schedule> [args...]
+
+	if len(cmd.Args) < 1 {
+		return errors.New("usage: digest <preview|send|schedule> [args...]")
+	}
+
+	subcommand := cmd.Args[0]
+	args := cmd.Args[1:]
+
+	switch subcommand {
+	case "preview":
+		return previewDigest(s, user, args)
+	case "send":
+		return sendDigest(s, user, args)
+	case "schedule":
+		return scheduleDigest(s, user, args)
+	}
+
+	return fmt.Errorf("unknown digest subcommand %s", subcommand)
+}
+
+func getDigestSchedule(s *state.State, user database.User) (database.DigestSchedule, bool, error) {
+	schedule, err := s.DB.GetDigestSchedule(context.Background(), user.ID)
+	if err == sql.ErrNoRows {
+		return database.DigestSchedule{}, false, nil
+	} else if err != nil {
+		return database.DigestSchedule{}, false, fmt.Errorf("failed to get digest schedule for user %s: %w", user.Name, err)
+	}
+
+	return schedule, true, nil
+}
+
+func digestOptions(s *state.State, user database.User, period, groupBy *string) error {
+	// unset --period and --group fall back to the user's schedule, then to daily by feed
+
+	schedule, ok, err := getDigestSchedule(s, user)
+	if err != nil {
+		return err
+	}
+
+	if *period == "" {
+		*period = digest.PeriodDaily
+		if ok {
+			*period = schedule.Period
+		}
+	}
+
+	if *groupBy == "" {
+		*groupBy = digest.GroupByFeed
+		if ok {
+			*groupBy = schedule.GroupBy
+		}
+	}
+
+	return digest.Validate(*period, *groupBy, digest.DefaultSendAt, digest.DefaultTimeZone)
+}
+
+func previewDigest(s *state.State, user database.User, args []string) error {
+	// usage: digest preview [--period daily|weekly] [--group feed|folder] [--html]
+
+	flags := flag.NewFlagSet("digest preview", flag.ContinueOnError)
+	period := flags.String("period", "", "how far back to look: "+strings.Join(digest.Periods, ", "))
+	groupBy := flags.String("group", "", "how to group posts: "+strings.Join(digest.GroupBys, ", "))
+	html := flags.Bool("html", false, "print the HTML version instead of plain text")
+
+	if _, err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	if err := digestOptions(s, user, period, groupBy); err != nil {
+		return err
+	}
+
+	d, err := digest.Load(context.Background(), s.DB, user, *period, *groupBy, time.Now())
+	if err != nil {
+		return err
+	}
+
+	render := digest.RenderText
+	if *html {
+		render = digest.RenderHTML
+	}
+
+	output, err := render(d)
+	if err != nil {
+		return err
+	}
+
+	fmt.Print(output)
+
+	return nil
+}
+
+func sendDigest(s *state.State, user database.User, args []string) error {
+	// usage: digest send [--period daily|weekly] [--group feed|folder] [--to <email>] [--out <file>]
+
+	flags := flag.NewFlagSet("digest send", flag.ContinueOnError)
+	period := flags.String("period", "", "how far back to look: "+strings.Join(digest.Periods, ", "))
+	groupBy := flags.String("group", "", "how to group posts: "+strings.Join(digest.GroupBys, ", "))
+	to := flags.String("to", "", "address to send to, defaults to the scheduled digest's address")
+	out := flags.String("out", "", "write the email to this file instead of sending it")
+
+	if _, err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	if err := digestOptions(s, user, period, groupBy); err != nil {
+		return err
+	}
+
+	if *to == "" {
+		schedule, ok, err := getDigestSchedule(s, user)
+		if err != nil {
+			return err
+		}
+		if ok {
+			*to = schedule.Email
+		}
+	}
+
+	if *to == "" && *out == "" {
+		return errors.New("--to is required when no digest is scheduled")
+	}
+
+	if *to != "" {
+		address, err := digest.ParseAddress(*to)
+		if err != nil {
+			return err
+		}
+		*to = address
+	}
+
+	d, err := digest.Load(context.Background(), s.DB, user, *period, *groupBy, time.Now())
+	if err != nil {
+		return err
+	}
+
+	from := "gator@localhost"
+	if s.Config.SMTP != nil && s.Config.SMTP.From != "" {
+		from = s.Config.SMTP.From
+	}
+
+	recipient := *to
+	if recipient == "" {
+		recipient = user.Name
+	}
+
+	message, err := digest.Message(from, recipient, d, time.Now())
+	if err != nil {
+		return err
+	}
+
+	if *out != "" {
+		if err := os.WriteFile(*out, message, 0644); err != nil {
+			return fmt.Errorf("failed to write digest to %s: %w", *out, err)
+		}
+
+		fmt.Printf("digest with %d posts written to %s\n", d.Total, *out)
+		return nil
+	}
+
+	if err := digest.Send(s.Config.SMTP, *to, message); err != nil {
+		return err
+	}
+
+	fmt.Printf("digest with %d posts sent to %s\n", d.Total, *to)
+
+	return nil
+}
+
+func scheduleDigest(s *state.State, user database.User, args []string) error {
+	// usage: digest schedule [--email <email>] [--period daily|weekly] [--day <weekday>] [--group feed|folder]
+	//                        [--at HH:MM] [--tz <zone>]
+	//    or: digest schedule --off
+	// without flags it shows the current schedule
+
+	flags := flag.NewFlagSet("digest schedule", flag.ContinueOnError)
+	email := flags.String("email", "", "address digests are sent to")
+	period := flags.String("period", "", "how often to send: "+strings.Join(digest.Periods, ", "))
+	groupBy := flags.String("group", "", "how to group posts: "+strings.Join(digest.GroupBys, ", "))
+	day := flags.String("day", "", "day of the week weekly digests are sent on, e.g. monday")
+	sendAt := flags.String("at", "", "local time to send at, e.g. 08:00")
+	timeZone := flags.String("tz", "", "time zone for --at, e.g. Europe/Berlin")
+	off := flags.Bool("off", false, "stop sending scheduled digests")
+
+	if _, err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	if *off {
+		deleted, err := s.DB.DeleteDigestSchedule(context.Background(), user.ID)
+		if err != nil {
+			return fmt.Errorf("failed to delete digest schedule for user %s: %w", user.Name, err)
+		}
+		if deleted == 0 {
+			return errors.New("no digest is scheduled")
+		}
+
+		fmt.Println("scheduled digests turned off")
+		return nil
+	}
+
+	schedule, ok, err := getDigestSchedule(s, user)
+	if err != nil {
+		return err
+	}
+
+	if flags.NFlag() == 0 {
+		if !ok {
+			fmt.Println("no digest is scheduled")
+			return nil
+		}
+
+		fmt.Println(describeDigestSchedule(schedule))
+		return nil
+	}
+
+	// flags that aren't given keep their current value
+	if !ok {
+		schedule = database.DigestSchedule{
+			Period:   digest.PeriodDaily,
+			GroupBy:  digest.GroupByFeed,
+			SendAt:   digest.DefaultSendAt,
+			TimeZone: digest.DefaultTimeZone,
+			Weekday:  int32(digest.DefaultWeekday),
+		}
+	}
+	if *email != "" {
+		address, err := digest.ParseAddress(*email)
+		if err != nil {
+			return err
+		}
+		schedule.Email = address
+	}
+	if *period != "" {
+		schedule.Period = *period
+	}
+	if *groupBy != "" {
+		schedule.GroupBy = *groupBy
+	}
+	if *day != "" {
+		weekday, err := digest.ParseWeekday(*day)
+		if err != nil {
+			return err
+		}
+		schedule.Weekday = int32(weekday)
+	}
+	if *sendAt != "" {
+		schedule.SendAt = *sendAt
+	}
+	if *timeZone != "" {
+		schedule.TimeZone = *timeZone
+	}
+
+	if schedule.Email == "" {
+		return errors.New("--email is required")
+	}
+
+	if err := digest.Validate(schedule.Period, schedule.GroupBy, schedule.SendAt, schedule.TimeZone); err != nil {
+		return err
+	}
+
+	schedule, err = s.DB.UpsertDigestSchedule(context.Background(), database.UpsertDigestScheduleParams{
+		UserID:    user.ID,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		Email:     schedule.Email,
+		Period:    schedule.Period,
+		GroupBy:   schedule.GroupBy,
+		SendAt:    schedule.SendAt,
+		TimeZone:  schedule.TimeZone,
+		Weekday:   schedule.Weekday,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save digest schedule for user %s: %w", user.Name, err)
+	}
+
+	fmt.Println(describeDigestSchedule(schedule))
+	fmt.Println("digests are sent while gator agg is running")
+
+	return nil
+}
+
+func describeDigestSchedule(schedule database.DigestSchedule) string {
+	sendAt := "at " + schedule.SendAt
+	if schedule.Period == digest.PeriodWeekly {
+		sendAt = "on " + time.Weekday(schedule.Weekday).String() + " " + sendAt
+	}
+
+	description := fmt.Sprintf("%s digest grouped by %s to %s %s %s",
+		schedule.Period,
+		schedule.GroupBy,
+		schedule.Email,
+		sendAt,
+		schedule.TimeZone,
+	)
+
+	if schedule.LastSentAt.Valid {
+		description += fmt.Sprintf(", last sent %s", schedule.LastSentAt.Time.Format(time.RFC1123))
+	}
+
+	if schedule.Failures > 0 {
+		description += fmt.Sprintf("\nfailed to send %d times, trying again %s: %s",
+			schedule.Failures,
+			schedule.RetryAt.Time.Format(time.RFC1123),
+			schedule.LastError.String,
+		)
+	}
+
+	return description
+}
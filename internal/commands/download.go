@@ -0,0 +1,294 @@
+package commands
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"blog-aggregator/internal/database"
+	"blog-aggregator/internal/podcast"
+	"blog-aggregator/internal/state"
+)
+
+func HandlerDownload(s *state.State, cmd Command, user database.User) error {
+	// usage: download [<feed>] [--latest n] [--dir <path>]
+	//    or: download keep <feed> <n|all>
+	//    or: download list
+
+	if len(cmd.Args) > 0 {
+		switch cmd.Args[0] {
+		case "keep":
+			return setKeepDownloads(s, user, cmd.Args[1:])
+		case "list":
+			return listDownloads(s, user)
+		}
+	}
+
+	flags := flag.NewFlagSet(cmd.Name, flag.ContinueOnError)
+	latest := flags.Int("latest", 0, "download the newest n episodes (default: the feed's keep setting, or 1)")
+	dir := flags.String("dir", "", "directory to download into (default: download_dir from the config, or ~/Downloads/gator)")
+
+	args, err := parseFlags(flags, cmd.Args)
+	if err != nil {
+		return err
+	}
+
+	if *latest < 0 {
+		return errors.New("--latest must be positive")
+	}
+
+	downloadDir, err := resolveDownloadDir(s, *dir)
+	if err != nil {
+		return err
+	}
+
+	// without a feed, every feed with a keep setting is downloaded
+	var follows []database.FeedFollow
+	names := map[string]string{}
+
+	if len(args) > 0 {
+		follow, feed, err := getFollow(s, user, args[0])
+		if err != nil {
+			return err
+		}
+		follows = append(follows, follow)
+		names[feed.ID] = feed.Name
+	} else {
+		rows, err := s.DB.GetFeedFollowsForUser(context.Background(), user.ID)
+		if err != nil {
+			return fmt.Errorf("failed to get feed follows for user %s: %w", user.Name, err)
+		}
+		for _, row := range rows {
+			if !row.KeepDownloads.Valid {
+				continue
+			}
+			follows = append(follows, database.FeedFollow{
+				FeedID:        row.FeedID,
+				DisplayName:   row.DisplayName,
+				KeepDownloads: row.KeepDownloads,
+			})
+			names[row.FeedID] = row.FeedName
+		}
+
+		if len(follows) == 0 {
+			fmt.Println("no feeds to download, set one up with 'download keep <feed> <n>' or name a feed")
+			return nil
+		}
+	}
+
+	// an interrupted download is kept and resumed by the next run
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	for _, follow := range follows {
+		feedName := names[follow.FeedID]
+		if follow.DisplayName.Valid {
+			feedName = follow.DisplayName.String
+		}
+
+		count := *latest
+		if count == 0 {
+			count = 1
+			if follow.KeepDownloads.Valid {
+				count = int(follow.KeepDownloads.Int32)
+			}
+		}
+
+		if err := downloadFeed(ctx, s, user, follow.FeedID, feedName, count, downloadDir); err != nil {
+			return err
+		}
+
+		if follow.KeepDownloads.Valid {
+			if err := pruneDownloads(s, user, follow.FeedID, follow.KeepDownloads.Int32); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func resolveDownloadDir(s *state.State, dir string) (string, error) {
+	if dir == "" {
+		dir = s.Config.DownloadDir
+	}
+
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to find home directory: %w", err)
+		}
+		return filepath.Join(home, "Downloads", "gator"), nil
+	}
+
+	return filepath.Abs(dir)
+}
+
+func downloadFeed(ctx context.Context, s *state.State, user database.User, feedID string, feedName string, count int, downloadDir string) error {
+	enclosures, err := s.DB.GetLatestEnclosuresForFeed(context.Background(), database.GetLatestEnclosuresForFeedParams{
+		UserID: user.ID,
+		FeedID: feedID,
+		Limit:  int32(count),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get enclosures of feed %s: %w", feedName, err)
+	}
+
+	if len(enclosures) == 0 {
+		fmt.Printf("%s: no episodes with media\n", feedName)
+		return nil
+	}
+
+	for _, enclosure := range enclosures {
+		if enclosure.DownloadPath.Valid {
+			if _, err := os.Stat(enclosure.DownloadPath.String); err == nil {
+				continue
+			}
+		}
+
+		dest := filepath.Join(
+			downloadDir,
+			podcast.DirName(feedName),
+			podcast.FileName(enclosure.PostTitle.String, enclosure.PublishedAt.Time, enclosure.Url, enclosure.MimeType.String),
+		)
+
+		fmt.Printf("%s: downloading %s\n", feedName, enclosure.PostTitle.String)
+
+		size, err := podcast.Download(ctx, podcast.DefaultClient, enclosure.Url, dest)
+		if err != nil {
+			if ctx.Err() != nil {
+				return errors.New("download interrupted, run it again to resume")
+			}
+			// one broken episode shouldn't stop the rest
+			fmt.Printf("Error downloading %s: %v\n", enclosure.Url, err)
+			continue
+		}
+
+		err = s.DB.CreateDownload(context.Background(), database.CreateDownloadParams{
+			UserID:      user.ID,
+			EnclosureID: enclosure.ID,
+			CreatedAt:   time.Now(),
+			Path:        dest,
+			Size:        size,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to record download of %s: %w", enclosure.Url, err)
+		}
+
+		fmt.Printf("  saved %s (%s)\n", dest, formatSize(size))
+	}
+
+	return nil
+}
+
+func pruneDownloads(s *state.State, user database.User, feedID string, keep int32) error {
+	// deletes the files of episodes older than the newest keep downloads
+
+	old, err := s.DB.GetDownloadsBeyondKeep(context.Background(), database.GetDownloadsBeyondKeepParams{
+		UserID: user.ID,
+		FeedID: feedID,
+		Offset: keep,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get old downloads: %w", err)
+	}
+
+	for _, download := range old {
+		if err := podcast.Remove(download.Path); err != nil {
+			return fmt.Errorf("failed to delete %s: %w", download.Path, err)
+		}
+
+		err := s.DB.DeleteDownload(context.Background(), database.DeleteDownloadParams{
+			UserID:      user.ID,
+			EnclosureID: download.EnclosureID,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to forget download %s: %w", download.Path, err)
+		}
+
+		fmt.Printf("  deleted %s\n", download.Path)
+	}
+
+	return nil
+}
+
+func setKeepDownloads(s *state.State, user database.User, args []string) error {
+	// usage: download keep <feed> <n|all>
+
+	if len(args) < 2 {
+		return errors.New("feed and number of episodes to keep (or all) are required")
+	}
+
+	keep := sql.NullInt32{}
+	if args[1] != "all" {
+		n, err := strconv.ParseInt(args[1], 10, 32)
+		if err != nil || n < 1 {
+			return fmt.Errorf("invalid number %s, please use a positive number or all", args[1])
+		}
+		keep = sql.NullInt32{Int32: int32(n), Valid: true}
+	}
+
+	_, feed, err := getFollow(s, user, args[0])
+	if err != nil {
+		return err
+	}
+
+	_, err = s.DB.SetFeedFollowKeepDownloads(context.Background(), database.SetFeedFollowKeepDownloadsParams{
+		UserID:        user.ID,
+		FeedID:        feed.ID,
+		KeepDownloads: keep,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to set download policy of feed %s: %w", feed.Name, err)
+	}
+
+	if keep.Valid {
+		fmt.Printf("'download' now fetches and keeps the newest %d episodes of %s\n", keep.Int32, feed.Name)
+	} else {
+		fmt.Printf("'download' no longer deletes old episodes of %s, and skips it unless named\n", feed.Name)
+	}
+
+	return nil
+}
+
+func listDownloads(s *state.State, user database.User) error {
+	downloads, err := s.DB.GetDownloadsForUser(context.Background(), user.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get downloads for user %s: %w", user.Name, err)
+	}
+
+	if len(downloads) == 0 {
+		fmt.Println("nothing downloaded yet")
+		return nil
+	}
+
+	feedName := ""
+	for _, download := range downloads {
+		if download.FeedName != feedName {
+			feedName = download.FeedName
+			fmt.Println(feedName)
+		}
+		fmt.Printf("  %s (%s)\n    %s\n", download.PostTitle.String, formatSize(download.Size), download.Path)
+	}
+
+	return nil
+}
+
+func formatSize(bytes int64) string {
+	switch {
+	case bytes >= 1<<30:
+		return fmt.Sprintf("%.1f GB", float64(bytes)/(1<<30))
+	case bytes >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(bytes)/(1<<20))
+	case bytes >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(bytes)/(1<<10))
+	}
+	return fmt.Sprintf("%d B", bytes)
+}
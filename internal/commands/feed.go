@@ -0,0 +1,355 @@
+package commands
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"blog-aggregator/internal/auth"
+	"blog-aggregator/internal/credentials"
+	"blog-aggregator/internal/database"
+	"blog-aggregator/internal/fetch"
+	"blog-aggregator/internal/state"
+)
+
+func HandlerFeed(s *state.State, cmd Command, user database.User) error {
+	// usage: feed <rename|seturl|delete|pause|resume|revive|fullcontent|auth> <feed> [args...]
+	// the feed can be given by URL, name, ID prefix or part of its name or URL
+
+	if len(cmd.Args) < 2 {
+		return errors.New("usage: feed <rename|seturl|delete|pause|resume|revive|fullcontent|auth> <feed> [args...]")
+	}
+
+	subcommand := cmd.Args[0]
+
+	// the feed always comes first, so its subcommand's flags go after it
+	if strings.HasPrefix(cmd.Args[1], "-") {
+		return fmt.Errorf("usage: feed %s <feed> [args...], flags go after the feed", subcommand)
+	}
+
+	feed, err := resolveFeed(s, cmd.Args[1])
+	if err != nil {
+		return err
+	}
+
+	if !canManageFeed(user, feed) {
+		return fmt.Errorf("only the owner of feed %s or an admin can %s it", feed.Name, subcommand)
+	}
+
+	args := cmd.Args[2:]
+
+	switch subcommand {
+	case "rename":
+		return renameFeed(s, feed, args)
+	case "seturl":
+		return setFeedURL(s, user, feed, args)
+	case "delete":
+		return deleteFeed(s, feed, args)
+	case "pause":
+		return setFeedPaused(s, feed, true)
+	case "resume":
+		return setFeedPaused(s, feed, false)
+	case "revive":
+		return reviveFeed(s, feed)
+	case "fullcontent":
+		return setFeedFullContent(s, feed, args)
+	case "auth":
+		return setFeedAuth(s, user, feed, args)
+	}
+
+	return fmt.Errorf("unknown feed subcommand %s", subcommand)
+}
+
+func canManageFeed(user database.User, feed database.Feed) bool {
+	// feeds can be changed by whoever owns them, or by any admin
+
+	if user.Role == auth.RoleAdmin {
+		return true
+	}
+
+	return feed.UserID.Valid && feed.UserID.String == user.ID
+}
+
+func renameFeed(s *state.State, feed database.Feed, args []string) error {
+	if len(args) < 1 {
+		return errors.New("new feed name is required")
+	}
+
+	renamed, err := s.DB.RenameFeed(context.Background(), database.RenameFeedParams{
+		ID:   feed.ID,
+		Name: args[0],
+	})
+	if err != nil {
+		return fmt.Errorf("failed to rename feed %s: %w", feed.Name, err)
+	}
+
+	fmt.Printf("feed %s renamed to %s\n", feed.Name, renamed.Name)
+
+	return nil
+}
+
+func setFeedURL(s *state.State, user database.User, feed database.Feed, args []string) error {
+	// posts and follows reference the feed by ID, so they stay attached
+
+	if len(args) < 1 {
+		return errors.New("new feed URL is required")
+	}
+
+	newURL := args[0]
+
+	existing, err := s.DB.GetFeedByURL(context.Background(), newURL)
+	if err == nil {
+		return fmt.Errorf("feed %s already uses URL %s", existing.Name, newURL)
+	} else if err != sql.ErrNoRows {
+		return fmt.Errorf("failed to get feed by URL %s: %w", newURL, err)
+	}
+
+	// credentials are sent wherever the feed points, so like the credentials
+	// themselves only the owner can send them to another host
+	if !fetch.SameHost(feed.Url, newURL) && (!feed.UserID.Valid || feed.UserID.String != user.ID) {
+		_, err := s.DB.GetFeedCredentials(context.Background(), feed.ID)
+		if err == nil {
+			return fmt.Errorf("feed %s has credentials, only its owner can point it at another host", feed.Name)
+		} else if err != sql.ErrNoRows {
+			return fmt.Errorf("failed to get credentials of feed %s: %w", feed.Name, err)
+		}
+	}
+
+	_, err = s.DB.SetFeedURL(context.Background(), database.SetFeedURLParams{
+		ID:  feed.ID,
+		Url: newURL,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to change URL of feed %s: %w", feed.Name, err)
+	}
+
+	fmt.Printf("feed %s now fetches from %s\n", feed.Name, newURL)
+
+	return nil
+}
+
+func deleteFeed(s *state.State, feed database.Feed, args []string) error {
+	// usage: feed delete <feed> [--yes]
+
+	flags := flag.NewFlagSet("feed delete", flag.ContinueOnError)
+	yes := flags.Bool("yes", false, "skip the confirmation prompt")
+
+	if _, err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	if !*yes {
+		prompt := fmt.Sprintf("deleting feed %s also deletes its posts and everyone's follows, type 'yes' to continue: ", feed.Name)
+		confirmed, err := confirm(prompt)
+		if err != nil {
+			return err
+		}
+		if !confirmed {
+			return errors.New("feed delete aborted")
+		}
+	}
+
+	if _, err := s.DB.DeleteFeed(context.Background(), feed.ID); err != nil {
+		return fmt.Errorf("failed to delete feed %s: %w", feed.Name, err)
+	}
+
+	fmt.Printf("feed %s deleted\n", feed.Name)
+
+	return nil
+}
+
+func reviveFeed(s *state.State, feed database.Feed) error {
+	// agg stops fetching feeds that answer 410 Gone; this gives one another try
+
+	if !feed.DeadAt.Valid {
+		return fmt.Errorf("feed %s is not dead", feed.Name)
+	}
+
+	if _, err := s.DB.ReviveFeed(context.Background(), feed.ID); err != nil {
+		return fmt.Errorf("failed to revive feed %s: %w", feed.Name, err)
+	}
+
+	fmt.Printf("feed %s revived, agg will fetch it again\n", feed.Name)
+
+	return nil
+}
+
+func setFeedPaused(s *state.State, feed database.Feed, paused bool) error {
+	if feed.Paused == paused {
+		if paused {
+			return fmt.Errorf("feed %s is already paused", feed.Name)
+		}
+		return fmt.Errorf("feed %s is not paused", feed.Name)
+	}
+
+	_, err := s.DB.SetFeedPaused(context.Background(), database.SetFeedPausedParams{
+		ID:     feed.ID,
+		Paused: paused,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update feed %s: %w", feed.Name, err)
+	}
+
+	if paused {
+		fmt.Printf("feed %s paused, agg will skip it until it is resumed\n", feed.Name)
+	} else {
+		fmt.Printf("feed %s resumed\n", feed.Name)
+	}
+
+	return nil
+}
+
+func setFeedFullContent(s *state.State, feed database.Feed, args []string) error {
+	// usage: feed fullcontent <feed> <on|off>
+
+	if len(args) < 1 || (args[0] != "on" && args[0] != "off") {
+		return errors.New("usage: feed fullcontent <feed> <on|off>")
+	}
+
+	enabled := args[0] == "on"
+
+	_, err := s.DB.SetFeedFetchFullContent(context.Background(), database.SetFeedFetchFullContentParams{
+		ID:               feed.ID,
+		FetchFullContent: enabled,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update feed %s: %w", feed.Name, err)
+	}
+
+	if enabled {
+		fmt.Printf("agg will download and extract the full article of new posts from %s\n", feed.Name)
+	} else {
+		fmt.Printf("agg will only keep the feed's own description of new posts from %s\n", feed.Name)
+	}
+
+	return nil
+}
+
+func setFeedAuth(s *state.State, user database.User, feed database.Feed, args []string) error {
+	// usage: feed auth <feed> basic <username> [password]
+	//    or: feed auth <feed> bearer [token]
+	//    or: feed auth <feed> header <name> [value]
+	//    or: feed auth <feed> <show|clear>
+	// secrets left out are asked for, so they don't end up in the shell history
+
+	// unlike other feed settings, not even admins can see or change credentials
+	if !feed.UserID.Valid || feed.UserID.String != user.ID {
+		return fmt.Errorf("only the owner of feed %s can manage its credentials", feed.Name)
+	}
+
+	if len(args) < 1 {
+		return errors.New("usage: feed auth <feed> <basic|bearer|header|show|clear> [args...]")
+	}
+
+	if args[0] == "clear" {
+		deleted, err := s.DB.DeleteFeedCredentials(context.Background(), feed.ID)
+		if err != nil {
+			return fmt.Errorf("failed to delete credentials of feed %s: %w", feed.Name, err)
+		}
+		if deleted == 0 {
+			return fmt.Errorf("feed %s has no credentials", feed.Name)
+		}
+		fmt.Printf("credentials of feed %s deleted\n", feed.Name)
+		return nil
+	}
+
+	key, err := credentials.Key(s.Config)
+	if err != nil {
+		return err
+	}
+
+	var creds credentials.Credentials
+	data, err := s.DB.GetFeedCredentials(context.Background(), feed.ID)
+	if err == nil {
+		if creds, err = credentials.Decrypt(key, feed.ID, data); err != nil {
+			return err
+		}
+	} else if err != sql.ErrNoRows {
+		return fmt.Errorf("failed to get credentials of feed %s: %w", feed.Name, err)
+	}
+
+	switch args[0] {
+	case "show":
+		if creds.Empty() {
+			fmt.Printf("feed %s has no credentials\n", feed.Name)
+			return nil
+		}
+		for _, line := range creds.Describe() {
+			fmt.Println(line)
+		}
+		return nil
+
+	case "basic":
+		if len(args) < 2 {
+			return errors.New("username is required")
+		}
+		password, err := secretArg(args, 2, "Password: ")
+		if err != nil {
+			return err
+		}
+		// only one of basic and bearer can be used, both go in the Authorization header
+		creds.Basic, creds.BearerToken = &credentials.Basic{Username: args[1], Password: password}, ""
+
+	case "bearer":
+		token, err := secretArg(args, 1, "Token: ")
+		if err != nil {
+			return err
+		}
+		if token == "" {
+			return errors.New("token is required")
+		}
+		creds.Basic, creds.BearerToken = nil, token
+
+	case "header":
+		if len(args) < 2 {
+			return errors.New("header name is required")
+		}
+		name := http.CanonicalHeaderKey(args[1])
+		value, err := secretArg(args, 2, name+": ")
+		if err != nil {
+			return err
+		}
+		if creds.Headers == nil {
+			creds.Headers = map[string]string{}
+		}
+		// an empty value removes the header
+		if value == "" {
+			delete(creds.Headers, name)
+		} else {
+			creds.Headers[name] = value
+		}
+
+	default:
+		return fmt.Errorf("unknown auth setting %s", args[0])
+	}
+
+	encrypted, err := credentials.Encrypt(key, feed.ID, creds)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt credentials: %w", err)
+	}
+
+	err = s.DB.SetFeedCredentials(context.Background(), database.SetFeedCredentialsParams{
+		FeedID:    feed.ID,
+		CreatedAt: time.Now(),
+		Data:      encrypted,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save credentials of feed %s: %w", feed.Name, err)
+	}
+
+	fmt.Printf("credentials of feed %s saved, agg sends them when fetching it\n", feed.Name)
+
+	return nil
+}
+
+func secretArg(args []string, index int, prompt string) (string, error) {
+	if len(args) > index {
+		return args[index], nil
+	}
+	return askSecret(prompt)
+}
@@ -0,0 +1,23 @@
+package commands
+
+import "flag"
+
+func parseFlags(flags *flag.FlagSet, args []string) ([]string, error) {
+	// like flags.Parse, but flags may also come after positional arguments,
+	// so "browse 10 --unread" works as well as "browse --unread 10"
+
+	var positional []string
+	for {
+		if err := flags.Parse(args); err != nil {
+			return nil, err
+		}
+
+		args = flags.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
@@ -0,0 +1,220 @@
+package commands
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"blog-aggregator/internal/database"
+	"blog-aggregator/internal/state"
+
+	"github.com/google/uuid"
+)
+
+func HandlerFolder(s *state.State, cmd Command, user database.User) error {
+	// usage: folder <list|create|rename|delete|assign|unassign> [args...]
+
+	if len(cmd.Args) < 1 {
+		return errors.New("usage: folder <list|create|rename|delete|assign|unassign> [args...]")
+	}
+
+	subcommand := cmd.Args[0]
+	args := cmd.Args[1:]
+
+	switch subcommand {
+	case "list":
+		return listFolders(s, user)
+	case "create":
+		return createFolder(s, user, args)
+	case "rename":
+		return renameFolder(s, user, args)
+	case "delete":
+		return deleteFolder(s, user, args)
+	case "assign":
+		return assignFolder(s, user, args)
+	case "unassign":
+		return unassignFolder(s, user, args)
+	}
+
+	return fmt.Errorf("unknown folder subcommand %s", subcommand)
+}
+
+func getFolder(s *state.State, user database.User, name string) (database.Folder, error) {
+	folder, err := s.DB.GetFolderByName(context.Background(), database.GetFolderByNameParams{
+		UserID: user.ID,
+		Name:   name,
+	})
+	if err == sql.ErrNoRows {
+		return database.Folder{}, fmt.Errorf("folder %s does not exist", name)
+	} else if err != nil {
+		return database.Folder{}, fmt.Errorf("failed to get folder %s: %w", name, err)
+	}
+
+	return folder, nil
+}
+
+func getFollow(s *state.State, user database.User, feedRef string) (database.FeedFollow, database.Feed, error) {
+	feed, err := resolveFeed(s, feedRef)
+	if err != nil {
+		return database.FeedFollow{}, database.Feed{}, err
+	}
+
+	follow, err := s.DB.GetFeedFollow(context.Background(), database.GetFeedFollowParams{
+		UserID: user.ID,
+		FeedID: feed.ID,
+	})
+	if err == sql.ErrNoRows {
+		return database.FeedFollow{}, database.Feed{}, fmt.Errorf("user %s is not following feed %s", user.Name, feed.Name)
+	} else if err != nil {
+		return database.FeedFollow{}, database.Feed{}, fmt.Errorf("failed to get follow of feed %s: %w", feed.Name, err)
+	}
+
+	return follow, feed, nil
+}
+
+func listFolders(s *state.State, user database.User) error {
+	folders, err := s.DB.GetFoldersForUser(context.Background(), user.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get folders for user %s: %w", user.Name, err)
+	}
+
+	for _, folder := range folders {
+		fmt.Println(folder.Name)
+	}
+
+	return nil
+}
+
+func createFolder(s *state.State, user database.User, args []string) error {
+	if len(args) < 1 {
+		return errors.New("folder name is required")
+	}
+
+	name := args[0]
+
+	_, err := s.DB.CreateFolder(context.Background(), database.CreateFolderParams{
+		ID:        uuid.NewString(),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		UserID:    user.ID,
+		Name:      name,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create folder %s: %w", name, err)
+	}
+
+	fmt.Printf("folder %s created\n", name)
+
+	return nil
+}
+
+func renameFolder(s *state.State, user database.User, args []string) error {
+	if len(args) < 2 {
+		return errors.New("current and new folder names are required")
+	}
+
+	folder, err := getFolder(s, user, args[0])
+	if err != nil {
+		return err
+	}
+
+	_, err = s.DB.RenameFolder(context.Background(), database.RenameFolderParams{
+		ID:   folder.ID,
+		Name: args[1],
+	})
+	if err != nil {
+		return fmt.Errorf("failed to rename folder %s: %w", folder.Name, err)
+	}
+
+	fmt.Printf("folder %s renamed to %s\n", folder.Name, args[1])
+
+	return nil
+}
+
+func deleteFolder(s *state.State, user database.User, args []string) error {
+	// only the folder goes away, the feeds in it stay followed
+
+	if len(args) < 1 {
+		return errors.New("folder name is required")
+	}
+
+	folder, err := getFolder(s, user, args[0])
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.DB.DeleteFolder(context.Background(), folder.ID); err != nil {
+		return fmt.Errorf("failed to delete folder %s: %w", folder.Name, err)
+	}
+
+	fmt.Printf("folder %s deleted\n", folder.Name)
+
+	return nil
+}
+
+func assignFolder(s *state.State, user database.User, args []string) error {
+	if len(args) < 2 {
+		return errors.New("feed and folder name are required")
+	}
+
+	follow, feed, err := getFollow(s, user, args[0])
+	if err != nil {
+		return err
+	}
+
+	folder, err := getFolder(s, user, args[1])
+	if err != nil {
+		return err
+	}
+
+	assigned, err := s.DB.AssignFeedFollowToFolder(context.Background(), database.AssignFeedFollowToFolderParams{
+		FeedFollowID: follow.ID,
+		FolderID:     folder.ID,
+		CreatedAt:    time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to add feed %s to folder %s: %w", feed.Name, folder.Name, err)
+	}
+
+	if assigned == 0 {
+		return fmt.Errorf("feed %s is already in folder %s", feed.Name, folder.Name)
+	}
+
+	fmt.Printf("feed %s added to folder %s\n", feed.Name, folder.Name)
+
+	return nil
+}
+
+func unassignFolder(s *state.State, user database.User, args []string) error {
+	if len(args) < 2 {
+		return errors.New("feed and folder name are required")
+	}
+
+	follow, feed, err := getFollow(s, user, args[0])
+	if err != nil {
+		return err
+	}
+
+	folder, err := getFolder(s, user, args[1])
+	if err != nil {
+		return err
+	}
+
+	removed, err := s.DB.UnassignFeedFollowFromFolder(context.Background(), database.UnassignFeedFollowFromFolderParams{
+		FeedFollowID: follow.ID,
+		FolderID:     folder.ID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to remove feed %s from folder %s: %w", feed.Name, folder.Name, err)
+	}
+
+	if removed == 0 {
+		return fmt.Errorf("feed %s is not in folder %s", feed.Name, folder.Name)
+	}
+
+	fmt.Printf("feed %s removed from folder %s\n", feed.Name, folder.Name)
+
+	return nil
+}
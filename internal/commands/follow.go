@@ -0,0 +1,79 @@
+package commands
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"blog-aggregator/internal/database"
+	"blog-aggregator/internal/state"
+)
+
+func HandlerLabelFeed(s *state.State, cmd Command, user database.User) error {
+	// usage: label <feed> [display name]
+	// the label only changes how the feed shows up for the current user,
+	// leaving out the name goes back to the feed's own name
+
+	if len(cmd.Args) < 1 {
+		return errors.New("feed argument is required")
+	}
+
+	_, feed, err := getFollow(s, user, cmd.Args[0])
+	if err != nil {
+		return err
+	}
+
+	label := strings.TrimSpace(strings.Join(cmd.Args[1:], " "))
+
+	_, err = s.DB.SetFeedFollowDisplayName(context.Background(), database.SetFeedFollowDisplayNameParams{
+		UserID:      user.ID,
+		FeedID:      feed.ID,
+		DisplayName: sql.NullString{String: label, Valid: label != ""},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to label feed %s: %w", feed.Name, err)
+	}
+
+	if label == "" {
+		fmt.Printf("feed %s shows up under its own name again\n", feed.Name)
+	} else {
+		fmt.Printf("feed %s now shows up as %s\n", feed.Name, label)
+	}
+
+	return nil
+}
+
+func HandlerSetFeedPriority(s *state.State, cmd Command, user database.User) error {
+	// usage: priority <feed> <number>
+	// higher priority feeds are listed first by following and browse
+
+	if len(cmd.Args) < 2 {
+		return errors.New("feed and priority arguments are required")
+	}
+
+	priority, err := strconv.ParseInt(cmd.Args[1], 10, 32)
+	if err != nil {
+		return fmt.Errorf("invalid priority %s, please use a whole number", cmd.Args[1])
+	}
+
+	_, feed, err := getFollow(s, user, cmd.Args[0])
+	if err != nil {
+		return err
+	}
+
+	_, err = s.DB.SetFeedFollowPriority(context.Background(), database.SetFeedFollowPriorityParams{
+		UserID:   user.ID,
+		FeedID:   feed.ID,
+		Priority: int32(priority),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to set priority of feed %s: %w", feed.Name, err)
+	}
+
+	fmt.Printf("feed %s now has priority %d\n", feed.Name, priority)
+
+	return nil
+}
@@ -0,0 +1,45 @@
+package commands
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"time"
+
+	"blog-aggregator/internal/database"
+	"blog-aggregator/internal/state"
+	"blog-aggregator/internal/utils"
+)
+
+func HandlerCollectFeeds(s *state.State, cmd Command, user database.User) error {
+	// usage: gc [--grace <duration>] [--dry-run]
+
+	flags := flag.NewFlagSet(cmd.Name, flag.ContinueOnError)
+	grace := flags.Duration("grace", utils.DefaultFeedGracePeriod, "how long a feed may go without followers before it is deleted")
+	dryRun := flags.Bool("dry-run", false, "print how many feeds would be deleted without changing anything")
+
+	if err := flags.Parse(cmd.Args); err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+
+	if *dryRun {
+		count, err := s.DB.CountCollectableFeeds(ctx, time.Now().Add(-*grace))
+		if err != nil {
+			return fmt.Errorf("failed to count unfollowed feeds: %w", err)
+		}
+
+		fmt.Printf("%d feeds without followers for more than %s would be deleted\n", count, *grace)
+		return nil
+	}
+
+	deleted, err := utils.CollectUnfollowedFeeds(ctx, s.DB, *grace)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("deleted %d feeds without followers for more than %s\n", deleted, *grace)
+
+	return nil
+}
@@ -0,0 +1,222 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"blog-aggregator/internal/database"
+	"blog-aggregator/internal/digest"
+	"blog-aggregator/internal/fetch"
+	"blog-aggregator/internal/imagecache"
+	"blog-aggregator/internal/scheduler"
+	"blog-aggregator/internal/state"
+	"blog-aggregator/internal/utils"
+	"blog-aggregator/internal/webhook"
+)
+
+const (
+	defaultScrapeInterval = time.Minute
+	defaultJobHistory     = 20
+	imageCacheWindow      = 2 * time.Hour
+)
+
+func aggJobs(s *state.State, scrapeSpec string, client *fetch.Client, intervals utils.FetchIntervals) []scheduler.Job {
+	// the jobs agg runs; schedules can be overridden in the config's "jobs" section
+
+	jobs := []scheduler.Job{
+		{
+			Name: "scrape",
+			Spec: scrapeSpec,
+			Run: func(ctx context.Context) (string, error) {
+				fetched, failed, err := utils.ScrapeFeeds(ctx, s, client, intervals)
+				if fetched == 0 && failed == 0 {
+					if err != nil {
+						return "", err
+					}
+					return "no feeds to fetch", nil
+				}
+				return fmt.Sprintf("%d fetched, %d failed", fetched, failed), err
+			},
+		},
+		{
+			Name: "webhooks",
+			Spec: "@every 1m",
+			Run: func(ctx context.Context) (string, error) {
+				delivered, failed, err := webhook.DeliverPending(ctx, s.DB, webhook.DefaultClient)
+				if delivered == 0 && failed == 0 {
+					return "", err
+				}
+				return fmt.Sprintf("%d delivered, %d failed", delivered, failed), err
+			},
+		},
+		{
+			Name: "digests",
+			Spec: "* * * * *",
+			Run: func(ctx context.Context) (string, error) {
+				sent, err := digest.SendScheduled(ctx, s.DB, s.Config.SMTP, time.Now())
+				if sent == 0 {
+					return "", err
+				}
+				return fmt.Sprintf("%d sent", sent), err
+			},
+		},
+		{
+			Name: "prune",
+			Spec: "0 3 * * *",
+			Run: func(ctx context.Context) (string, error) {
+				feeds, err := utils.CollectUnfollowedFeeds(ctx, s.DB, utils.DefaultFeedGracePeriod)
+				if err != nil {
+					return "", err
+				}
+
+				posts, err := utils.PrunePosts(ctx, s.DB, utils.PostRetention(s.Config))
+				if err != nil {
+					return "", err
+				}
+
+				jobRuns, deliveries, err := utils.PruneHistory(ctx, s.DB, utils.DefaultHistoryRetention)
+				if err != nil {
+					return "", err
+				}
+
+				return fmt.Sprintf("deleted %d unfollowed feeds, %d posts, %d job runs, %d webhook deliveries", feeds, posts, jobRuns, deliveries), nil
+			},
+		},
+		{
+			Name: "maintenance",
+			Spec: "0 4 * * 0",
+			Run: func(ctx context.Context) (string, error) {
+				// refreshes the planner statistics the indexes are chosen by
+				if err := s.DB.AnalyzeTables(ctx); err != nil {
+					return "", fmt.Errorf("failed to analyze tables: %w", err)
+				}
+				return "analyzed tables", nil
+			},
+		},
+	}
+
+	if s.Config.ImageCacheDir != "" {
+		jobs = append(jobs, scheduler.Job{
+			Name: "images",
+			Spec: "@every 10m",
+			Run: func(ctx context.Context) (string, error) {
+				// failed images are retried for a while, then given up on
+				since := time.Now().Add(-imageCacheWindow)
+				cached, failed, err := imagecache.CacheRecent(ctx, s.DB, imagecache.DefaultClient, s.Config.ImageCacheDir, since)
+				if cached == 0 && failed == 0 {
+					return "", err
+				}
+				return fmt.Sprintf("%d cached, %d failed", cached, failed), err
+			},
+		})
+	}
+
+	for i, job := range jobs {
+		if spec, ok := s.Config.Jobs[job.Name]; ok && job.Name != "scrape" {
+			jobs[i].Spec = spec
+		}
+	}
+
+	return jobs
+}
+
+func HandlerJobs(s *state.State, cmd Command, user database.User) error {
+	// usage: jobs
+	//    or: jobs history <job> [--limit n]
+
+	if len(cmd.Args) > 0 && cmd.Args[0] == "history" {
+		return jobHistory(s, cmd.Args[1:])
+	}
+
+	if len(cmd.Args) > 0 {
+		return fmt.Errorf("unknown jobs subcommand %s", cmd.Args[0])
+	}
+
+	jobs, err := s.DB.GetJobs(context.Background())
+	if err != nil {
+		return fmt.Errorf("failed to get jobs: %w", err)
+	}
+
+	if len(jobs) == 0 {
+		fmt.Println("no jobs yet, they are set up the first time gator agg runs")
+		return nil
+	}
+
+	for _, job := range jobs {
+		fmt.Printf("* %s (%s)\n", job.Name, job.Schedule)
+
+		if job.LastRunAt.Valid {
+			lastRun := fmt.Sprintf("%s, %s", job.LastRunAt.Time.Format(time.RFC1123), job.LastStatus.String)
+			if job.LastSummary.Valid {
+				lastRun += ": " + job.LastSummary.String
+			}
+			fmt.Printf("  Last run:   %s\n", lastRun)
+		} else {
+			fmt.Printf("  Last run:   never\n")
+		}
+
+		if job.NextRunAt.Valid {
+			fmt.Printf("  Next run:   %s\n", job.NextRunAt.Time.Format(time.RFC1123))
+		}
+
+		if job.LastErrorAt.Valid {
+			fmt.Printf("  Last error: %s, %s\n", job.LastErrorAt.Time.Format(time.RFC1123), job.LastError.String)
+		}
+	}
+
+	return nil
+}
+
+func jobHistory(s *state.State, args []string) error {
+	flags := flag.NewFlagSet("jobs history", flag.ContinueOnError)
+	limit := flags.Int("limit", defaultJobHistory, "number of runs to show")
+
+	args, err := parseFlags(flags, args)
+	if err != nil {
+		return err
+	}
+
+	if len(args) < 1 {
+		return errors.New("job name argument is required")
+	}
+
+	if *limit < 1 {
+		return errors.New("--limit must be positive")
+	}
+
+	runs, err := s.DB.GetJobRuns(context.Background(), database.GetJobRunsParams{
+		JobName: args[0],
+		Limit:   int32(*limit),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get runs of job %s: %w", args[0], err)
+	}
+
+	if len(runs) == 0 {
+		fmt.Printf("job %s has not run yet\n", args[0])
+		return nil
+	}
+
+	for _, run := range runs {
+		duration := "-"
+		if run.FinishedAt.Valid {
+			duration = run.FinishedAt.Time.Sub(run.StartedAt).Round(time.Millisecond).String()
+		}
+
+		details := []string{run.Status}
+		if run.Summary.Valid {
+			details = append(details, run.Summary.String)
+		}
+		if run.Error.Valid {
+			details = append(details, run.Error.String)
+		}
+
+		fmt.Printf("%s (%s) %s\n", run.StartedAt.Format(time.RFC3339), duration, strings.Join(details, ": "))
+	}
+
+	return nil
+}
@@ -0,0 +1,73 @@
+package commands
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// shared so buffered input isn't lost between prompts
+var stdin = bufio.NewReader(os.Stdin)
+
+func ask(prompt string) (string, error) {
+	fmt.Print(prompt)
+
+	answer, err := stdin.ReadString('\n')
+	if err != nil && answer == "" {
+		return "", fmt.Errorf("failed to read answer: %w", err)
+	}
+
+	return strings.TrimSpace(answer), nil
+}
+
+func askSecret(prompt string) (string, error) {
+	// like ask, without echoing what is typed when stdin is a terminal
+
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return ask(prompt)
+	}
+
+	fmt.Print(prompt)
+	secret, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Println()
+	if err != nil {
+		return "", fmt.Errorf("failed to read answer: %w", err)
+	}
+
+	return strings.TrimSpace(string(secret)), nil
+}
+
+func confirm(prompt string) (bool, error) {
+	// asks the user a yes/no question on stdin, anything other than "yes" is a no
+
+	answer, err := ask(prompt)
+	if err != nil {
+		return false, err
+	}
+
+	return strings.EqualFold(answer, "yes"), nil
+}
+
+func choose(prompt string, options []string) (int, error) {
+	// prints a numbered list and returns the index of the option the user picked
+
+	for i, option := range options {
+		fmt.Printf("  %d) %s\n", i+1, option)
+	}
+
+	answer, err := ask(fmt.Sprintf("%s [1-%d]: ", prompt, len(options)))
+	if err != nil {
+		return 0, err
+	}
+
+	picked, err := strconv.Atoi(answer)
+	if err != nil || picked < 1 || picked > len(options) {
+		return 0, fmt.Errorf("invalid choice %q", answer)
+	}
+
+	return picked - 1, nil
+}
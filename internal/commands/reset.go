@@ -0,0 +1,215 @@
+package commands
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+
+	"blog-aggregator/internal/database"
+	"blog-aggregator/internal/state"
+)
+
+type tableCount struct {
+	Table string
+	Rows  int64
+}
+
+type resetPlan struct {
+	Scope  string
+	Counts []tableCount
+	Notes  []string
+	Run    func(ctx context.Context) (int64, error)
+}
+
+func HandlerReset(s *state.State, cmd Command, user database.User) error {
+	// usage: reset [--user <name> | --posts | --fetch-state] [--dry-run] [--yes]
+
+	flags := flag.NewFlagSet(cmd.Name, flag.ContinueOnError)
+	targetUser := flags.String("user", "", "only delete this user and the feeds they own")
+	postsOnly := flags.Bool("posts", false, "only delete posts")
+	fetchState := flags.Bool("fetch-state", false, "only clear feed fetch timestamps")
+	dryRun := flags.Bool("dry-run", false, "print affected row counts without changing anything")
+	yes := flags.Bool("yes", false, "skip the confirmation prompt")
+
+	if err := flags.Parse(cmd.Args); err != nil {
+		return err
+	}
+
+	scopes := 0
+	for _, set := range []bool{*targetUser != "", *postsOnly, *fetchState} {
+		if set {
+			scopes++
+		}
+	}
+	if scopes > 1 {
+		return errors.New("--user, --posts and --fetch-state cannot be combined")
+	}
+
+	ctx := context.Background()
+
+	var plan resetPlan
+	var err error
+	switch {
+	case *targetUser != "":
+		plan, err = planUserReset(ctx, s, *targetUser)
+	case *postsOnly:
+		plan, err = planPostsReset(ctx, s)
+	case *fetchState:
+		plan, err = planFetchStateReset(ctx, s)
+	default:
+		plan, err = planFullReset(ctx, s)
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("reset scope: %s\n", plan.Scope)
+	for _, count := range plan.Counts {
+		fmt.Printf("  %s: %d\n", count.Table, count.Rows)
+	}
+	for _, note := range plan.Notes {
+		fmt.Println(note)
+	}
+
+	if *dryRun {
+		fmt.Println("dry run, nothing was changed")
+		return nil
+	}
+
+	if !*yes {
+		confirmed, err := confirm("type 'yes' to continue: ")
+		if err != nil {
+			return err
+		}
+		if !confirmed {
+			return errors.New("reset aborted")
+		}
+	}
+
+	affected, err := plan.Run(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to reset %s: %w", plan.Scope, err)
+	}
+
+	fmt.Printf("reset %s successfully (%d rows)\n", plan.Scope, affected)
+
+	return nil
+}
+
+func planFullReset(ctx context.Context, s *state.State) (resetPlan, error) {
+	users, err := s.DB.CountUsers(ctx)
+	if err != nil {
+		return resetPlan{}, fmt.Errorf("failed to count users: %w", err)
+	}
+	feeds, err := s.DB.CountFeeds(ctx)
+	if err != nil {
+		return resetPlan{}, fmt.Errorf("failed to count feeds: %w", err)
+	}
+	follows, err := s.DB.CountFeedFollows(ctx)
+	if err != nil {
+		return resetPlan{}, fmt.Errorf("failed to count feed follows: %w", err)
+	}
+	posts, err := s.DB.CountPosts(ctx)
+	if err != nil {
+		return resetPlan{}, fmt.Errorf("failed to count posts: %w", err)
+	}
+
+	return resetPlan{
+		Scope: "all users",
+		Counts: []tableCount{
+			{Table: "users", Rows: users},
+			{Table: "feeds", Rows: feeds},
+			{Table: "feed_follows", Rows: follows},
+			{Table: "posts", Rows: posts},
+		},
+		Run: func(ctx context.Context) (int64, error) {
+			// feeds no longer cascade from users, so they have to go explicitly
+			var affected int64
+			err := s.WithTx(ctx, func(q *database.Queries) error {
+				deletedFeeds, err := q.DeleteAllFeeds(ctx)
+				if err != nil {
+					return err
+				}
+				deletedUsers, err := q.ResetUserTable(ctx)
+				if err != nil {
+					return err
+				}
+				affected = deletedFeeds + deletedUsers
+				return nil
+			})
+			return affected, err
+		},
+	}, nil
+}
+
+func planUserReset(ctx context.Context, s *state.State, username string) (resetPlan, error) {
+	target, err := s.DB.GetUserByName(ctx, username)
+	if err == sql.ErrNoRows {
+		return resetPlan{}, fmt.Errorf("user %s does not exist", username)
+	} else if err != nil {
+		return resetPlan{}, fmt.Errorf("failed to get user %s: %w", username, err)
+	}
+
+	feeds, err := s.DB.CountFeedsForUser(ctx, sql.NullString{String: target.ID, Valid: true})
+	if err != nil {
+		return resetPlan{}, fmt.Errorf("failed to count feeds for user %s: %w", username, err)
+	}
+	follows, err := s.DB.CountFeedFollowsForUser(ctx, target.ID)
+	if err != nil {
+		return resetPlan{}, fmt.Errorf("failed to count feed follows for user %s: %w", username, err)
+	}
+
+	return resetPlan{
+		Scope: "user " + username,
+		Counts: []tableCount{
+			{Table: "users", Rows: 1},
+			{Table: "feed_follows", Rows: follows},
+		},
+		Notes: []string{
+			fmt.Sprintf("%d feeds owned by %s are kept and handed to their earliest remaining follower", feeds, username),
+		},
+		Run: func(ctx context.Context) (int64, error) {
+			var affected int64
+			err := s.WithTx(ctx, func(q *database.Queries) error {
+				deleted, err := q.DeleteUser(ctx, target.ID)
+				if err != nil {
+					return err
+				}
+				if _, err := q.AdoptOrphanedFeeds(ctx); err != nil {
+					return err
+				}
+				affected = deleted
+				return nil
+			})
+			return affected, err
+		},
+	}, nil
+}
+
+func planPostsReset(ctx context.Context, s *state.State) (resetPlan, error) {
+	posts, err := s.DB.CountPosts(ctx)
+	if err != nil {
+		return resetPlan{}, fmt.Errorf("failed to count posts: %w", err)
+	}
+
+	return resetPlan{
+		Scope:  "posts",
+		Counts: []tableCount{{Table: "posts", Rows: posts}},
+		Run:    s.DB.DeletePosts,
+	}, nil
+}
+
+func planFetchStateReset(ctx context.Context, s *state.State) (resetPlan, error) {
+	feeds, err := s.DB.CountFetchedFeeds(ctx)
+	if err != nil {
+		return resetPlan{}, fmt.Errorf("failed to count fetched feeds: %w", err)
+	}
+
+	return resetPlan{
+		Scope:  "fetch state",
+		Counts: []tableCount{{Table: "feeds", Rows: feeds}},
+		Run:    s.DB.ResetFeedFetchState,
+	}, nil
+}
@@ -0,0 +1,206 @@
+package commands
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"blog-aggregator/internal/database"
+	"blog-aggregator/internal/search"
+	"blog-aggregator/internal/state"
+)
+
+const (
+	shortIDLength     = 8
+	minIDPrefixLength = 4
+	// the most fuzzy matches offered to pick from
+	maxFuzzyMatches = 10
+)
+
+func shortID(id string) string {
+	if len(id) <= shortIDLength {
+		return id
+	}
+
+	return id[:shortIDLength]
+}
+
+func findFeeds(ctx context.Context, s *state.State, ref string) ([]database.Feed, error) {
+	// tries the most specific ways of naming a feed first: exact URL, exact name,
+	// ID prefix, a substring of the name or URL, then a fuzzy match of either
+
+	feed, err := s.DB.GetFeedByURL(ctx, ref)
+	if err == nil {
+		return []database.Feed{feed}, nil
+	} else if err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to get feed by URL %s: %w", ref, err)
+	}
+
+	feeds, err := s.DB.GetFeedsByName(ctx, ref)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get feeds named %s: %w", ref, err)
+	}
+	if len(feeds) > 0 {
+		return feeds, nil
+	}
+
+	if len(ref) >= minIDPrefixLength {
+		feeds, err = s.DB.GetFeedsByIDPrefix(ctx, search.EscapeLike(strings.ToLower(ref)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to get feeds by ID %s: %w", ref, err)
+		}
+		if len(feeds) > 0 {
+			return feeds, nil
+		}
+	}
+
+	feeds, err = s.DB.SearchFeeds(ctx, search.Pattern(ref))
+	if err != nil {
+		return nil, fmt.Errorf("failed to search feeds for %s: %w", ref, err)
+	}
+	if len(feeds) > 0 {
+		return feeds, nil
+	}
+
+	return fuzzyFindFeeds(ctx, s, ref)
+}
+
+func fuzzyFindFeeds(ctx context.Context, s *state.State, ref string) ([]database.Feed, error) {
+	// the feeds whose name or URL contains ref's characters in order, best matches first
+
+	feeds, err := s.DB.ListFeeds(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get feeds: %w", err)
+	}
+
+	type match struct {
+		feed  database.Feed
+		score int
+	}
+
+	var matches []match
+	for _, feed := range feeds {
+		nameScore, nameOK := fuzzyScore(ref, feed.Name)
+		// the scheme would only get in the way of matching the host
+		_, host, found := strings.Cut(feed.Url, "://")
+		if !found {
+			host = feed.Url
+		}
+		urlScore, urlOK := fuzzyScore(ref, strings.TrimPrefix(host, "www."))
+		switch {
+		case nameOK && (!urlOK || nameScore >= urlScore):
+			matches = append(matches, match{feed, nameScore})
+		case urlOK:
+			matches = append(matches, match{feed, urlScore})
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].score > matches[j].score
+	})
+	if len(matches) > maxFuzzyMatches {
+		matches = matches[:maxFuzzyMatches]
+	}
+
+	found := make([]database.Feed, len(matches))
+	for i, m := range matches {
+		found[i] = m.feed
+	}
+
+	return found, nil
+}
+
+func fuzzyScore(pattern string, text string) (int, bool) {
+	// whether text contains the characters of pattern in order, ignoring case and
+	// spaces, and how well: matches at the start of a word and runs of consecutive
+	// matches count extra, characters skipped in between count against it
+
+	runes := []rune(strings.ToLower(text))
+	score, next, last := 0, 0, -1
+
+	for _, c := range strings.ToLower(pattern) {
+		if unicode.IsSpace(c) {
+			continue
+		}
+
+		for next < len(runes) && runes[next] != c {
+			next++
+		}
+		if next == len(runes) {
+			return 0, false
+		}
+
+		score++
+		if next == 0 || !unicode.IsLetter(runes[next-1]) && !unicode.IsDigit(runes[next-1]) {
+			score += 3
+		}
+		if last >= 0 {
+			if next == last+1 {
+				score += 2
+			} else {
+				score -= min(next-last-1, 5)
+			}
+		}
+
+		last = next
+		next++
+	}
+
+	return score, last >= 0
+}
+
+func resolveFeed(s *state.State, ref string) (database.Feed, error) {
+	// turns whatever the user typed into exactly one feed, asking them to pick when it's ambiguous
+
+	feeds, err := findFeeds(context.Background(), s, ref)
+	if err != nil {
+		return database.Feed{}, err
+	}
+
+	switch len(feeds) {
+	case 0:
+		return database.Feed{}, fmt.Errorf("no feed matches %s", ref)
+	case 1:
+		return feeds[0], nil
+	}
+
+	fmt.Printf("%s matches %d feeds:\n", ref, len(feeds))
+	options := make([]string, len(feeds))
+	for i, feed := range feeds {
+		options[i] = describeFeed(feed)
+	}
+
+	picked, err := choose("pick a feed", options)
+	if err != nil {
+		return database.Feed{}, err
+	}
+
+	return feeds[picked], nil
+}
+
+func matchFeeds(s *state.State, pattern string) ([]database.Feed, error) {
+	// used by bulk operations: matches feeds whose name or URL contains pattern,
+	// where "*" works as a wildcard and everything else is taken literally
+
+	if strings.TrimSpace(pattern) == "" {
+		return nil, errors.New("pattern can't be empty, use * to match every feed")
+	}
+
+	// search.Pattern leaves * alone, so it can become LIKE's wildcard afterwards
+	query := strings.ReplaceAll(search.Pattern(pattern), "*", "%")
+
+	feeds, err := s.DB.SearchFeeds(context.Background(), query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search feeds for %s: %w", pattern, err)
+	}
+
+	return feeds, nil
+}
+
+func describeFeed(feed database.Feed) string {
+	return fmt.Sprintf("%s (%s) [%s]", feed.Name, feed.Url, shortID(feed.ID))
+}
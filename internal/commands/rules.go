@@ -0,0 +1,350 @@
+package commands
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"blog-aggregator/internal/database"
+	"blog-aggregator/internal/rules"
+	"blog-aggregator/internal/state"
+
+	"github.com/google/uuid"
+)
+
+const maxRuleTestMatches = 20
+
+type ruleOptions struct {
+	feed      *string
+	field     *string
+	matchType *string
+	action    *string
+	tag       *string
+}
+
+func newRuleFlags(name string) (*flag.FlagSet, ruleOptions) {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	options := ruleOptions{
+		feed:      flags.String("feed", "", "only match posts from this feed"),
+		field:     flags.String("field", rules.FieldTitle, "post field to match: "+strings.Join(rules.Fields, ", ")),
+		matchType: flags.String("match", rules.MatchSubstring, "how to match the pattern: "+strings.Join(rules.MatchTypes, ", ")),
+		action:    flags.String("action", rules.ActionHide, "what to do with matching posts: "+strings.Join(rules.Actions, ", ")),
+		tag:       flags.String("tag", "", "tag to add when the action is tag"),
+	}
+
+	return flags, options
+}
+
+func HandlerRules(s *state.State, cmd Command, user database.User) error {
+	// usage: rules <list|add|delete|test|apply> [args...]
+
+	if len(cmd.Args) < 1 {
+		return errors.New("usage: rules <list|add|delete|test|apply> [args...]")
+	}
+
+	subcommand := cmd.Args[0]
+	args := cmd.Args[1:]
+
+	switch subcommand {
+	case "list":
+		return listRules(s, user)
+	case "add":
+		return addRule(s, user, args)
+	case "delete":
+		return deleteRule(s, user, args)
+	case "test":
+		return testRule(s, user, args)
+	case "apply":
+		return applyRules(s, user, args)
+	}
+
+	return fmt.Errorf("unknown rules subcommand %s", subcommand)
+}
+
+func buildRule(s *state.State, user database.User, options ruleOptions, pattern string) (database.Rule, error) {
+	// turns command line options into an unsaved rule, checking everything the database would reject
+
+	if err := rules.Validate(*options.field, *options.matchType, *options.action, *options.tag); err != nil {
+		return database.Rule{}, err
+	}
+
+	rule := database.Rule{
+		ID:        uuid.NewString(),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		UserID:    user.ID,
+		Field:     *options.field,
+		MatchType: *options.matchType,
+		Pattern:   pattern,
+		Action:    *options.action,
+		Tag:       sql.NullString{String: *options.tag, Valid: *options.action == rules.ActionTag},
+	}
+
+	if *options.feed != "" {
+		feed, err := resolveFeed(s, *options.feed)
+		if err != nil {
+			return database.Rule{}, err
+		}
+		rule.FeedID = sql.NullString{String: feed.ID, Valid: true}
+	}
+
+	if _, err := rules.Compile(rule); err != nil {
+		return database.Rule{}, err
+	}
+
+	return rule, nil
+}
+
+func describeRule(rule database.Rule, feedName sql.NullString) string {
+	scope := "all feeds"
+	if feedName.Valid {
+		scope = feedName.String
+	}
+
+	action := rule.Action
+	if rule.Action == rules.ActionTag {
+		action = fmt.Sprintf("%s %q", rule.Action, rule.Tag.String)
+	}
+
+	return fmt.Sprintf("[%s] %s %s %q in %s -> %s", shortID(rule.ID), rule.Field, rule.MatchType, rule.Pattern, scope, action)
+}
+
+func findRule(s *state.State, user database.User, ref string) (database.GetRulesForUserRow, error) {
+	userRules, err := s.DB.GetRulesForUser(context.Background(), user.ID)
+	if err != nil {
+		return database.GetRulesForUserRow{}, fmt.Errorf("failed to get rules for user %s: %w", user.Name, err)
+	}
+
+	var found []database.GetRulesForUserRow
+	for _, rule := range userRules {
+		if strings.HasPrefix(rule.ID, strings.ToLower(ref)) {
+			found = append(found, rule)
+		}
+	}
+
+	switch len(found) {
+	case 0:
+		return database.GetRulesForUserRow{}, fmt.Errorf("no rule with ID %s", ref)
+	case 1:
+		return found[0], nil
+	}
+
+	return database.GetRulesForUserRow{}, fmt.Errorf("%d rules start with %s, use more of the ID", len(found), ref)
+}
+
+func ruleFromRow(row database.GetRulesForUserRow) database.Rule {
+	return database.Rule{
+		ID:        row.ID,
+		CreatedAt: row.CreatedAt,
+		UpdatedAt: row.UpdatedAt,
+		UserID:    row.UserID,
+		FeedID:    row.FeedID,
+		Field:     row.Field,
+		MatchType: row.MatchType,
+		Pattern:   row.Pattern,
+		Action:    row.Action,
+		Tag:       row.Tag,
+	}
+}
+
+func listRules(s *state.State, user database.User) error {
+	userRules, err := s.DB.GetRulesForUser(context.Background(), user.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get rules for user %s: %w", user.Name, err)
+	}
+
+	for _, rule := range userRules {
+		fmt.Println(describeRule(ruleFromRow(rule), rule.FeedName))
+	}
+
+	return nil
+}
+
+func addRule(s *state.State, user database.User, args []string) error {
+	// usage: rules add [--feed <feed>] [--field title] [--match substring] [--action hide] [--tag <tag>] <pattern>
+
+	flags, options := newRuleFlags("rules add")
+	args, err := parseFlags(flags, args)
+	if err != nil {
+		return err
+	}
+
+	if len(args) < 1 {
+		return errors.New("pattern argument is required")
+	}
+
+	rule, err := buildRule(s, user, options, args[0])
+	if err != nil {
+		return err
+	}
+
+	created, err := s.DB.CreateRule(context.Background(), database.CreateRuleParams{
+		ID:        rule.ID,
+		CreatedAt: rule.CreatedAt,
+		UpdatedAt: rule.UpdatedAt,
+		UserID:    rule.UserID,
+		FeedID:    rule.FeedID,
+		Field:     rule.Field,
+		MatchType: rule.MatchType,
+		Pattern:   rule.Pattern,
+		Action:    rule.Action,
+		Tag:       rule.Tag,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create rule: %w", err)
+	}
+
+	fmt.Printf("rule %s added, new posts will be checked against it. run 'rules apply %s' to apply it to existing posts\n", shortID(created.ID), shortID(created.ID))
+
+	return nil
+}
+
+func deleteRule(s *state.State, user database.User, args []string) error {
+	if len(args) < 1 {
+		return errors.New("rule ID argument is required")
+	}
+
+	rule, err := findRule(s, user, args[0])
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.DB.DeleteRule(context.Background(), rule.ID); err != nil {
+		return fmt.Errorf("failed to delete rule %s: %w", shortID(rule.ID), err)
+	}
+
+	fmt.Printf("rule %s deleted\n", shortID(rule.ID))
+
+	return nil
+}
+
+func testRule(s *state.State, user database.User, args []string) error {
+	// usage: rules test <rule id>
+	//    or: rules test [--feed <feed>] [--field title] [--match substring] <pattern>
+	// previews which of the posts you follow a rule matches, without changing anything
+
+	flags, options := newRuleFlags("rules test")
+	args, err := parseFlags(flags, args)
+	if err != nil {
+		return err
+	}
+
+	if len(args) < 1 {
+		return errors.New("rule ID or pattern argument is required")
+	}
+
+	var rule database.Rule
+	if flags.NFlag() == 0 {
+		if row, err := findRule(s, user, args[0]); err == nil {
+			rule = ruleFromRow(row)
+		}
+	}
+	if rule.ID == "" {
+		rule, err = buildRule(s, user, options, args[0])
+		if err != nil {
+			return err
+		}
+	}
+
+	matcher, err := rules.Compile(rule)
+	if err != nil {
+		return err
+	}
+
+	posts, err := s.DB.GetFollowedPosts(context.Background(), user.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get posts for user %s: %w", user.Name, err)
+	}
+
+	matched, missing := 0, 0
+	for _, post := range posts {
+		rulePost := rules.FromFollowedPost(post)
+		if !rulePost.Has(rule.Field) {
+			missing++
+		}
+		if !matcher.Matches(rulePost) {
+			continue
+		}
+
+		matched++
+		if matched <= maxRuleTestMatches {
+			fmt.Printf("* %s (%s)\n", post.Title.String, post.Url)
+		}
+	}
+
+	if matched > maxRuleTestMatches {
+		fmt.Printf("... and %d more\n", matched-maxRuleTestMatches)
+	}
+	fmt.Printf("%d of %d posts match\n", matched, len(posts))
+	printMissingField(rule.Field, missing)
+
+	return nil
+}
+
+func applyRules(s *state.State, user database.User, args []string) error {
+	// usage: rules apply [rule id]
+	// runs your rules (or just one) against every post you already follow
+
+	var toApply []database.Rule
+	if len(args) > 0 {
+		row, err := findRule(s, user, args[0])
+		if err != nil {
+			return err
+		}
+		toApply = append(toApply, ruleFromRow(row))
+	} else {
+		userRules, err := s.DB.GetRulesForUser(context.Background(), user.ID)
+		if err != nil {
+			return fmt.Errorf("failed to get rules for user %s: %w", user.Name, err)
+		}
+
+		for _, row := range userRules {
+			toApply = append(toApply, ruleFromRow(row))
+		}
+	}
+
+	matchers, err := rules.CompileAll(toApply)
+	if err != nil {
+		return err
+	}
+
+	posts, err := s.DB.GetFollowedPosts(context.Background(), user.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get posts for user %s: %w", user.Name, err)
+	}
+
+	return s.WithTx(context.Background(), func(q *database.Queries) error {
+		for _, matcher := range matchers {
+			applied, missing := 0, 0
+			for _, post := range posts {
+				rulePost := rules.FromFollowedPost(post)
+				if !rulePost.Has(matcher.Rule.Field) {
+					missing++
+				}
+				if !matcher.Matches(rulePost) {
+					continue
+				}
+
+				if err := matcher.Apply(context.Background(), q, post.ID); err != nil {
+					return fmt.Errorf("failed to apply rule %s to post %s: %w", shortID(matcher.Rule.ID), post.Url, err)
+				}
+				applied++
+			}
+
+			fmt.Printf("rule %s applied to %d posts\n", shortID(matcher.Rule.ID), applied)
+			printMissingField(matcher.Rule.Field, missing)
+		}
+		return nil
+	})
+}
+
+func printMissingField(field string, missing int) {
+	// so a rule on a field few posts have doesn't just silently match nothing
+	if missing > 0 {
+		fmt.Printf("%d posts have no %s and can't match\n", missing, field)
+	}
+}
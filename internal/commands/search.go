@@ -0,0 +1,200 @@
+package commands
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"blog-aggregator/internal/database"
+	"blog-aggregator/internal/search"
+	"blog-aggregator/internal/state"
+
+	"github.com/google/uuid"
+)
+
+func HandlerSearch(s *state.State, cmd Command, user database.User) error {
+	// usage: search <save|list|delete> [args...]
+
+	if len(cmd.Args) < 1 {
+		return errors.New("usage: search <save|list|delete> [args...]")
+	}
+
+	subcommand := cmd.Args[0]
+	args := cmd.Args[1:]
+
+	switch subcommand {
+	case "save":
+		return saveSearch(s, user, args)
+	case "list":
+		return listSearches(s, user)
+	case "delete":
+		return deleteSearch(s, user, args)
+	}
+
+	return fmt.Errorf("unknown search subcommand %s", subcommand)
+}
+
+func getSavedSearch(s *state.State, user database.User, name string) (database.SavedSearch, error) {
+	savedSearch, err := s.DB.GetSavedSearchByName(context.Background(), database.GetSavedSearchByNameParams{
+		UserID: user.ID,
+		Name:   name,
+	})
+	if err == sql.ErrNoRows {
+		return database.SavedSearch{}, fmt.Errorf("saved search %s does not exist", name)
+	} else if err != nil {
+		return database.SavedSearch{}, fmt.Errorf("failed to get saved search %s: %w", name, err)
+	}
+
+	return savedSearch, nil
+}
+
+func searchFilters(user database.User, savedSearch database.SavedSearch) database.CountPostsForUserParams {
+	// the filters a saved search puts on GetPostsForUser and CountPostsForUser
+
+	filters := database.CountPostsForUserParams{
+		UserID:   user.ID,
+		FeedID:   savedSearch.FeedID,
+		FolderID: savedSearch.FolderID,
+		Patterns: search.Patterns(savedSearch.Query),
+		Author:   search.AuthorPattern(savedSearch.Author.String),
+		Category: savedSearch.Category,
+	}
+
+	if after, ok := search.PublishedAfter(savedSearch, time.Now()); ok {
+		filters.PublishedAfter = sql.NullTime{Time: after, Valid: true}
+	}
+
+	return filters
+}
+
+func saveSearch(s *state.State, user database.User, args []string) error {
+	// usage: search save <name> [--feed <feed>] [--folder <folder>] [--author <name>] [--category <name>]
+	//                          [--since <duration>] [--alert] [keywords...]
+
+	flags := flag.NewFlagSet("search save", flag.ContinueOnError)
+	feedRef := flags.String("feed", "", "only search posts from this feed")
+	folderName := flags.String("folder", "", "only search posts from feeds in this folder")
+	author := flags.String("author", "", "only search posts by an author whose name contains this")
+	category := flags.String("category", "", "only search posts in this category")
+	since := flags.Duration("since", 0, "only search posts published within this long, e.g. 168h")
+	alert := flags.Bool("alert", false, "tag new matching posts with the search name as agg collects them")
+
+	args, err := parseFlags(flags, args)
+	if err != nil {
+		return err
+	}
+
+	if len(args) < 1 {
+		return errors.New("search name is required")
+	}
+
+	if len(args) < 2 && *author == "" && *category == "" {
+		return errors.New("keywords, --author or --category are required")
+	}
+
+	name := args[0]
+	query := strings.Join(args[1:], " ")
+
+	params := database.CreateSavedSearchParams{
+		ID:        uuid.NewString(),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		UserID:    user.ID,
+		Name:      name,
+		Query:     query,
+		Alert:     *alert,
+		Author:    sql.NullString{String: *author, Valid: *author != ""},
+		Category:  sql.NullString{String: *category, Valid: *category != ""},
+	}
+
+	if *feedRef != "" {
+		feed, err := resolveFeed(s, *feedRef)
+		if err != nil {
+			return err
+		}
+		params.FeedID = sql.NullString{String: feed.ID, Valid: true}
+	}
+
+	if *folderName != "" {
+		folder, err := getFolder(s, user, *folderName)
+		if err != nil {
+			return err
+		}
+		params.FolderID = sql.NullString{String: folder.ID, Valid: true}
+	}
+
+	if *since < 0 {
+		return errors.New("--since must be positive")
+	}
+	if *since > 0 {
+		hours := int32((*since + time.Hour - 1) / time.Hour)
+		params.MaxAgeHours = sql.NullInt32{Int32: hours, Valid: true}
+	}
+
+	if _, err := s.DB.CreateSavedSearch(context.Background(), params); err != nil {
+		return fmt.Errorf("failed to save search %s: %w", name, err)
+	}
+
+	fmt.Printf("search %s saved, browse it with 'browse --search %s'\n", name, name)
+
+	return nil
+}
+
+func listSearches(s *state.State, user database.User) error {
+	searches, err := s.DB.GetSavedSearchesForUser(context.Background(), user.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get saved searches for user %s: %w", user.Name, err)
+	}
+
+	for _, savedSearch := range searches {
+		var details []string
+		if savedSearch.Query != "" {
+			details = append(details, fmt.Sprintf("%q", savedSearch.Query))
+		}
+		if savedSearch.Author.Valid {
+			details = append(details, "by "+savedSearch.Author.String)
+		}
+		if savedSearch.Category.Valid {
+			details = append(details, "in "+savedSearch.Category.String)
+		}
+		if savedSearch.FeedID.Valid {
+			details = append(details, "one feed")
+		}
+		if savedSearch.FolderID.Valid {
+			details = append(details, "one folder")
+		}
+		if savedSearch.MaxAgeHours.Valid {
+			details = append(details, fmt.Sprintf("last %dh", savedSearch.MaxAgeHours.Int32))
+		}
+		if savedSearch.Alert {
+			details = append(details, "alerts on")
+		}
+
+		fmt.Printf("%s: %s\n", savedSearch.Name, strings.Join(details, ", "))
+	}
+
+	return nil
+}
+
+func deleteSearch(s *state.State, user database.User, args []string) error {
+	if len(args) < 1 {
+		return errors.New("search name is required")
+	}
+
+	savedSearch, err := getSavedSearch(s, user, args[0])
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.DB.DeleteSavedSearch(context.Background(), savedSearch.ID); err != nil {
+		return fmt.Errorf("failed to delete saved search %s: %w", savedSearch.Name, err)
+	}
+
+	fmt.Printf("saved search %s deleted\n", savedSearch.Name)
+
+	return nil
+}
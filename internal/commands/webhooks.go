@@ -0,0 +1,251 @@
+package commands
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"blog-aggregator/internal/database"
+	"blog-aggregator/internal/state"
+	"blog-aggregator/internal/webhook"
+
+	"github.com/google/uuid"
+)
+
+const defaultWebhookLogLimit = 20
+
+func HandlerWebhooks(s *state.State, cmd Command, user database.User) error {
+	// usage: webhooks <add|list|delete|log> [args...]
+
+	if len(cmd.Args) < 1 {
+		return errors.New("usage: webhooks <add|list|delete|log> [args...]")
+	}
+
+	subcommand := cmd.Args[0]
+	args := cmd.Args[1:]
+
+	switch subcommand {
+	case "add":
+		return addWebhook(s, user, args)
+	case "list":
+		return listWebhooks(s, user)
+	case "delete":
+		return deleteWebhook(s, user, args)
+	case "log":
+		return webhookLog(s, user, args)
+	}
+
+	return fmt.Errorf("unknown webhooks subcommand %s", subcommand)
+}
+
+func findWebhook(s *state.State, user database.User, ref string) (database.Webhook, error) {
+	webhooks, err := s.DB.GetWebhooksForUser(context.Background(), user.ID)
+	if err != nil {
+		return database.Webhook{}, fmt.Errorf("failed to get webhooks for user %s: %w", user.Name, err)
+	}
+
+	var found []database.Webhook
+	for _, hook := range webhooks {
+		if strings.HasPrefix(hook.ID, strings.ToLower(ref)) {
+			found = append(found, hook)
+		}
+	}
+
+	switch len(found) {
+	case 0:
+		return database.Webhook{}, fmt.Errorf("no webhook with ID %s", ref)
+	case 1:
+		return found[0], nil
+	}
+
+	return database.Webhook{}, fmt.Errorf("%d webhooks start with %s, use more of the ID", len(found), ref)
+}
+
+func addWebhook(s *state.State, user database.User, args []string) error {
+	// usage: webhooks add <url> [--secret <secret>] [--feed <feed>] [--folder <folder>] [--rule <rule id>]
+
+	flags := flag.NewFlagSet("webhooks add", flag.ContinueOnError)
+	secret := flags.String("secret", "", "secret used to sign payloads, generated if not given")
+	feedRef := flags.String("feed", "", "only send posts from this feed")
+	folderName := flags.String("folder", "", "only send posts from feeds in this folder")
+	ruleRef := flags.String("rule", "", "only send posts matching this rule")
+
+	args, err := parseFlags(flags, args)
+	if err != nil {
+		return err
+	}
+
+	if len(args) < 1 {
+		return errors.New("webhook URL argument is required")
+	}
+
+	target, err := url.Parse(args[0])
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return fmt.Errorf("%s is not an http or https URL", args[0])
+	}
+
+	generated := *secret == ""
+	if generated {
+		*secret, err = webhook.NewSecret()
+		if err != nil {
+			return fmt.Errorf("failed to generate webhook secret: %w", err)
+		}
+	}
+
+	params := database.CreateWebhookParams{
+		ID:        uuid.NewString(),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		UserID:    user.ID,
+		Url:       target.String(),
+		Secret:    *secret,
+	}
+
+	if *feedRef != "" {
+		feed, err := resolveFeed(s, *feedRef)
+		if err != nil {
+			return err
+		}
+		params.FeedID = sql.NullString{String: feed.ID, Valid: true}
+	}
+
+	if *folderName != "" {
+		folder, err := getFolder(s, user, *folderName)
+		if err != nil {
+			return err
+		}
+		params.FolderID = sql.NullString{String: folder.ID, Valid: true}
+	}
+
+	if *ruleRef != "" {
+		rule, err := findRule(s, user, *ruleRef)
+		if err != nil {
+			return err
+		}
+		params.RuleID = sql.NullString{String: rule.ID, Valid: true}
+	}
+
+	hook, err := s.DB.CreateWebhook(context.Background(), params)
+	if err != nil {
+		return fmt.Errorf("failed to create webhook: %w", err)
+	}
+
+	fmt.Printf("webhook %s added for %s\n", shortID(hook.ID), hook.Url)
+	if generated {
+		// the secret is not shown again
+		fmt.Printf("secret: %s\n", hook.Secret)
+	}
+	fmt.Printf("payloads are signed with HMAC-SHA256 in the %s header\n", webhook.SignatureHeader)
+
+	return nil
+}
+
+func describeWebhook(hook database.Webhook) string {
+	var filters []string
+	if hook.FeedID.Valid {
+		filters = append(filters, "feed "+shortID(hook.FeedID.String))
+	}
+	if hook.FolderID.Valid {
+		filters = append(filters, "folder "+shortID(hook.FolderID.String))
+	}
+	if hook.RuleID.Valid {
+		filters = append(filters, "rule "+shortID(hook.RuleID.String))
+	}
+
+	scope := "all followed feeds"
+	if len(filters) > 0 {
+		scope = strings.Join(filters, ", ")
+	}
+
+	return fmt.Sprintf("[%s] %s (%s)", shortID(hook.ID), hook.Url, scope)
+}
+
+func listWebhooks(s *state.State, user database.User) error {
+	webhooks, err := s.DB.GetWebhooksForUser(context.Background(), user.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get webhooks for user %s: %w", user.Name, err)
+	}
+
+	if len(webhooks) == 0 {
+		fmt.Println("no webhooks")
+		return nil
+	}
+
+	for _, hook := range webhooks {
+		fmt.Println(describeWebhook(hook))
+	}
+
+	return nil
+}
+
+func deleteWebhook(s *state.State, user database.User, args []string) error {
+	if len(args) < 1 {
+		return errors.New("webhook ID argument is required")
+	}
+
+	hook, err := findWebhook(s, user, args[0])
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.DB.DeleteWebhook(context.Background(), hook.ID); err != nil {
+		return fmt.Errorf("failed to delete webhook %s: %w", shortID(hook.ID), err)
+	}
+
+	fmt.Printf("webhook %s deleted\n", shortID(hook.ID))
+
+	return nil
+}
+
+func webhookLog(s *state.State, user database.User, args []string) error {
+	// usage: webhooks log [--limit n]
+
+	flags := flag.NewFlagSet("webhooks log", flag.ContinueOnError)
+	limit := flags.Int("limit", defaultWebhookLogLimit, "number of attempts to show")
+
+	if _, err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	if *limit < 1 {
+		return errors.New("--limit must be positive")
+	}
+
+	attempts, err := s.DB.GetWebhookAttemptsForUser(context.Background(), database.GetWebhookAttemptsForUserParams{
+		UserID: user.ID,
+		Limit:  int32(*limit),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get webhook attempts for user %s: %w", user.Name, err)
+	}
+
+	if len(attempts) == 0 {
+		fmt.Println("no webhook attempts yet")
+		return nil
+	}
+
+	for _, attempt := range attempts {
+		result := "ok"
+		if attempt.Error.Valid {
+			result = attempt.Error.String
+		} else if attempt.StatusCode.Valid {
+			result = fmt.Sprintf("%d", attempt.StatusCode.Int32)
+		}
+
+		fmt.Printf("%s [%s] %s -> %s: %s (%s)\n",
+			attempt.AttemptedAt.Format(time.RFC3339),
+			shortID(attempt.WebhookID),
+			attempt.PostUrl,
+			attempt.WebhookUrl,
+			result,
+			attempt.DeliveryStatus,
+		)
+	}
+
+	return nil
+}
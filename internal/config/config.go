@@ -2,6 +2,9 @@ package config
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
 )
@@ -9,8 +12,52 @@ import (
 const configFilename = ".gatorconfig.json"
 
 type Config struct {
-	DBUrl           string `json:"db_url"`
-	CurrentUserName string `json:"current_user_name"`
+	DBUrl           string      `json:"db_url"`
+	CurrentUserName string      `json:"current_user_name"`
+	SMTP            *SMTPConfig `json:"smtp,omitempty"`
+	// overrides the schedules of agg's jobs, by job name
+	Jobs map[string]string `json:"jobs,omitempty"`
+	// where the download command saves enclosures, ~/Downloads/gator by default
+	DownloadDir string `json:"download_dir,omitempty"`
+	// agg caches thumbnails of post images here for offline reading, off when empty
+	ImageCacheDir string `json:"image_cache_dir,omitempty"`
+	// tunes the client feeds are fetched with, anything left out keeps its default
+	HTTP *HTTPConfig `json:"http,omitempty"`
+	// bounds on how often agg fetches each feed
+	FetchInterval *FetchIntervalConfig `json:"fetch_interval,omitempty"`
+	// base64 AES-256 key that feed credentials are encrypted with; the
+	// GATOR_CREDENTIALS_KEY environment variable takes precedence
+	CredentialsKey string `json:"credentials_key,omitempty"`
+	// posts older than this many days are pruned, 0 keeps them forever
+	PostRetentionDays int `json:"post_retention_days,omitempty"`
+}
+
+type FetchIntervalConfig struct {
+	// durations like "15m"
+	Min string `json:"min,omitempty"`
+	Max string `json:"max,omitempty"`
+}
+
+type HTTPConfig struct {
+	UserAgent string `json:"user_agent,omitempty"`
+	// durations like "10s"
+	ConnectTimeout string `json:"connect_timeout,omitempty"`
+	Timeout        string `json:"timeout,omitempty"`
+	MaxBodyBytes   int64  `json:"max_body_bytes,omitempty"`
+	// politeness towards each host: time between requests, and requests at once
+	HostDelay       string `json:"host_delay,omitempty"`
+	HostConcurrency int    `json:"host_concurrency,omitempty"`
+	// e.g. "http://proxy.internal:3128"; without it the HTTPS_PROXY and HTTP_PROXY
+	// environment variables are used
+	Proxy string `json:"proxy,omitempty"`
+}
+
+type SMTPConfig struct {
+	Host     string `json:"host"`
+	Port     int    `json:"port"`
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	From     string `json:"from"`
 }
 
 func Read() (Config, error) {
@@ -42,7 +89,6 @@ func (cfg *Config) SetUser(username string) error {
 	return write(*cfg)
 }
 
-
 func getConfigPath() (string, error) {
 	// func returns a string with the path to the config file in the user's home directory
 	homeDir, err := os.UserHomeDir()
@@ -68,5 +114,11 @@ func write(config Config) error {
 		return err
 	}
 
-	return os.WriteFile(configPath, fileData, 0644)
-}
\ No newline at end of file
+	// the config holds the credentials key and the SMTP password, so only its owner
+	// may read it; WriteFile only sets the mode of a new file, so an older one is fixed first
+	if err := os.Chmod(configPath, 0600); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to restrict permissions of %s: %w", configPath, err)
+	}
+
+	return os.WriteFile(configPath, fileData, 0600)
+}
@@ -0,0 +1,43 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteIsPrivate(t *testing.T) {
+	for _, existing := range []bool{false, true} {
+		home := t.TempDir()
+		t.Setenv("HOME", home)
+		path := filepath.Join(home, configFilename)
+
+		// configs written before were readable by everyone
+		if existing {
+			if err := os.WriteFile(path, []byte(`{"db_url":"postgres://localhost/gator"}`), 0644); err != nil {
+				t.Fatalf("failed to write config: %v", err)
+			}
+		}
+
+		cfg := Config{DBUrl: "postgres://localhost/gator", CredentialsKey: "secret"}
+		if err := cfg.SetUser("alice"); err != nil {
+			t.Fatalf("SetUser: %v", err)
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatalf("failed to stat config: %v", err)
+		}
+		if mode := info.Mode().Perm(); mode != 0600 {
+			t.Errorf("existing config %v: mode = %v, want -rw-------", existing, mode)
+		}
+
+		read, err := Read()
+		if err != nil {
+			t.Fatalf("Read: %v", err)
+		}
+		if read.CurrentUserName != "alice" || read.CredentialsKey != "secret" {
+			t.Errorf("Read = %+v", read)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package credentials
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+
+	"blog-aggregator/internal/config"
+)
+
+// takes precedence over credentials_key in the config
+const KeyEnv = "GATOR_CREDENTIALS_KEY"
+
+const keySize = 32
+
+var ErrNoKey = fmt.Errorf("no credentials key configured, set %s or credentials_key in the config to 32 random bytes in base64 (openssl rand -base64 32)", KeyEnv)
+
+type Basic struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// how to authenticate when fetching a feed; only ever stored encrypted
+type Credentials struct {
+	Basic       *Basic            `json:"basic,omitempty"`
+	BearerToken string            `json:"bearer_token,omitempty"`
+	Headers     map[string]string `json:"headers,omitempty"`
+}
+
+func Key(cfg *config.Config) ([]byte, error) {
+	// the AES-256 key credentials are encrypted with, from the environment or the config
+
+	encoded := os.Getenv(KeyEnv)
+	if encoded == "" {
+		encoded = cfg.CredentialsKey
+	}
+	if encoded == "" {
+		return nil, ErrNoKey
+	}
+
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil || len(key) != keySize {
+		return nil, fmt.Errorf("invalid credentials key, expected %d random bytes in base64 (openssl rand -base64 32)", keySize)
+	}
+
+	return key, nil
+}
+
+func (c Credentials) Empty() bool {
+	return c.Basic == nil && c.BearerToken == "" && len(c.Headers) == 0
+}
+
+func (c Credentials) Header() http.Header {
+	// the request headers that authenticate with these credentials
+
+	header := http.Header{}
+	for name, value := range c.Headers {
+		header.Set(name, value)
+	}
+
+	// basic and bearer both use Authorization, the later one set wins
+	if c.Basic != nil {
+		token := base64.StdEncoding.EncodeToString([]byte(c.Basic.Username + ":" + c.Basic.Password))
+		header.Set("Authorization", "Basic "+token)
+	}
+	if c.BearerToken != "" {
+		header.Set("Authorization", "Bearer "+c.BearerToken)
+	}
+
+	return header
+}
+
+func (c Credentials) Describe() []string {
+	// what is set, with the secrets masked, for showing to the feed's owner
+
+	var lines []string
+	if c.Basic != nil {
+		lines = append(lines, fmt.Sprintf("basic auth as %s, password %s", c.Basic.Username, mask(c.Basic.Password)))
+	}
+	if c.BearerToken != "" {
+		lines = append(lines, "bearer token "+mask(c.BearerToken))
+	}
+
+	names := make([]string, 0, len(c.Headers))
+	for name := range c.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		lines = append(lines, fmt.Sprintf("header %s: %s", name, mask(c.Headers[name])))
+	}
+
+	return lines
+}
+
+func mask(secret string) string {
+	// long secrets keep their last 4 characters so they can be told apart
+	if len(secret) < 12 {
+		return "****"
+	}
+	return "****" + secret[len(secret)-4:]
+}
+
+func Encrypt(key []byte, feedID string, c Credentials) ([]byte, error) {
+	// AES-GCM with the nonce in front; the feed ID is authenticated too, so
+	// encrypted credentials can't be copied over to another feed
+
+	plaintext, err := json.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	return aead.Seal(nonce, nonce, plaintext, []byte(feedID)), nil
+}
+
+func Decrypt(key []byte, feedID string, data []byte) (Credentials, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return Credentials{}, err
+	}
+
+	if len(data) < aead.NonceSize() {
+		return Credentials{}, errors.New("encrypted credentials are truncated")
+	}
+
+	nonce, ciphertext := data[:aead.NonceSize()], data[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, []byte(feedID))
+	if err != nil {
+		return Credentials{}, errors.New("failed to decrypt credentials, was the credentials key changed?")
+	}
+
+	var c Credentials
+	if err := json.Unmarshal(plaintext, &c); err != nil {
+		return Credentials{}, fmt.Errorf("failed to read credentials: %w", err)
+	}
+
+	return c, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid credentials key: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
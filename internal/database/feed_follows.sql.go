@@ -7,9 +7,34 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
+
+	"github.com/lib/pq"
 )
 
+const countFeedFollows = `-- name: CountFeedFollows :one
+SELECT COUNT(*) FROM feed_follows
+`
+
+func (q *Queries) CountFeedFollows(ctx context.Context) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countFeedFollows)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countFeedFollowsForUser = `-- name: CountFeedFollowsForUser :one
+SELECT COUNT(*) FROM feed_follows WHERE user_id = $1
+`
+
+func (q *Queries) CountFeedFollowsForUser(ctx context.Context, userID string) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countFeedFollowsForUser, userID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createFeedFollow = `-- name: CreateFeedFollow :one
 WITH inserted_feed_follow AS (
     INSERT INTO feed_follows(
@@ -25,10 +50,10 @@ WITH inserted_feed_follow AS (
         $4,
         $5
     )
-    RETURNING id, created_at, updated_at, user_id, feed_id
+    RETURNING id, created_at, updated_at, user_id, feed_id, display_name, priority, keep_downloads
 )
 SELECT 
-    inserted_feed_follow.id, inserted_feed_follow.created_at, inserted_feed_follow.updated_at, inserted_feed_follow.user_id, inserted_feed_follow.feed_id,
+    inserted_feed_follow.id, inserted_feed_follow.created_at, inserted_feed_follow.updated_at, inserted_feed_follow.user_id, inserted_feed_follow.feed_id, inserted_feed_follow.display_name, inserted_feed_follow.priority, inserted_feed_follow.keep_downloads,
     feeds.name AS feed_name,
     users.name AS user_name
 FROM inserted_feed_follow
@@ -45,13 +70,16 @@ type CreateFeedFollowParams struct {
 }
 
 type CreateFeedFollowRow struct {
-	ID        string
-	CreatedAt time.Time
-	UpdatedAt time.Time
-	UserID    string
-	FeedID    string
-	FeedName  string
-	UserName  string
+	ID            string
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	UserID        string
+	FeedID        string
+	DisplayName   sql.NullString
+	Priority      int32
+	KeepDownloads sql.NullInt32
+	FeedName      string
+	UserName      string
 }
 
 func (q *Queries) CreateFeedFollow(ctx context.Context, arg CreateFeedFollowParams) (CreateFeedFollowRow, error) {
@@ -69,14 +97,45 @@ func (q *Queries) CreateFeedFollow(ctx context.Context, arg CreateFeedFollowPara
 		&i.UpdatedAt,
 		&i.UserID,
 		&i.FeedID,
+		&i.DisplayName,
+		&i.Priority,
+		&i.KeepDownloads,
 		&i.FeedName,
 		&i.UserName,
 	)
 	return i, err
 }
 
+const followFeedIfNotFollowing = `-- name: FollowFeedIfNotFollowing :execrows
+INSERT INTO feed_follows (id, created_at, updated_at, user_id, feed_id)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (user_id, feed_id) DO NOTHING
+`
+
+type FollowFeedIfNotFollowingParams struct {
+	ID        string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	UserID    string
+	FeedID    string
+}
+
+func (q *Queries) FollowFeedIfNotFollowing(ctx context.Context, arg FollowFeedIfNotFollowingParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, followFeedIfNotFollowing,
+		arg.ID,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+		arg.UserID,
+		arg.FeedID,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const getFeedByURL = `-- name: GetFeedByURL :one
-SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at FROM feeds WHERE url = $1
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, unfollowed_at, paused, fetch_full_content, parse_warnings, dead_at, next_fetch_at FROM feeds WHERE url = $1
 `
 
 func (q *Queries) GetFeedByURL(ctx context.Context, url string) (Feed, error) {
@@ -90,13 +149,45 @@ func (q *Queries) GetFeedByURL(ctx context.Context, url string) (Feed, error) {
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.UnfollowedAt,
+		&i.Paused,
+		&i.FetchFullContent,
+		pq.Array(&i.ParseWarnings),
+		&i.DeadAt,
+		&i.NextFetchAt,
+	)
+	return i, err
+}
+
+const getFeedFollow = `-- name: GetFeedFollow :one
+SELECT id, created_at, updated_at, user_id, feed_id, display_name, priority, keep_downloads FROM feed_follows
+WHERE user_id = $1 AND feed_id = $2
+`
+
+type GetFeedFollowParams struct {
+	UserID string
+	FeedID string
+}
+
+func (q *Queries) GetFeedFollow(ctx context.Context, arg GetFeedFollowParams) (FeedFollow, error) {
+	row := q.db.QueryRowContext(ctx, getFeedFollow, arg.UserID, arg.FeedID)
+	var i FeedFollow
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.FeedID,
+		&i.DisplayName,
+		&i.Priority,
+		&i.KeepDownloads,
 	)
 	return i, err
 }
 
 const getFeedFollowsForUser = `-- name: GetFeedFollowsForUser :many
 SELECT
-    feed_follows.id, feed_follows.created_at, feed_follows.updated_at, feed_follows.user_id, feed_follows.feed_id,
+    feed_follows.id, feed_follows.created_at, feed_follows.updated_at, feed_follows.user_id, feed_follows.feed_id, feed_follows.display_name, feed_follows.priority, feed_follows.keep_downloads,
     feeds.name AS feed_name,
     users.name AS user_name
 FROM feed_follows
@@ -106,13 +197,16 @@ WHERE feed_follows.user_id = $1
 `
 
 type GetFeedFollowsForUserRow struct {
-	ID        string
-	CreatedAt time.Time
-	UpdatedAt time.Time
-	UserID    string
-	FeedID    string
-	FeedName  string
-	UserName  string
+	ID            string
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	UserID        string
+	FeedID        string
+	DisplayName   sql.NullString
+	Priority      int32
+	KeepDownloads sql.NullInt32
+	FeedName      string
+	UserName      string
 }
 
 func (q *Queries) GetFeedFollowsForUser(ctx context.Context, userID string) ([]GetFeedFollowsForUserRow, error) {
@@ -130,6 +224,9 @@ func (q *Queries) GetFeedFollowsForUser(ctx context.Context, userID string) ([]G
 			&i.UpdatedAt,
 			&i.UserID,
 			&i.FeedID,
+			&i.DisplayName,
+			&i.Priority,
+			&i.KeepDownloads,
 			&i.FeedName,
 			&i.UserName,
 		); err != nil {
@@ -146,12 +243,75 @@ func (q *Queries) GetFeedFollowsForUser(ctx context.Context, userID string) ([]G
 	return items, nil
 }
 
+const getFollowedFeedsByFolder = `-- name: GetFollowedFeedsByFolder :many
+SELECT
+    feed_follows.feed_id,
+    COALESCE(feed_follows.display_name, feeds.name) AS feed_name,
+    feed_follows.priority,
+    folders.name AS folder_name,
+    (
+        SELECT COUNT(*) FROM posts
+        LEFT JOIN user_posts ON user_posts.post_id = posts.id
+            AND user_posts.user_id = feed_follows.user_id
+        WHERE posts.feed_id = feed_follows.feed_id
+          AND user_posts.read_at IS NULL
+          AND user_posts.hidden_at IS NULL
+    ) AS unread_count
+FROM feed_follows
+INNER JOIN feeds ON feed_follows.feed_id = feeds.id
+LEFT JOIN feed_follow_folders ON feed_follow_folders.feed_follow_id = feed_follows.id
+LEFT JOIN folders ON folders.id = feed_follow_folders.folder_id
+WHERE feed_follows.user_id = $1
+ORDER BY folders.name ASC NULLS LAST, feed_follows.priority DESC, feed_name ASC
+`
+
+type GetFollowedFeedsByFolderRow struct {
+	FeedID      string
+	FeedName    string
+	Priority    int32
+	FolderName  sql.NullString
+	UnreadCount int64
+}
+
+func (q *Queries) GetFollowedFeedsByFolder(ctx context.Context, userID string) ([]GetFollowedFeedsByFolderRow, error) {
+	rows, err := q.db.QueryContext(ctx, getFollowedFeedsByFolder, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetFollowedFeedsByFolderRow
+	for rows.Next() {
+		var i GetFollowedFeedsByFolderRow
+		if err := rows.Scan(
+			&i.FeedID,
+			&i.FeedName,
+			&i.Priority,
+			&i.FolderName,
+			&i.UnreadCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getNextFeedToFetch = `-- name: GetNextFeedToFetch :one
-SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at FROM feeds
-ORDER BY last_fetched_at ASC NULLS FIRST
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, unfollowed_at, paused, fetch_full_content, parse_warnings, dead_at, next_fetch_at FROM feeds
+WHERE NOT paused
+  AND dead_at IS NULL
+  AND (next_fetch_at IS NULL OR next_fetch_at <= CURRENT_TIMESTAMP)
+ORDER BY next_fetch_at ASC NULLS FIRST, last_fetched_at ASC NULLS FIRST
 LIMIT 1
 `
 
+// the feed that has been due the longest
 func (q *Queries) GetNextFeedToFetch(ctx context.Context) (Feed, error) {
 	row := q.db.QueryRowContext(ctx, getNextFeedToFetch)
 	var i Feed
@@ -163,6 +323,12 @@ func (q *Queries) GetNextFeedToFetch(ctx context.Context) (Feed, error) {
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.UnfollowedAt,
+		&i.Paused,
+		&i.FetchFullContent,
+		pq.Array(&i.ParseWarnings),
+		&i.DeadAt,
+		&i.NextFetchAt,
 	)
 	return i, err
 }
@@ -170,16 +336,112 @@ func (q *Queries) GetNextFeedToFetch(ctx context.Context) (Feed, error) {
 const markFeedFetched = `-- name: MarkFeedFetched :exec
 UPDATE feeds
 SET last_fetched_at = CURRENT_TIMESTAMP,
+    next_fetch_at = $2,
     updated_at = CURRENT_TIMESTAMP
 WHERE id = $1
 `
 
-func (q *Queries) MarkFeedFetched(ctx context.Context, id string) error {
-	_, err := q.db.ExecContext(ctx, markFeedFetched, id)
+type MarkFeedFetchedParams struct {
+	ID          string
+	NextFetchAt sql.NullTime
+}
+
+// next_fetch_at is when to retry if the fetch fails, a successful one replaces it
+func (q *Queries) MarkFeedFetched(ctx context.Context, arg MarkFeedFetchedParams) error {
+	_, err := q.db.ExecContext(ctx, markFeedFetched, arg.ID, arg.NextFetchAt)
 	return err
 }
 
-const unfollowFeed = `-- name: UnfollowFeed :exec
+const moveFeedFollows = `-- name: MoveFeedFollows :execrows
+UPDATE feed_follows
+SET feed_id = $1,
+    updated_at = CURRENT_TIMESTAMP
+WHERE feed_id = $2
+  AND NOT EXISTS (
+      SELECT 1 FROM feed_follows AS existing
+      WHERE existing.user_id = feed_follows.user_id
+        AND existing.feed_id = $1
+  )
+`
+
+type MoveFeedFollowsParams struct {
+	ToFeedID   string
+	FromFeedID string
+}
+
+// users already following the target feed keep that follow instead
+func (q *Queries) MoveFeedFollows(ctx context.Context, arg MoveFeedFollowsParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, moveFeedFollows, arg.ToFeedID, arg.FromFeedID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const setFeedFollowDisplayName = `-- name: SetFeedFollowDisplayName :execrows
+UPDATE feed_follows
+SET display_name = $3,
+    updated_at = CURRENT_TIMESTAMP
+WHERE user_id = $1 AND feed_id = $2
+`
+
+type SetFeedFollowDisplayNameParams struct {
+	UserID      string
+	FeedID      string
+	DisplayName sql.NullString
+}
+
+func (q *Queries) SetFeedFollowDisplayName(ctx context.Context, arg SetFeedFollowDisplayNameParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, setFeedFollowDisplayName, arg.UserID, arg.FeedID, arg.DisplayName)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const setFeedFollowKeepDownloads = `-- name: SetFeedFollowKeepDownloads :execrows
+UPDATE feed_follows
+SET keep_downloads = $3,
+    updated_at = CURRENT_TIMESTAMP
+WHERE user_id = $1 AND feed_id = $2
+`
+
+type SetFeedFollowKeepDownloadsParams struct {
+	UserID        string
+	FeedID        string
+	KeepDownloads sql.NullInt32
+}
+
+func (q *Queries) SetFeedFollowKeepDownloads(ctx context.Context, arg SetFeedFollowKeepDownloadsParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, setFeedFollowKeepDownloads, arg.UserID, arg.FeedID, arg.KeepDownloads)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const setFeedFollowPriority = `-- name: SetFeedFollowPriority :execrows
+UPDATE feed_follows
+SET priority = $3,
+    updated_at = CURRENT_TIMESTAMP
+WHERE user_id = $1 AND feed_id = $2
+`
+
+type SetFeedFollowPriorityParams struct {
+	UserID   string
+	FeedID   string
+	Priority int32
+}
+
+func (q *Queries) SetFeedFollowPriority(ctx context.Context, arg SetFeedFollowPriorityParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, setFeedFollowPriority, arg.UserID, arg.FeedID, arg.Priority)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const unfollowFeed = `-- name: UnfollowFeed :execrows
 DELETE FROM feed_follows
 WHERE user_id = $1 AND feed_id = $2
 `
@@ -189,7 +451,10 @@ type UnfollowFeedParams struct {
 	FeedID string
 }
 
-func (q *Queries) UnfollowFeed(ctx context.Context, arg UnfollowFeedParams) error {
-	_, err := q.db.ExecContext(ctx, unfollowFeed, arg.UserID, arg.FeedID)
-	return err
+func (q *Queries) UnfollowFeed(ctx context.Context, arg UnfollowFeedParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, unfollowFeed, arg.UserID, arg.FeedID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
 }
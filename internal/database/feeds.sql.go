@@ -9,8 +9,92 @@ import (
 	"context"
 	"database/sql"
 	"time"
+
+	"github.com/lib/pq"
 )
 
+const adoptOrphanedFeeds = `-- name: AdoptOrphanedFeeds :execrows
+UPDATE feeds
+SET user_id = (
+        SELECT feed_follows.user_id FROM feed_follows
+        WHERE feed_follows.feed_id = feeds.id
+        ORDER BY feed_follows.created_at ASC
+        LIMIT 1
+    ),
+    updated_at = CURRENT_TIMESTAMP
+WHERE feeds.user_id IS NULL
+  AND EXISTS (SELECT 1 FROM feed_follows WHERE feed_follows.feed_id = feeds.id)
+`
+
+func (q *Queries) AdoptOrphanedFeeds(ctx context.Context) (int64, error) {
+	result, err := q.db.ExecContext(ctx, adoptOrphanedFeeds)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const clearRefollowedFeeds = `-- name: ClearRefollowedFeeds :execrows
+UPDATE feeds
+SET unfollowed_at = NULL
+WHERE unfollowed_at IS NOT NULL
+  AND EXISTS (SELECT 1 FROM feed_follows WHERE feed_follows.feed_id = feeds.id)
+`
+
+func (q *Queries) ClearRefollowedFeeds(ctx context.Context) (int64, error) {
+	result, err := q.db.ExecContext(ctx, clearRefollowedFeeds)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const countCollectableFeeds = `-- name: CountCollectableFeeds :one
+SELECT COUNT(*) FROM feeds
+WHERE NOT EXISTS (SELECT 1 FROM feed_follows WHERE feed_follows.feed_id = feeds.id)
+  AND unfollowed_at < $1::timestamptz
+`
+
+func (q *Queries) CountCollectableFeeds(ctx context.Context, cutoff time.Time) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countCollectableFeeds, cutoff)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countFeeds = `-- name: CountFeeds :one
+SELECT COUNT(*) FROM feeds
+`
+
+func (q *Queries) CountFeeds(ctx context.Context) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countFeeds)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countFeedsForUser = `-- name: CountFeedsForUser :one
+SELECT COUNT(*) FROM feeds WHERE user_id = $1
+`
+
+func (q *Queries) CountFeedsForUser(ctx context.Context, userID sql.NullString) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countFeedsForUser, userID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countFetchedFeeds = `-- name: CountFetchedFeeds :one
+SELECT COUNT(*) FROM feeds WHERE last_fetched_at IS NOT NULL
+`
+
+func (q *Queries) CountFetchedFeeds(ctx context.Context) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countFetchedFeeds)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createFeed = `-- name: CreateFeed :one
 INSERT INTO feeds (
     id,
@@ -27,7 +111,7 @@ INSERT INTO feeds (
     $5,
     $6
 )
-RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, unfollowed_at, paused, fetch_full_content, parse_warnings, dead_at, next_fetch_at
 `
 
 type CreateFeedParams struct {
@@ -36,7 +120,7 @@ type CreateFeedParams struct {
 	UpdatedAt time.Time
 	Name      string
 	Url       string
-	UserID    string
+	UserID    sql.NullString
 }
 
 func (q *Queries) CreateFeed(ctx context.Context, arg CreateFeedParams) (Feed, error) {
@@ -57,27 +141,77 @@ func (q *Queries) CreateFeed(ctx context.Context, arg CreateFeedParams) (Feed, e
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.UnfollowedAt,
+		&i.Paused,
+		&i.FetchFullContent,
+		pq.Array(&i.ParseWarnings),
+		&i.DeadAt,
+		&i.NextFetchAt,
 	)
 	return i, err
 }
 
+const deleteAllFeeds = `-- name: DeleteAllFeeds :execrows
+DELETE FROM feeds
+`
+
+func (q *Queries) DeleteAllFeeds(ctx context.Context) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteAllFeeds)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteCollectableFeeds = `-- name: DeleteCollectableFeeds :execrows
+DELETE FROM feeds
+WHERE NOT EXISTS (SELECT 1 FROM feed_follows WHERE feed_follows.feed_id = feeds.id)
+  AND unfollowed_at < $1::timestamptz
+`
+
+func (q *Queries) DeleteCollectableFeeds(ctx context.Context, cutoff time.Time) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteCollectableFeeds, cutoff)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteFeed = `-- name: DeleteFeed :execrows
+DELETE FROM feeds WHERE id = $1
+`
+
+func (q *Queries) DeleteFeed(ctx context.Context, id string) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteFeed, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const getFeeds = `-- name: GetFeeds :many
 SELECT 
-    feeds.id, feeds.created_at, feeds.updated_at, feeds.name, feeds.url, feeds.user_id, feeds.last_fetched_at,
+    feeds.id, feeds.created_at, feeds.updated_at, feeds.name, feeds.url, feeds.user_id, feeds.last_fetched_at, feeds.unfollowed_at, feeds.paused, feeds.fetch_full_content, feeds.parse_warnings, feeds.dead_at, feeds.next_fetch_at,
     users.name AS user_name
 FROM feeds
 LEFT JOIN users ON feeds.user_id = users.id
 `
 
 type GetFeedsRow struct {
-	ID            string
-	CreatedAt     time.Time
-	UpdatedAt     time.Time
-	Name          string
-	Url           string
-	UserID        string
-	LastFetchedAt sql.NullTime
-	UserName      sql.NullString
+	ID               string
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+	Name             string
+	Url              string
+	UserID           sql.NullString
+	LastFetchedAt    sql.NullTime
+	UnfollowedAt     sql.NullTime
+	Paused           bool
+	FetchFullContent bool
+	ParseWarnings    []string
+	DeadAt           sql.NullTime
+	NextFetchAt      sql.NullTime
+	UserName         sql.NullString
 }
 
 func (q *Queries) GetFeeds(ctx context.Context) ([]GetFeedsRow, error) {
@@ -97,6 +231,12 @@ func (q *Queries) GetFeeds(ctx context.Context) ([]GetFeedsRow, error) {
 			&i.Url,
 			&i.UserID,
 			&i.LastFetchedAt,
+			&i.UnfollowedAt,
+			&i.Paused,
+			&i.FetchFullContent,
+			pq.Array(&i.ParseWarnings),
+			&i.DeadAt,
+			&i.NextFetchAt,
 			&i.UserName,
 		); err != nil {
 			return nil, err
@@ -111,3 +251,421 @@ func (q *Queries) GetFeeds(ctx context.Context) ([]GetFeedsRow, error) {
 	}
 	return items, nil
 }
+
+const getFeedsByIDPrefix = `-- name: GetFeedsByIDPrefix :many
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, unfollowed_at, paused, fetch_full_content, parse_warnings, dead_at, next_fetch_at FROM feeds
+WHERE id LIKE $1::text || '%'
+ORDER BY created_at ASC
+`
+
+// the prefix has LIKE's wildcards escaped
+func (q *Queries) GetFeedsByIDPrefix(ctx context.Context, prefix string) ([]Feed, error) {
+	rows, err := q.db.QueryContext(ctx, getFeedsByIDPrefix, prefix)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Feed
+	for rows.Next() {
+		var i Feed
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Name,
+			&i.Url,
+			&i.UserID,
+			&i.LastFetchedAt,
+			&i.UnfollowedAt,
+			&i.Paused,
+			&i.FetchFullContent,
+			pq.Array(&i.ParseWarnings),
+			&i.DeadAt,
+			&i.NextFetchAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getFeedsByName = `-- name: GetFeedsByName :many
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, unfollowed_at, paused, fetch_full_content, parse_warnings, dead_at, next_fetch_at FROM feeds
+WHERE LOWER(name) = LOWER($1::text)
+ORDER BY created_at ASC
+`
+
+func (q *Queries) GetFeedsByName(ctx context.Context, name string) ([]Feed, error) {
+	rows, err := q.db.QueryContext(ctx, getFeedsByName, name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Feed
+	for rows.Next() {
+		var i Feed
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Name,
+			&i.Url,
+			&i.UserID,
+			&i.LastFetchedAt,
+			&i.UnfollowedAt,
+			&i.Paused,
+			&i.FetchFullContent,
+			pq.Array(&i.ParseWarnings),
+			&i.DeadAt,
+			&i.NextFetchAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listFeeds = `-- name: ListFeeds :many
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, unfollowed_at, paused, fetch_full_content, parse_warnings, dead_at, next_fetch_at FROM feeds
+ORDER BY name ASC
+`
+
+func (q *Queries) ListFeeds(ctx context.Context) ([]Feed, error) {
+	rows, err := q.db.QueryContext(ctx, listFeeds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Feed
+	for rows.Next() {
+		var i Feed
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Name,
+			&i.Url,
+			&i.UserID,
+			&i.LastFetchedAt,
+			&i.UnfollowedAt,
+			&i.Paused,
+			&i.FetchFullContent,
+			pq.Array(&i.ParseWarnings),
+			&i.DeadAt,
+			&i.NextFetchAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markFeedDead = `-- name: MarkFeedDead :exec
+UPDATE feeds
+SET dead_at = CURRENT_TIMESTAMP,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = $1
+`
+
+func (q *Queries) MarkFeedDead(ctx context.Context, id string) error {
+	_, err := q.db.ExecContext(ctx, markFeedDead, id)
+	return err
+}
+
+const markUnfollowedFeeds = `-- name: MarkUnfollowedFeeds :execrows
+UPDATE feeds
+SET unfollowed_at = CURRENT_TIMESTAMP
+WHERE unfollowed_at IS NULL
+  AND NOT EXISTS (SELECT 1 FROM feed_follows WHERE feed_follows.feed_id = feeds.id)
+`
+
+func (q *Queries) MarkUnfollowedFeeds(ctx context.Context) (int64, error) {
+	result, err := q.db.ExecContext(ctx, markUnfollowedFeeds)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const renameFeed = `-- name: RenameFeed :one
+UPDATE feeds
+SET name = $2,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = $1
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, unfollowed_at, paused, fetch_full_content, parse_warnings, dead_at, next_fetch_at
+`
+
+type RenameFeedParams struct {
+	ID   string
+	Name string
+}
+
+func (q *Queries) RenameFeed(ctx context.Context, arg RenameFeedParams) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, renameFeed, arg.ID, arg.Name)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.UnfollowedAt,
+		&i.Paused,
+		&i.FetchFullContent,
+		pq.Array(&i.ParseWarnings),
+		&i.DeadAt,
+		&i.NextFetchAt,
+	)
+	return i, err
+}
+
+const resetFeedFetchState = `-- name: ResetFeedFetchState :execrows
+UPDATE feeds
+SET last_fetched_at = NULL,
+    next_fetch_at = NULL,
+    updated_at = CURRENT_TIMESTAMP
+WHERE last_fetched_at IS NOT NULL
+`
+
+func (q *Queries) ResetFeedFetchState(ctx context.Context) (int64, error) {
+	result, err := q.db.ExecContext(ctx, resetFeedFetchState)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const reviveFeed = `-- name: ReviveFeed :one
+UPDATE feeds
+SET dead_at = NULL,
+    next_fetch_at = NULL,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = $1
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, unfollowed_at, paused, fetch_full_content, parse_warnings, dead_at, next_fetch_at
+`
+
+func (q *Queries) ReviveFeed(ctx context.Context, id string) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, reviveFeed, id)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.UnfollowedAt,
+		&i.Paused,
+		&i.FetchFullContent,
+		pq.Array(&i.ParseWarnings),
+		&i.DeadAt,
+		&i.NextFetchAt,
+	)
+	return i, err
+}
+
+const searchFeeds = `-- name: SearchFeeds :many
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, unfollowed_at, paused, fetch_full_content, parse_warnings, dead_at, next_fetch_at FROM feeds
+WHERE name ILIKE $1::text
+   OR url ILIKE $1::text
+ORDER BY name ASC
+`
+
+// pattern is an ILIKE pattern, see search.Pattern
+func (q *Queries) SearchFeeds(ctx context.Context, pattern string) ([]Feed, error) {
+	rows, err := q.db.QueryContext(ctx, searchFeeds, pattern)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Feed
+	for rows.Next() {
+		var i Feed
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Name,
+			&i.Url,
+			&i.UserID,
+			&i.LastFetchedAt,
+			&i.UnfollowedAt,
+			&i.Paused,
+			&i.FetchFullContent,
+			pq.Array(&i.ParseWarnings),
+			&i.DeadAt,
+			&i.NextFetchAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const setFeedFetchFullContent = `-- name: SetFeedFetchFullContent :one
+UPDATE feeds
+SET fetch_full_content = $2,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = $1
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, unfollowed_at, paused, fetch_full_content, parse_warnings, dead_at, next_fetch_at
+`
+
+type SetFeedFetchFullContentParams struct {
+	ID               string
+	FetchFullContent bool
+}
+
+func (q *Queries) SetFeedFetchFullContent(ctx context.Context, arg SetFeedFetchFullContentParams) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, setFeedFetchFullContent, arg.ID, arg.FetchFullContent)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.UnfollowedAt,
+		&i.Paused,
+		&i.FetchFullContent,
+		pq.Array(&i.ParseWarnings),
+		&i.DeadAt,
+		&i.NextFetchAt,
+	)
+	return i, err
+}
+
+const setFeedNextFetchAt = `-- name: SetFeedNextFetchAt :exec
+UPDATE feeds
+SET next_fetch_at = $2
+WHERE id = $1
+`
+
+type SetFeedNextFetchAtParams struct {
+	ID          string
+	NextFetchAt sql.NullTime
+}
+
+func (q *Queries) SetFeedNextFetchAt(ctx context.Context, arg SetFeedNextFetchAtParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedNextFetchAt, arg.ID, arg.NextFetchAt)
+	return err
+}
+
+const setFeedParseWarnings = `-- name: SetFeedParseWarnings :exec
+UPDATE feeds
+SET parse_warnings = $2
+WHERE id = $1
+`
+
+type SetFeedParseWarningsParams struct {
+	ID            string
+	ParseWarnings []string
+}
+
+func (q *Queries) SetFeedParseWarnings(ctx context.Context, arg SetFeedParseWarningsParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedParseWarnings, arg.ID, pq.Array(arg.ParseWarnings))
+	return err
+}
+
+const setFeedPaused = `-- name: SetFeedPaused :one
+UPDATE feeds
+SET paused = $2,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = $1
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, unfollowed_at, paused, fetch_full_content, parse_warnings, dead_at, next_fetch_at
+`
+
+type SetFeedPausedParams struct {
+	ID     string
+	Paused bool
+}
+
+func (q *Queries) SetFeedPaused(ctx context.Context, arg SetFeedPausedParams) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, setFeedPaused, arg.ID, arg.Paused)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.UnfollowedAt,
+		&i.Paused,
+		&i.FetchFullContent,
+		pq.Array(&i.ParseWarnings),
+		&i.DeadAt,
+		&i.NextFetchAt,
+	)
+	return i, err
+}
+
+const setFeedURL = `-- name: SetFeedURL :one
+UPDATE feeds
+SET url = $2,
+    dead_at = NULL,
+    next_fetch_at = NULL,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = $1
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, unfollowed_at, paused, fetch_full_content, parse_warnings, dead_at, next_fetch_at
+`
+
+type SetFeedURLParams struct {
+	ID  string
+	Url string
+}
+
+// a new URL gets a dead feed fetched again, right away
+func (q *Queries) SetFeedURL(ctx context.Context, arg SetFeedURLParams) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, setFeedURL, arg.ID, arg.Url)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.UnfollowedAt,
+		&i.Paused,
+		&i.FetchFullContent,
+		pq.Array(&i.ParseWarnings),
+		&i.DeadAt,
+		&i.NextFetchAt,
+	)
+	return i, err
+}
@@ -9,38 +9,226 @@ import (
 	"time"
 )
 
+type Author struct {
+	ID        string
+	CreatedAt time.Time
+	Name      string
+}
+
+type Category struct {
+	ID        string
+	CreatedAt time.Time
+	Name      string
+}
+
+type DigestSchedule struct {
+	UserID     string
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	Email      string
+	Period     string
+	GroupBy    string
+	SendAt     string
+	TimeZone   string
+	LastSentAt sql.NullTime
+	Weekday    int32
+	Failures   int32
+	LastError  sql.NullString
+	RetryAt    sql.NullTime
+}
+
+type Download struct {
+	UserID      string
+	EnclosureID string
+	CreatedAt   time.Time
+	Path        string
+	Size        int64
+}
+
+type Enclosure struct {
+	ID        string
+	CreatedAt time.Time
+	PostID    string
+	Url       string
+	MimeType  sql.NullString
+	Length    sql.NullInt64
+}
+
 type Feed struct {
+	ID               string
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+	Name             string
+	Url              string
+	UserID           sql.NullString
+	LastFetchedAt    sql.NullTime
+	UnfollowedAt     sql.NullTime
+	Paused           bool
+	FetchFullContent bool
+	ParseWarnings    []string
+	DeadAt           sql.NullTime
+	NextFetchAt      sql.NullTime
+}
+
+type FeedCredential struct {
+	FeedID    string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	Data      []byte
+}
+
+type FeedFollow struct {
 	ID            string
 	CreatedAt     time.Time
 	UpdatedAt     time.Time
-	Name          string
-	Url           string
 	UserID        string
-	LastFetchedAt sql.NullTime
+	FeedID        string
+	DisplayName   sql.NullString
+	Priority      int32
+	KeepDownloads sql.NullInt32
 }
 
-type FeedFollow struct {
+type FeedFollowFolder struct {
+	FeedFollowID string
+	FolderID     string
+	CreatedAt    time.Time
+}
+
+type Folder struct {
 	ID        string
 	CreatedAt time.Time
 	UpdatedAt time.Time
 	UserID    string
-	FeedID    string
+	Name      string
+}
+
+type Job struct {
+	Name      string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	Schedule  string
+	NextRunAt sql.NullTime
+}
+
+type JobRun struct {
+	ID         string
+	JobName    string
+	StartedAt  time.Time
+	FinishedAt sql.NullTime
+	Status     string
+	Summary    sql.NullString
+	Error      sql.NullString
 }
 
 type Post struct {
+	ID              string
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	Title           sql.NullString
+	Url             string
+	Description     sql.NullString
+	PublishedAt     sql.NullTime
+	FeedID          string
+	ContentHtml     sql.NullString
+	ContentText     sql.NullString
+	CommentsUrl     sql.NullString
+	DurationSeconds sql.NullInt32
+	Episode         sql.NullInt32
+	Season          sql.NullInt32
+	ImageUrl        sql.NullString
+}
+
+type PostAuthor struct {
+	PostID   string
+	AuthorID string
+}
+
+type PostCategory struct {
+	PostID     string
+	CategoryID string
+}
+
+type PostTag struct {
+	UserID    string
+	PostID    string
+	Tag       string
+	CreatedAt time.Time
+}
+
+type Rule struct {
+	ID        string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	UserID    string
+	FeedID    sql.NullString
+	Field     string
+	MatchType string
+	Pattern   string
+	Action    string
+	Tag       sql.NullString
+}
+
+type SavedSearch struct {
 	ID          string
 	CreatedAt   time.Time
 	UpdatedAt   time.Time
-	Title       sql.NullString
-	Url         string
-	Description sql.NullString
-	PublishedAt sql.NullTime
-	FeedID      string
+	UserID      string
+	Name        string
+	Query       string
+	FeedID      sql.NullString
+	FolderID    sql.NullString
+	MaxAgeHours sql.NullInt32
+	Alert       bool
+	Author      sql.NullString
+	Category    sql.NullString
 }
 
 type User struct {
+	ID         string
+	Name       string
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	Role       string
+	DisabledAt sql.NullTime
+}
+
+type UserPost struct {
+	UserID    string
+	PostID    string
+	ReadAt    sql.NullTime
+	HiddenAt  sql.NullTime
+	StarredAt sql.NullTime
+}
+
+type Webhook struct {
 	ID        string
-	Name      string
 	CreatedAt time.Time
 	UpdatedAt time.Time
+	UserID    string
+	Url       string
+	Secret    string
+	FeedID    sql.NullString
+	FolderID  sql.NullString
+	RuleID    sql.NullString
+}
+
+type WebhookAttempt struct {
+	ID          string
+	DeliveryID  string
+	AttemptedAt time.Time
+	StatusCode  sql.NullInt32
+	Error       sql.NullString
+}
+
+type WebhookDelivery struct {
+	ID            string
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	WebhookID     string
+	PostID        string
+	Payload       string
+	Status        string
+	Attempts      int32
+	NextAttemptAt time.Time
+	DeliveredAt   sql.NullTime
 }
@@ -9,8 +9,91 @@ import (
 	"context"
 	"database/sql"
 	"time"
+
+	"github.com/lib/pq"
 )
 
+const countPosts = `-- name: CountPosts :one
+SELECT COUNT(*) FROM posts
+`
+
+func (q *Queries) CountPosts(ctx context.Context) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countPosts)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countPostsForUser = `-- name: CountPostsForUser :one
+SELECT COUNT(*) FROM posts
+INNER JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+LEFT JOIN user_posts ON user_posts.post_id = posts.id
+    AND user_posts.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = $1
+  AND user_posts.hidden_at IS NULL
+  AND ($2::text IS NULL OR posts.feed_id = $2::text)
+  AND (
+    $3::text IS NULL
+    OR EXISTS (
+        SELECT 1 FROM feed_follow_folders
+        WHERE feed_follow_folders.feed_follow_id = feed_follows.id
+          AND feed_follow_folders.folder_id = $3::text
+    )
+  )
+  AND (
+    $4::timestamptz IS NULL
+    OR posts.published_at >= $4::timestamptz
+  )
+  AND (COALESCE(posts.title, '') || ' ' || COALESCE(posts.description, '')) ILIKE ALL ($5::text[])
+  AND (
+    $6::text IS NULL
+    OR EXISTS (
+        SELECT 1 FROM post_authors
+        INNER JOIN authors ON authors.id = post_authors.author_id
+        WHERE post_authors.post_id = posts.id
+          AND authors.name ILIKE $6::text
+    )
+  )
+  AND (
+    $7::text IS NULL
+    OR EXISTS (
+        SELECT 1 FROM post_categories
+        INNER JOIN categories ON categories.id = post_categories.category_id
+        WHERE post_categories.post_id = posts.id
+          AND lower(categories.name) = lower($7::text)
+    )
+  )
+  AND (NOT $8::boolean OR user_posts.read_at IS NULL)
+`
+
+type CountPostsForUserParams struct {
+	UserID         string
+	FeedID         sql.NullString
+	FolderID       sql.NullString
+	PublishedAfter sql.NullTime
+	Patterns       []string
+	Author         sql.NullString
+	Category       sql.NullString
+	UnreadOnly     bool
+}
+
+// takes the same filters as GetPostsForUser
+func (q *Queries) CountPostsForUser(ctx context.Context, arg CountPostsForUserParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countPostsForUser,
+		arg.UserID,
+		arg.FeedID,
+		arg.FolderID,
+		arg.PublishedAfter,
+		pq.Array(arg.Patterns),
+		arg.Author,
+		arg.Category,
+		arg.UnreadOnly,
+	)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createPost = `-- name: CreatePost :one
 INSERT INTO posts (
     id,
@@ -20,22 +103,36 @@ INSERT INTO posts (
     url,
     description,
     published_at,
-    feed_id
+    feed_id,
+    content_html,
+    content_text,
+    comments_url,
+    duration_seconds,
+    episode,
+    season,
+    image_url
 ) VALUES (
-    $1, $2, $3, $4, $5, $6, $7, $8
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15
 )
-RETURNING id, created_at, updated_at, title, url, description, published_at, feed_id
+RETURNING id, created_at, updated_at, title, url, description, published_at, feed_id, content_html, content_text, comments_url, duration_seconds, episode, season, image_url
 `
 
 type CreatePostParams struct {
-	ID          string
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
-	Title       sql.NullString
-	Url         string
-	Description sql.NullString
-	PublishedAt sql.NullTime
-	FeedID      string
+	ID              string
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	Title           sql.NullString
+	Url             string
+	Description     sql.NullString
+	PublishedAt     sql.NullTime
+	FeedID          string
+	ContentHtml     sql.NullString
+	ContentText     sql.NullString
+	CommentsUrl     sql.NullString
+	DurationSeconds sql.NullInt32
+	Episode         sql.NullInt32
+	Season          sql.NullInt32
+	ImageUrl        sql.NullString
 }
 
 func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, error) {
@@ -48,6 +145,13 @@ func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, e
 		arg.Description,
 		arg.PublishedAt,
 		arg.FeedID,
+		arg.ContentHtml,
+		arg.ContentText,
+		arg.CommentsUrl,
+		arg.DurationSeconds,
+		arg.Episode,
+		arg.Season,
+		arg.ImageUrl,
 	)
 	var i Post
 	err := row.Scan(
@@ -59,40 +163,339 @@ func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, e
 		&i.Description,
 		&i.PublishedAt,
 		&i.FeedID,
+		&i.ContentHtml,
+		&i.ContentText,
+		&i.CommentsUrl,
+		&i.DurationSeconds,
+		&i.Episode,
+		&i.Season,
+		&i.ImageUrl,
 	)
 	return i, err
 }
 
+const deleteOldPosts = `-- name: DeleteOldPosts :execrows
+DELETE FROM posts
+WHERE COALESCE(posts.published_at, posts.created_at) < $1::timestamptz
+  AND NOT EXISTS (
+      SELECT 1 FROM user_posts
+      WHERE user_posts.post_id = posts.id
+        AND user_posts.starred_at IS NOT NULL
+  )
+  AND NOT EXISTS (
+      SELECT 1 FROM feed_follows
+      LEFT JOIN user_posts ON user_posts.post_id = posts.id
+          AND user_posts.user_id = feed_follows.user_id
+      WHERE feed_follows.feed_id = posts.feed_id
+        AND user_posts.read_at IS NULL
+        AND user_posts.hidden_at IS NULL
+  )
+  AND NOT EXISTS (
+      SELECT 1 FROM enclosures
+      INNER JOIN downloads ON downloads.enclosure_id = enclosures.id
+      WHERE enclosures.post_id = posts.id
+  )
+  AND NOT EXISTS (
+      SELECT 1 FROM webhook_deliveries
+      WHERE webhook_deliveries.post_id = posts.id
+        AND webhook_deliveries.status = 'pending'
+  )
+`
+
+// posts published before the cutoff, except ones someone starred, hasn't read yet,
+// downloaded an episode of or still has a webhook delivery pending for
+func (q *Queries) DeleteOldPosts(ctx context.Context, before time.Time) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteOldPosts, before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deletePosts = `-- name: DeletePosts :execrows
+DELETE FROM posts
+`
+
+func (q *Queries) DeletePosts(ctx context.Context) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deletePosts)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getFollowedPosts = `-- name: GetFollowedPosts :many
+SELECT
+    posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.content_html, posts.content_text, posts.comments_url, posts.duration_seconds, posts.episode, posts.season, posts.image_url,
+    ARRAY(
+        SELECT authors.name FROM authors
+        INNER JOIN post_authors ON post_authors.author_id = authors.id
+        WHERE post_authors.post_id = posts.id
+        ORDER BY authors.name ASC
+    )::text[] AS authors,
+    ARRAY(
+        SELECT categories.name FROM categories
+        INNER JOIN post_categories ON post_categories.category_id = categories.id
+        WHERE post_categories.post_id = posts.id
+        ORDER BY categories.name ASC
+    )::text[] AS categories
+FROM posts
+INNER JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+WHERE feed_follows.user_id = $1
+ORDER BY posts.published_at DESC NULLS FIRST
+`
+
+type GetFollowedPostsRow struct {
+	ID              string
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	Title           sql.NullString
+	Url             string
+	Description     sql.NullString
+	PublishedAt     sql.NullTime
+	FeedID          string
+	ContentHtml     sql.NullString
+	ContentText     sql.NullString
+	CommentsUrl     sql.NullString
+	DurationSeconds sql.NullInt32
+	Episode         sql.NullInt32
+	Season          sql.NullInt32
+	ImageUrl        sql.NullString
+	Authors         []string
+	Categories      []string
+}
+
+func (q *Queries) GetFollowedPosts(ctx context.Context, userID string) ([]GetFollowedPostsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getFollowedPosts, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetFollowedPostsRow
+	for rows.Next() {
+		var i GetFollowedPostsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.PublishedAt,
+			&i.FeedID,
+			&i.ContentHtml,
+			&i.ContentText,
+			&i.CommentsUrl,
+			&i.DurationSeconds,
+			&i.Episode,
+			&i.Season,
+			&i.ImageUrl,
+			pq.Array(&i.Authors),
+			pq.Array(&i.Categories),
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPostImagesSince = `-- name: GetPostImagesSince :many
+SELECT id, image_url FROM posts
+WHERE image_url IS NOT NULL AND created_at >= $1
+ORDER BY created_at DESC
+LIMIT $2
+`
+
+type GetPostImagesSinceParams struct {
+	CreatedAt time.Time
+	Limit     int32
+}
+
+type GetPostImagesSinceRow struct {
+	ID       string
+	ImageUrl sql.NullString
+}
+
+func (q *Queries) GetPostImagesSince(ctx context.Context, arg GetPostImagesSinceParams) ([]GetPostImagesSinceRow, error) {
+	rows, err := q.db.QueryContext(ctx, getPostImagesSince, arg.CreatedAt, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetPostImagesSinceRow
+	for rows.Next() {
+		var i GetPostImagesSinceRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.ImageUrl,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPostTags = `-- name: GetPostTags :many
+SELECT tag FROM post_tags
+WHERE user_id = $1 AND post_id = $2
+ORDER BY tag ASC
+`
+
+type GetPostTagsParams struct {
+	UserID string
+	PostID string
+}
+
+func (q *Queries) GetPostTags(ctx context.Context, arg GetPostTagsParams) ([]string, error) {
+	rows, err := q.db.QueryContext(ctx, getPostTags, arg.UserID, arg.PostID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var tag string
+		if err := rows.Scan(&tag); err != nil {
+			return nil, err
+		}
+		items = append(items, tag)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getPostsForUser = `-- name: GetPostsForUser :many
 SELECT 
-    posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id,
-    feeds.name AS feed_name
+    posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.content_html, posts.content_text, posts.comments_url, posts.duration_seconds, posts.episode, posts.season, posts.image_url,
+    COALESCE(feed_follows.display_name, feeds.name) AS feed_name,
+    user_posts.starred_at,
+    ARRAY(
+        SELECT authors.name FROM authors
+        INNER JOIN post_authors ON post_authors.author_id = authors.id
+        WHERE post_authors.post_id = posts.id
+        ORDER BY authors.name ASC
+    )::text[] AS authors,
+    ARRAY(
+        SELECT categories.name FROM categories
+        INNER JOIN post_categories ON post_categories.category_id = categories.id
+        WHERE post_categories.post_id = posts.id
+        ORDER BY categories.name ASC
+    )::text[] AS categories
 FROM posts
 INNER JOIN feeds ON posts.feed_id = feeds.id
-WHERE feeds.user_id = $1
-ORDER BY posts.published_at DESC NULLS FIRST
-LIMIT $2
+INNER JOIN feed_follows ON feed_follows.feed_id = feeds.id
+LEFT JOIN user_posts ON user_posts.post_id = posts.id
+    AND user_posts.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = $1
+  AND user_posts.hidden_at IS NULL
+  AND ($2::text IS NULL OR posts.feed_id = $2::text)
+  AND (
+    $3::text IS NULL
+    OR EXISTS (
+        SELECT 1 FROM feed_follow_folders
+        WHERE feed_follow_folders.feed_follow_id = feed_follows.id
+          AND feed_follow_folders.folder_id = $3::text
+    )
+  )
+  AND (
+    $4::timestamptz IS NULL
+    OR posts.published_at >= $4::timestamptz
+  )
+  AND (COALESCE(posts.title, '') || ' ' || COALESCE(posts.description, '')) ILIKE ALL ($5::text[])
+  AND (
+    $6::text IS NULL
+    OR EXISTS (
+        SELECT 1 FROM post_authors
+        INNER JOIN authors ON authors.id = post_authors.author_id
+        WHERE post_authors.post_id = posts.id
+          AND authors.name ILIKE $6::text
+    )
+  )
+  AND (
+    $7::text IS NULL
+    OR EXISTS (
+        SELECT 1 FROM post_categories
+        INNER JOIN categories ON categories.id = post_categories.category_id
+        WHERE post_categories.post_id = posts.id
+          AND lower(categories.name) = lower($7::text)
+    )
+  )
+  AND (
+    NOT $8::boolean
+    OR EXISTS (SELECT 1 FROM enclosures WHERE enclosures.post_id = posts.id)
+  )
+  AND (NOT $9::boolean OR user_posts.read_at IS NULL)
+ORDER BY feed_follows.priority DESC, posts.published_at DESC NULLS FIRST
+LIMIT $10
 `
 
 type GetPostsForUserParams struct {
-	UserID string
-	Limit  int32
+	UserID         string
+	FeedID         sql.NullString
+	FolderID       sql.NullString
+	PublishedAfter sql.NullTime
+	Patterns       []string
+	Author         sql.NullString
+	Category       sql.NullString
+	MediaOnly      bool
+	UnreadOnly     bool
+	MaxPosts       int32
 }
 
 type GetPostsForUserRow struct {
-	ID          string
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
-	Title       sql.NullString
-	Url         string
-	Description sql.NullString
-	PublishedAt sql.NullTime
-	FeedID      string
-	FeedName    string
+	ID              string
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	Title           sql.NullString
+	Url             string
+	Description     sql.NullString
+	PublishedAt     sql.NullTime
+	FeedID          string
+	ContentHtml     sql.NullString
+	ContentText     sql.NullString
+	CommentsUrl     sql.NullString
+	DurationSeconds sql.NullInt32
+	Episode         sql.NullInt32
+	Season          sql.NullInt32
+	ImageUrl        sql.NullString
+	FeedName        string
+	StarredAt       sql.NullTime
+	Authors         []string
+	Categories      []string
 }
 
 func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams) ([]GetPostsForUserRow, error) {
-	rows, err := q.db.QueryContext(ctx, getPostsForUser, arg.UserID, arg.Limit)
+	rows, err := q.db.QueryContext(ctx, getPostsForUser,
+		arg.UserID,
+		arg.FeedID,
+		arg.FolderID,
+		arg.PublishedAfter,
+		pq.Array(arg.Patterns),
+		arg.Author,
+		arg.Category,
+		arg.MediaOnly,
+		arg.UnreadOnly,
+		arg.MaxPosts,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -109,7 +512,17 @@ func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams
 			&i.Description,
 			&i.PublishedAt,
 			&i.FeedID,
+			&i.ContentHtml,
+			&i.ContentText,
+			&i.CommentsUrl,
+			&i.DurationSeconds,
+			&i.Episode,
+			&i.Season,
+			&i.ImageUrl,
 			&i.FeedName,
+			&i.StarredAt,
+			pq.Array(&i.Authors),
+			pq.Array(&i.Categories),
 		); err != nil {
 			return nil, err
 		}
@@ -123,3 +536,115 @@ func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams
 	}
 	return items, nil
 }
+
+const hidePost = `-- name: HidePost :exec
+INSERT INTO user_posts (user_id, post_id, hidden_at)
+VALUES ($1, $2, CURRENT_TIMESTAMP)
+ON CONFLICT (user_id, post_id) DO UPDATE
+SET hidden_at = COALESCE(user_posts.hidden_at, EXCLUDED.hidden_at)
+`
+
+type HidePostParams struct {
+	UserID string
+	PostID string
+}
+
+func (q *Queries) HidePost(ctx context.Context, arg HidePostParams) error {
+	_, err := q.db.ExecContext(ctx, hidePost, arg.UserID, arg.PostID)
+	return err
+}
+
+const markPostRead = `-- name: MarkPostRead :exec
+INSERT INTO user_posts (user_id, post_id, read_at)
+VALUES ($1, $2, CURRENT_TIMESTAMP)
+ON CONFLICT (user_id, post_id) DO UPDATE
+SET read_at = COALESCE(user_posts.read_at, EXCLUDED.read_at)
+`
+
+type MarkPostReadParams struct {
+	UserID string
+	PostID string
+}
+
+func (q *Queries) MarkPostRead(ctx context.Context, arg MarkPostReadParams) error {
+	_, err := q.db.ExecContext(ctx, markPostRead, arg.UserID, arg.PostID)
+	return err
+}
+
+const moveFeedPosts = `-- name: MoveFeedPosts :execrows
+UPDATE posts
+SET feed_id = $1
+WHERE feed_id = $2
+`
+
+type MoveFeedPostsParams struct {
+	ToFeedID   string
+	FromFeedID string
+}
+
+func (q *Queries) MoveFeedPosts(ctx context.Context, arg MoveFeedPostsParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, moveFeedPosts, arg.ToFeedID, arg.FromFeedID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const setPostContent = `-- name: SetPostContent :exec
+UPDATE posts
+SET content_html = $2,
+    content_text = $3,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = $1
+`
+
+type SetPostContentParams struct {
+	ID          string
+	ContentHtml sql.NullString
+	ContentText sql.NullString
+}
+
+func (q *Queries) SetPostContent(ctx context.Context, arg SetPostContentParams) error {
+	_, err := q.db.ExecContext(ctx, setPostContent, arg.ID, arg.ContentHtml, arg.ContentText)
+	return err
+}
+
+const starPost = `-- name: StarPost :exec
+INSERT INTO user_posts (user_id, post_id, starred_at)
+VALUES ($1, $2, CURRENT_TIMESTAMP)
+ON CONFLICT (user_id, post_id) DO UPDATE
+SET starred_at = COALESCE(user_posts.starred_at, EXCLUDED.starred_at)
+`
+
+type StarPostParams struct {
+	UserID string
+	PostID string
+}
+
+func (q *Queries) StarPost(ctx context.Context, arg StarPostParams) error {
+	_, err := q.db.ExecContext(ctx, starPost, arg.UserID, arg.PostID)
+	return err
+}
+
+const tagPost = `-- name: TagPost :exec
+INSERT INTO post_tags (user_id, post_id, tag, created_at)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (user_id, post_id, tag) DO NOTHING
+`
+
+type TagPostParams struct {
+	UserID    string
+	PostID    string
+	Tag       string
+	CreatedAt time.Time
+}
+
+func (q *Queries) TagPost(ctx context.Context, arg TagPostParams) error {
+	_, err := q.db.ExecContext(ctx, tagPost,
+		arg.UserID,
+		arg.PostID,
+		arg.Tag,
+		arg.CreatedAt,
+	)
+	return err
+}
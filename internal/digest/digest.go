@@ -0,0 +1,386 @@
+package digest
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	htmltemplate "html/template"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"blog-aggregator/internal/config"
+	"blog-aggregator/internal/database"
+)
+
+const (
+	PeriodDaily  = "daily"
+	PeriodWeekly = "weekly"
+
+	GroupByFeed   = "feed"
+	GroupByFolder = "folder"
+
+	DefaultSendAt   = "08:00"
+	DefaultTimeZone = "UTC"
+	DefaultWeekday  = time.Monday
+
+	// how early a digest may go out and still count for its send time, for clock drift
+	dueTolerance = 5 * time.Minute
+
+	// how long until a digest that failed to send is tried again, doubling per failure
+	baseFailureBackoff = 5 * time.Minute
+	maxFailureBackoff  = 12 * time.Hour
+
+	// posts in feeds without a folder end up here when grouping by folder
+	unfiledSection = "Unfiled"
+
+	maxPosts = 500
+)
+
+var (
+	Periods  = []string{PeriodDaily, PeriodWeekly}
+	GroupBys = []string{GroupByFeed, GroupByFolder}
+)
+
+type Post struct {
+	Title       string
+	URL         string
+	FeedName    string
+	ImageURL    string
+	PublishedAt time.Time
+}
+
+type Section struct {
+	Name  string
+	Posts []Post
+}
+
+type Digest struct {
+	UserName string
+	Period   string
+	Since    time.Time
+	Sections []Section
+	Total    int
+}
+
+func PeriodLength(period string) time.Duration {
+	if period == PeriodWeekly {
+		return 7 * 24 * time.Hour
+	}
+
+	return 24 * time.Hour
+}
+
+func Validate(period, groupBy, sendAt, timeZone string) error {
+	if !slices.Contains(Periods, period) {
+		return fmt.Errorf("unknown period %s, use one of: %s", period, strings.Join(Periods, ", "))
+	}
+
+	if !slices.Contains(GroupBys, groupBy) {
+		return fmt.Errorf("unknown grouping %s, use one of: %s", groupBy, strings.Join(GroupBys, ", "))
+	}
+
+	if _, _, err := parseSendAt(sendAt); err != nil {
+		return err
+	}
+
+	if _, err := time.LoadLocation(timeZone); err != nil {
+		return fmt.Errorf("unknown time zone %s", timeZone)
+	}
+
+	return nil
+}
+
+func ParseWeekday(day string) (time.Weekday, error) {
+	// accepts full and three letter English day names, in any case
+
+	day = strings.ToLower(day)
+	for weekday := time.Sunday; weekday <= time.Saturday; weekday++ {
+		name := strings.ToLower(weekday.String())
+		if day == name || day == name[:3] {
+			return weekday, nil
+		}
+	}
+
+	return 0, fmt.Errorf("unknown day %s, use a day of the week like monday", day)
+}
+
+func parseSendAt(sendAt string) (int, int, error) {
+	hour, minute, ok := strings.Cut(sendAt, ":")
+	if ok {
+		h, hourErr := strconv.Atoi(hour)
+		m, minuteErr := strconv.Atoi(minute)
+		if hourErr == nil && minuteErr == nil && h >= 0 && h < 24 && m >= 0 && m < 60 {
+			return h, m, nil
+		}
+	}
+
+	return 0, 0, fmt.Errorf("send time %s should look like 08:00", sendAt)
+}
+
+func Due(schedule database.GetDigestSchedulesRow, now time.Time) (bool, error) {
+	// a digest is due once per send time, and send times are a full period apart. the
+	// first one goes out at the first send time after the schedule was set up
+
+	hour, minute, err := parseSendAt(schedule.SendAt)
+	if err != nil {
+		return false, err
+	}
+
+	location, err := time.LoadLocation(schedule.TimeZone)
+	if err != nil {
+		return false, fmt.Errorf("unknown time zone %s", schedule.TimeZone)
+	}
+
+	slot := lastSlot(now.In(location), schedule.Period, time.Weekday(schedule.Weekday), hour, minute)
+
+	if !schedule.LastSentAt.Valid {
+		return slot.After(schedule.CreatedAt), nil
+	}
+
+	// the send time the last digest went out for: one that went out late because agg
+	// wasn't running still counts for the send time it missed
+	served := lastSlot(schedule.LastSentAt.Time.Add(dueTolerance).In(location), schedule.Period, time.Weekday(schedule.Weekday), hour, minute)
+
+	return slot.After(served), nil
+}
+
+func lastSlot(local time.Time, period string, weekday time.Weekday, hour, minute int) time.Time {
+	// the most recent send time at or before local: today's or yesterday's for daily
+	// digests, the most recent weekday's for weekly ones
+
+	slot := time.Date(local.Year(), local.Month(), local.Day(), hour, minute, 0, 0, local.Location())
+
+	step := 1
+	if period == PeriodWeekly {
+		step = 7
+		slot = slot.AddDate(0, 0, -((int(local.Weekday()) - int(weekday) + 7) % 7))
+	}
+
+	if slot.After(local) {
+		slot = slot.AddDate(0, 0, -step)
+	}
+
+	return slot
+}
+
+func Load(ctx context.Context, db *database.Queries, user database.User, period, groupBy string, now time.Time) (Digest, error) {
+	since := now.Add(-PeriodLength(period))
+
+	rows, err := db.GetDigestPosts(ctx, database.GetDigestPostsParams{
+		UserID:   user.ID,
+		Since:    since,
+		MaxPosts: maxPosts,
+	})
+	if err != nil {
+		return Digest{}, fmt.Errorf("failed to get digest posts for user %s: %w", user.Name, err)
+	}
+
+	return Build(user.Name, period, groupBy, since, rows), nil
+}
+
+func Build(userName, period, groupBy string, since time.Time, rows []database.GetDigestPostsRow) Digest {
+	// rows come once per folder a feed is in, so posts are deduplicated per section
+	// and sections keep the order of their first post (feed priority)
+
+	digest := Digest{
+		UserName: userName,
+		Period:   period,
+		Since:    since,
+	}
+
+	sectionIndex := map[string]int{}
+	seen := map[string]bool{}
+	counted := map[string]bool{}
+
+	for _, row := range rows {
+		name := row.FeedName
+		if groupBy == GroupByFolder {
+			name = unfiledSection
+			if row.FolderName.Valid {
+				name = row.FolderName.String
+			}
+		}
+
+		if seen[name+"\x00"+row.ID] {
+			continue
+		}
+		seen[name+"\x00"+row.ID] = true
+
+		if !counted[row.ID] {
+			counted[row.ID] = true
+			digest.Total++
+		}
+
+		index, ok := sectionIndex[name]
+		if !ok {
+			index = len(digest.Sections)
+			sectionIndex[name] = index
+			digest.Sections = append(digest.Sections, Section{Name: name})
+		}
+
+		post := Post{
+			Title:    row.Title.String,
+			URL:      row.Url,
+			FeedName: row.FeedName,
+			ImageURL: row.ImageUrl.String,
+		}
+		if post.Title == "" {
+			post.Title = row.Url
+		}
+		if row.PublishedAt.Valid {
+			post.PublishedAt = row.PublishedAt.Time
+		}
+
+		digest.Sections[index].Posts = append(digest.Sections[index].Posts, post)
+	}
+
+	if groupBy == GroupByFolder {
+		slices.SortStableFunc(digest.Sections, func(a, b Section) int {
+			switch {
+			case a.Name == b.Name:
+				return 0
+			case a.Name == unfiledSection:
+				return 1
+			case b.Name == unfiledSection:
+				return -1
+			}
+			return strings.Compare(a.Name, b.Name)
+		})
+	}
+
+	return digest
+}
+
+func (d Digest) Subject() string {
+	return fmt.Sprintf("Your %s gator digest: %d unread posts", d.Period, d.Total)
+}
+
+var templateFuncs = map[string]any{
+	"date": func(t time.Time) string {
+		if t.IsZero() {
+			return ""
+		}
+		return t.Format("Jan 2 15:04")
+	},
+}
+
+var textTemplate = template.Must(template.New("text").Funcs(templateFuncs).Parse(
+	`{{.Subject}}
+Unread posts since {{date .Since}} for {{.UserName}}
+{{range .Sections}}
+== {{.Name}} ==
+{{range .Posts}}- {{.Title}}{{with date .PublishedAt}} ({{.}}){{end}}
+  {{.URL}}
+{{end}}{{else}}
+Nothing new, you're all caught up.
+{{end}}`))
+
+var htmlTemplate = htmltemplate.Must(htmltemplate.New("html").Funcs(templateFuncs).Parse(
+	`<!DOCTYPE html>
+<html>
+<body style="font-family: sans-serif;">
+<h1>{{.Subject}}</h1>
+<p>Unread posts since {{date .Since}} for {{.UserName}}</p>
+{{range .Sections}}
+<h2>{{.Name}}</h2>
+<ul>
+{{range .Posts}}<li>{{with .ImageURL}}<img src="{{.}}" alt="" width="80" style="float: left; margin-right: 8px;">{{end}}<a href="{{.URL}}">{{.Title}}</a>{{with date .PublishedAt}} <small>{{.}}</small>{{end}}<br style="clear: both;"></li>
+{{end}}</ul>
+{{else}}
+<p>Nothing new, you're all caught up.</p>
+{{end}}
+</body>
+</html>
+`))
+
+func RenderText(d Digest) (string, error) {
+	var buf bytes.Buffer
+	if err := textTemplate.Execute(&buf, d); err != nil {
+		return "", fmt.Errorf("failed to render digest: %w", err)
+	}
+
+	return buf.String(), nil
+}
+
+func RenderHTML(d Digest) (string, error) {
+	var buf bytes.Buffer
+	if err := htmlTemplate.Execute(&buf, d); err != nil {
+		return "", fmt.Errorf("failed to render digest: %w", err)
+	}
+
+	return buf.String(), nil
+}
+
+func SendScheduled(ctx context.Context, db *database.Queries, smtpConfig *config.SMTPConfig, now time.Time) (int, error) {
+	// sends every digest that is due, called from the agg loop
+
+	schedules, err := db.GetDigestSchedules(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get digest schedules: %w", err)
+	}
+
+	sent := 0
+	for _, schedule := range schedules {
+		due, err := Due(schedule, now)
+		if err != nil {
+			fmt.Printf("Error checking digest schedule of %s: %v\n", schedule.UserName, err)
+			continue
+		}
+		if !due {
+			continue
+		}
+
+		// a digest that failed to send waits out its backoff
+		if schedule.RetryAt.Valid && now.Before(schedule.RetryAt.Time) {
+			continue
+		}
+
+		if smtpConfig == nil {
+			return sent, fmt.Errorf("digests are due but smtp is not configured")
+		}
+
+		user := database.User{ID: schedule.UserID, Name: schedule.UserName}
+		digest, err := Load(ctx, db, user, schedule.Period, schedule.GroupBy, now)
+		if err != nil {
+			return sent, err
+		}
+
+		// empty digests are skipped but still count as sent, so the next one waits a period
+		if digest.Total > 0 {
+			message, err := Message(smtpConfig.From, schedule.Email, digest, now)
+			if err != nil {
+				return sent, err
+			}
+
+			if err := Send(smtpConfig, schedule.Email, message); err != nil {
+				retryAt := now.Add(FailureBackoff(int(schedule.Failures) + 1))
+				fmt.Printf("Error sending digest to %s, trying again at %s: %v\n", schedule.UserName, retryAt.Format(time.DateTime), err)
+
+				err = db.RecordDigestFailure(ctx, database.RecordDigestFailureParams{
+					UserID:    schedule.UserID,
+					LastError: sql.NullString{String: err.Error(), Valid: true},
+					RetryAt:   sql.NullTime{Time: retryAt, Valid: true},
+				})
+				if err != nil {
+					return sent, fmt.Errorf("failed to record failed digest of %s: %w", schedule.UserName, err)
+				}
+				continue
+			}
+			sent++
+		}
+
+		err = db.MarkDigestSent(ctx, database.MarkDigestSentParams{
+			UserID:     schedule.UserID,
+			LastSentAt: sql.NullTime{Time: now, Valid: true},
+		})
+		if err != nil {
+			return sent, fmt.Errorf("failed to mark digest of %s as sent: %w", schedule.UserName, err)
+		}
+	}
+
+	return sent, nil
+}
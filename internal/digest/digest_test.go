@@ -0,0 +1,300 @@
+package digest
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"blog-aggregator/internal/database"
+)
+
+func at(t *testing.T, value string, zone string) time.Time {
+	t.Helper()
+
+	location, err := time.LoadLocation(zone)
+	if err != nil {
+		t.Fatalf("failed to load time zone %s: %v", zone, err)
+	}
+
+	parsed, err := time.ParseInLocation("2006-01-02 15:04:05", value, location)
+	if err != nil {
+		t.Fatalf("failed to parse %s: %v", value, err)
+	}
+
+	return parsed
+}
+
+func TestDue(t *testing.T) {
+	// 2026-10-19 is a Monday
+	tests := []struct {
+		name     string
+		period   string
+		weekday  time.Weekday
+		sendAt   string
+		zone     string
+		created  string
+		lastSent string
+		now      string
+		want     bool
+	}{
+		{
+			name:    "daily, first send time after the schedule was set up",
+			period:  PeriodDaily,
+			created: "2026-10-18 20:00:00",
+			now:     "2026-10-19 08:00:30",
+			want:    true,
+		},
+		{
+			name:    "daily, set up after today's send time",
+			period:  PeriodDaily,
+			created: "2026-10-19 09:00:00",
+			now:     "2026-10-19 20:00:00",
+			want:    false,
+		},
+		{
+			name:     "daily, already sent today",
+			period:   PeriodDaily,
+			created:  "2026-10-01 00:00:00",
+			lastSent: "2026-10-19 08:00:05",
+			now:      "2026-10-19 20:00:00",
+			want:     false,
+		},
+		{
+			name:     "daily, sent yesterday",
+			period:   PeriodDaily,
+			created:  "2026-10-01 00:00:00",
+			lastSent: "2026-10-18 08:00:05",
+			now:      "2026-10-19 08:00:30",
+			want:     true,
+		},
+		{
+			name:     "daily, before today's send time",
+			period:   PeriodDaily,
+			created:  "2026-10-01 00:00:00",
+			lastSent: "2026-10-18 08:00:05",
+			now:      "2026-10-19 07:59:00",
+			want:     false,
+		},
+		{
+			name:     "daily, sent late yesterday",
+			period:   PeriodDaily,
+			created:  "2026-10-01 00:00:00",
+			lastSent: "2026-10-18 12:00:00",
+			now:      "2026-10-19 08:00:30",
+			want:     true,
+		},
+		{
+			name:     "daily, sent a little early because of clock drift",
+			period:   PeriodDaily,
+			created:  "2026-10-01 00:00:00",
+			lastSent: "2026-10-19 07:58:00",
+			now:      "2026-10-19 08:00:30",
+			want:     false,
+		},
+		{
+			name:     "daily, several send times missed",
+			period:   PeriodDaily,
+			created:  "2026-10-01 00:00:00",
+			lastSent: "2026-10-15 08:00:05",
+			now:      "2026-10-19 03:00:00",
+			want:     true,
+		},
+		{
+			name:     "daily, only one digest for several missed send times",
+			period:   PeriodDaily,
+			created:  "2026-10-01 00:00:00",
+			lastSent: "2026-10-19 03:00:00",
+			now:      "2026-10-19 07:00:00",
+			want:     false,
+		},
+		{
+			name:     "weekly, four days after the last one",
+			period:   PeriodWeekly,
+			weekday:  time.Monday,
+			created:  "2026-10-01 00:00:00",
+			lastSent: "2026-10-19 08:00:05",
+			now:      "2026-10-23 08:00:30",
+			want:     false,
+		},
+		{
+			name:     "weekly, six days after the last one",
+			period:   PeriodWeekly,
+			weekday:  time.Monday,
+			created:  "2026-10-01 00:00:00",
+			lastSent: "2026-10-19 08:00:05",
+			now:      "2026-10-25 08:00:30",
+			want:     false,
+		},
+		{
+			name:     "weekly, a week after the last one",
+			period:   PeriodWeekly,
+			weekday:  time.Monday,
+			created:  "2026-10-01 00:00:00",
+			lastSent: "2026-10-12 08:00:05",
+			now:      "2026-10-19 08:00:00",
+			want:     true,
+		},
+		{
+			name:     "weekly, catching up on a missed send time",
+			period:   PeriodWeekly,
+			weekday:  time.Friday,
+			created:  "2026-10-01 00:00:00",
+			lastSent: "2026-10-09 08:00:05",
+			now:      "2026-10-21 08:00:30",
+			want:     true,
+		},
+		{
+			name:     "weekly, not again before the chosen day after catching up",
+			period:   PeriodWeekly,
+			weekday:  time.Friday,
+			created:  "2026-10-01 00:00:00",
+			lastSent: "2026-10-21 08:00:30",
+			now:      "2026-10-22 08:00:30",
+			want:     false,
+		},
+		{
+			name:     "weekly, on the chosen day",
+			period:   PeriodWeekly,
+			weekday:  time.Friday,
+			created:  "2026-10-01 00:00:00",
+			lastSent: "2026-10-16 08:00:05",
+			now:      "2026-10-23 08:00:30",
+			want:     true,
+		},
+		{
+			name:    "weekly, set up midweek waits for the chosen day",
+			period:  PeriodWeekly,
+			weekday: time.Monday,
+			created: "2026-10-21 12:00:00",
+			now:     "2026-10-25 08:00:30",
+			want:    false,
+		},
+		{
+			name:    "weekly, first chosen day after setting it up",
+			period:  PeriodWeekly,
+			weekday: time.Monday,
+			created: "2026-10-21 12:00:00",
+			now:     "2026-10-26 08:00:30",
+			want:    true,
+		},
+		{
+			name:     "weekly, across the start of daylight saving time",
+			period:   PeriodWeekly,
+			weekday:  time.Sunday,
+			zone:     "Europe/Berlin",
+			created:  "2026-03-01 00:00:00",
+			lastSent: "2026-03-22 08:00:05",
+			now:      "2026-03-29 08:00:30",
+			want:     true,
+		},
+		{
+			name:     "daily, send time is local to the time zone",
+			period:   PeriodDaily,
+			zone:     "America/New_York",
+			created:  "2026-10-01 00:00:00",
+			lastSent: "2026-10-18 08:00:05",
+			now:      "2026-10-19 07:30:00",
+			want:     false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			zone := tt.zone
+			if zone == "" {
+				zone = "UTC"
+			}
+			sendAt := tt.sendAt
+			if sendAt == "" {
+				sendAt = DefaultSendAt
+			}
+
+			schedule := database.GetDigestSchedulesRow{
+				Period:    tt.period,
+				Weekday:   int32(tt.weekday),
+				SendAt:    sendAt,
+				TimeZone:  zone,
+				CreatedAt: at(t, tt.created, zone),
+			}
+			if tt.lastSent != "" {
+				schedule.LastSentAt = sql.NullTime{Time: at(t, tt.lastSent, zone), Valid: true}
+			}
+
+			got, err := Due(schedule, at(t, tt.now, zone))
+			if err != nil {
+				t.Fatalf("Due: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Due = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDueSendsOncePerPeriod(t *testing.T) {
+	// runs the digests job every minute for four weeks and counts what goes out; the
+	// schedule was set up before the first send time, so the first digest is on time
+
+	for _, period := range Periods {
+		schedule := database.GetDigestSchedulesRow{
+			Period:    period,
+			Weekday:   int32(time.Wednesday),
+			SendAt:    "07:30",
+			TimeZone:  "Europe/Berlin",
+			CreatedAt: at(t, "2026-10-05 00:00:00", "UTC"),
+		}
+
+		start := at(t, "2026-10-05 00:00:00", "UTC")
+		var sends []time.Time
+		for now := start; now.Before(start.AddDate(0, 0, 28)); now = now.Add(time.Minute) {
+			due, err := Due(schedule, now)
+			if err != nil {
+				t.Fatalf("Due: %v", err)
+			}
+			if due {
+				sends = append(sends, now)
+				schedule.LastSentAt = sql.NullTime{Time: now, Valid: true}
+			}
+		}
+
+		want := 28
+		if period == PeriodWeekly {
+			want = 4
+		}
+		if len(sends) != want {
+			t.Errorf("%s digest sent %d times in four weeks, want %d: %v", period, len(sends), want, sends)
+		}
+
+		for _, sent := range sends {
+			local := sent.In(at(t, "2026-10-05 00:00:00", "Europe/Berlin").Location())
+			if local.Format("15:04") != "07:30" || period == PeriodWeekly && local.Weekday() != time.Wednesday {
+				t.Errorf("%s digest sent at %s", period, local.Format("Monday 15:04 MST"))
+			}
+		}
+	}
+}
+
+func TestParseWeekday(t *testing.T) {
+	tests := []struct {
+		day     string
+		want    time.Weekday
+		wantErr bool
+	}{
+		{day: "monday", want: time.Monday},
+		{day: "Fri", want: time.Friday},
+		{day: "SUNDAY", want: time.Sunday},
+		{day: "someday", wantErr: true},
+		{day: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseWeekday(tt.day)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseWeekday(%q) error = %v, want error %v", tt.day, err, tt.wantErr)
+			continue
+		}
+		if err == nil && got != tt.want {
+			t.Errorf("ParseWeekday(%q) = %v, want %v", tt.day, got, tt.want)
+		}
+	}
+}
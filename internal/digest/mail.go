@@ -0,0 +1,132 @@
+package digest
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"time"
+
+	"blog-aggregator/internal/config"
+)
+
+func Message(from, to string, d Digest, now time.Time) ([]byte, error) {
+	// builds a multipart/alternative email with the plain text and HTML versions
+
+	text, err := RenderText(d)
+	if err != nil {
+		return nil, err
+	}
+
+	html, err := RenderHTML(d)
+	if err != nil {
+		return nil, err
+	}
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+
+	for _, part := range []struct {
+		contentType string
+		content     string
+	}{
+		{"text/plain; charset=utf-8", text},
+		{"text/html; charset=utf-8", html},
+	} {
+		partWriter, err := writer.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to build digest email: %w", err)
+		}
+
+		encoder := quotedprintable.NewWriter(partWriter)
+		if _, err := encoder.Write([]byte(part.content)); err != nil {
+			return nil, fmt.Errorf("failed to build digest email: %w", err)
+		}
+		if err := encoder.Close(); err != nil {
+			return nil, fmt.Errorf("failed to build digest email: %w", err)
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("failed to build digest email: %w", err)
+	}
+
+	var message bytes.Buffer
+	fmt.Fprintf(&message, "From: %s\r\n", from)
+	fmt.Fprintf(&message, "To: %s\r\n", to)
+	fmt.Fprintf(&message, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", d.Subject()))
+	fmt.Fprintf(&message, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	fmt.Fprintf(&message, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&message, "Content-Type: multipart/alternative; boundary=%s\r\n", writer.Boundary())
+	fmt.Fprintf(&message, "\r\n")
+	message.Write(body.Bytes())
+
+	return message.Bytes(), nil
+}
+
+func ParseAddress(email string) (string, error) {
+	// the bare address of an email like "alice@example.com" or "Alice <alice@example.com>",
+	// which is what digests are sent to
+
+	address, err := mail.ParseAddress(email)
+	if err != nil {
+		return "", fmt.Errorf("invalid email address %q: %w", email, err)
+	}
+
+	return address.Address, nil
+}
+
+func FailureBackoff(failures int) time.Duration {
+	// 5m, 10m, 20m, ... capped at maxFailureBackoff, so a bad address or a broken
+	// server isn't retried every minute
+
+	backoff := baseFailureBackoff
+	for i := 1; i < failures; i++ {
+		backoff *= 2
+		if backoff >= maxFailureBackoff {
+			return maxFailureBackoff
+		}
+	}
+
+	return backoff
+}
+
+func Send(smtpConfig *config.SMTPConfig, to string, message []byte) error {
+	// STARTTLS is used when the server offers it; auth is skipped without a username,
+	// which is what local stand-ins like MailHog expect
+
+	if smtpConfig == nil || smtpConfig.Host == "" {
+		return errors.New("smtp is not configured, add an smtp section to ~/.gatorconfig.json")
+	}
+
+	if smtpConfig.From == "" {
+		return errors.New("smtp.from is not configured")
+	}
+
+	port := smtpConfig.Port
+	if port == 0 {
+		port = 25
+	}
+	addr := net.JoinHostPort(smtpConfig.Host, strconv.Itoa(port))
+
+	var auth smtp.Auth
+	if smtpConfig.Username != "" {
+		auth = smtp.PlainAuth("", smtpConfig.Username, smtpConfig.Password, smtpConfig.Host)
+	}
+
+	if err := smtp.SendMail(addr, auth, smtpConfig.From, []string{to}, message); err != nil {
+		return fmt.Errorf("failed to send digest to %s: %w", to, err)
+	}
+
+	return nil
+}
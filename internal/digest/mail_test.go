@@ -0,0 +1,308 @@
+package digest
+
+import (
+	"bufio"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+
+	"blog-aggregator/internal/config"
+)
+
+// smtpStandIn is a minimal SMTP server on a local listener, like MailHog, that keeps
+// the envelope and message of every mail it accepts
+type smtpStandIn struct {
+	listener net.Listener
+	mails    chan receivedMail
+}
+
+type receivedMail struct {
+	from string
+	to   []string
+	data []byte
+}
+
+func newSMTPStandIn(t *testing.T) *smtpStandIn {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	server := &smtpStandIn{listener: listener, mails: make(chan receivedMail, 1)}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go server.serve(t, conn)
+		}
+	}()
+
+	return server
+}
+
+func (s *smtpStandIn) config() *config.SMTPConfig {
+	addr := s.listener.Addr().(*net.TCPAddr)
+	return &config.SMTPConfig{
+		Host: addr.IP.String(),
+		Port: addr.Port,
+		From: "gator@example.com",
+	}
+}
+
+func (s *smtpStandIn) serve(t *testing.T, conn net.Conn) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	text := textproto.NewConn(conn)
+	reply := func(line string) {
+		if err := text.PrintfLine("%s", line); err != nil {
+			t.Errorf("smtp stand-in failed to reply: %v", err)
+		}
+	}
+
+	var mail receivedMail
+	reply("220 localhost ESMTP stand-in")
+	for {
+		line, err := text.ReadLine()
+		if err != nil {
+			return
+		}
+
+		command, argument, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(command) {
+		case "EHLO", "HELO":
+			// no STARTTLS or AUTH, like a local stand-in
+			reply("250-localhost")
+			reply("250 8BITMIME")
+		case "MAIL":
+			mail.from = address(argument)
+			reply("250 OK")
+		case "RCPT":
+			mail.to = append(mail.to, address(argument))
+			reply("250 OK")
+		case "DATA":
+			reply("354 end data with <CR><LF>.<CR><LF>")
+			data, err := io.ReadAll(text.DotReader())
+			if err != nil {
+				t.Errorf("smtp stand-in failed to read message: %v", err)
+				return
+			}
+			mail.data = data
+			s.mails <- mail
+			reply("250 OK: queued")
+		case "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("502 command not implemented")
+		}
+	}
+}
+
+func address(argument string) string {
+	// the <address> of "FROM:<address> BODY=8BITMIME" and the like
+	_, rest, _ := strings.Cut(argument, "<")
+	address, _, _ := strings.Cut(rest, ">")
+	return address
+}
+
+func testDigest() Digest {
+	return Digest{
+		UserName: "alice",
+		Period:   PeriodDaily,
+		Since:    time.Date(2026, 10, 18, 8, 0, 0, 0, time.UTC),
+		Sections: []Section{
+			{
+				Name: "Go Blog",
+				Posts: []Post{
+					{Title: "Go 1.30 is released", URL: "https://go.dev/blog/go1.30", FeedName: "Go Blog"},
+					{Title: "Generic méthodes", URL: "https://go.dev/blog/methods", FeedName: "Go Blog"},
+				},
+			},
+		},
+		Total: 2,
+	}
+}
+
+func TestSendToLocalStandIn(t *testing.T) {
+	server := newSMTPStandIn(t)
+	smtpConfig := server.config()
+	now := time.Date(2026, 10, 19, 8, 0, 0, 0, time.UTC)
+
+	message, err := Message(smtpConfig.From, "alice@example.com", testDigest(), now)
+	if err != nil {
+		t.Fatalf("Message: %v", err)
+	}
+
+	if err := Send(smtpConfig, "alice@example.com", message); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	var received receivedMail
+	select {
+	case received = <-server.mails:
+	case <-time.After(5 * time.Second):
+		t.Fatal("smtp stand-in received no mail")
+	}
+
+	if received.from != "gator@example.com" {
+		t.Errorf("MAIL FROM = %s, want gator@example.com", received.from)
+	}
+	if len(received.to) != 1 || received.to[0] != "alice@example.com" {
+		t.Errorf("RCPT TO = %v, want [alice@example.com]", received.to)
+	}
+
+	parsed, err := mail.ReadMessage(strings.NewReader(string(received.data)))
+	if err != nil {
+		t.Fatalf("failed to parse received mail: %v", err)
+	}
+
+	subject, err := new(mime.WordDecoder).DecodeHeader(parsed.Header.Get("Subject"))
+	if err != nil {
+		t.Fatalf("failed to decode subject: %v", err)
+	}
+	if subject != testDigest().Subject() {
+		t.Errorf("Subject = %q, want %q", subject, testDigest().Subject())
+	}
+
+	mediaType, params, err := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("Content-Type = %s, want multipart/alternative", parsed.Header.Get("Content-Type"))
+	}
+
+	// multipart.Reader undoes the quoted-printable encoding of each part
+	parts := map[string]string{}
+	reader := multipart.NewReader(parsed.Body, params["boundary"])
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("failed to read part: %v", err)
+		}
+
+		body, err := io.ReadAll(part)
+		if err != nil {
+			t.Fatalf("failed to read part: %v", err)
+		}
+		partType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		parts[partType] = string(body)
+	}
+
+	for _, partType := range []string{"text/plain", "text/html"} {
+		body, ok := parts[partType]
+		if !ok {
+			t.Errorf("mail has no %s part", partType)
+			continue
+		}
+		for _, want := range []string{"Go 1.30 is released", "Generic méthodes", "https://go.dev/blog/go1.30"} {
+			if !strings.Contains(body, want) {
+				t.Errorf("%s part doesn't contain %q:\n%s", partType, want, body)
+			}
+		}
+	}
+}
+
+func TestSendFailsWhenServerRejects(t *testing.T) {
+	// a server that turns the connection away after the greeting
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer listener.Close()
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		writer := bufio.NewWriter(conn)
+		writer.WriteString("554 no mail accepted here\r\n")
+		writer.Flush()
+	}()
+
+	addr := listener.Addr().(*net.TCPAddr)
+	smtpConfig := &config.SMTPConfig{Host: addr.IP.String(), Port: addr.Port, From: "gator@example.com"}
+
+	err = Send(smtpConfig, "alice@example.com", []byte("Subject: test\r\n\r\ntest\r\n"))
+	if err == nil {
+		t.Fatal("Send succeeded against a server that rejects mail")
+	}
+	if !strings.Contains(err.Error(), "alice@example.com") {
+		t.Errorf("error doesn't name the recipient: %v", err)
+	}
+}
+
+func TestSendRequiresConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		config *config.SMTPConfig
+	}{
+		{"no smtp section", nil},
+		{"no host", &config.SMTPConfig{From: "gator@example.com"}},
+		{"no from address", &config.SMTPConfig{Host: "localhost"}},
+	}
+
+	for _, tt := range tests {
+		if err := Send(tt.config, "alice@example.com", []byte("test")); err == nil {
+			t.Errorf("%s: Send succeeded", tt.name)
+		}
+	}
+}
+
+func TestParseAddress(t *testing.T) {
+	tests := []struct {
+		email   string
+		want    string
+		wantErr bool
+	}{
+		{email: "alice@example.com", want: "alice@example.com"},
+		{email: "Alice <alice@example.com>", want: "alice@example.com"},
+		{email: "  alice@example.com  ", want: "alice@example.com"},
+		{email: "alice", wantErr: true},
+		{email: "alice@", wantErr: true},
+		{email: "alice@example.com, bob@example.com", wantErr: true},
+		{email: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseAddress(tt.email)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseAddress(%q) error = %v, want error %v", tt.email, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseAddress(%q) = %q, want %q", tt.email, got, tt.want)
+		}
+	}
+}
+
+func TestFailureBackoff(t *testing.T) {
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{1, 5 * time.Minute},
+		{2, 10 * time.Minute},
+		{4, 40 * time.Minute},
+		{9, maxFailureBackoff},
+		{100, maxFailureBackoff},
+	}
+
+	for _, tt := range tests {
+		if got := FailureBackoff(tt.failures); got != tt.want {
+			t.Errorf("FailureBackoff(%d) = %v, want %v", tt.failures, got, tt.want)
+		}
+	}
+}
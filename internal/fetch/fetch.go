@@ -0,0 +1,541 @@
+package fetch
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"blog-aggregator/internal/config"
+)
+
+const (
+	DefaultUserAgent      = "gator"
+	DefaultConnectTimeout = 10 * time.Second
+	DefaultTimeout        = 30 * time.Second
+	DefaultMaxBodyBytes   = 10 * 1024 * 1024
+
+	DefaultHostDelay       = time.Second
+	DefaultHostConcurrency = 2
+)
+
+var (
+	ErrTooLarge   = errors.New("response body too large")
+	ErrDisallowed = errors.New("disallowed by robots.txt")
+)
+
+// returned instead of waiting when a host asked for a break, by Retry-After or a
+// long Crawl-delay; After is how long until it can be tried again
+type RetryAfterError struct {
+	Host  string
+	After time.Duration
+}
+
+func (e *RetryAfterError) Error() string {
+	return fmt.Sprintf("%s asked to wait, retry in %s", e.Host, e.After.Round(time.Second))
+}
+
+type Options struct {
+	UserAgent string
+	// limits connecting, including the TLS handshake
+	ConnectTimeout time.Duration
+	// limits the whole request, from connecting to reading the last byte of the body
+	Timeout      time.Duration
+	MaxBodyBytes int64
+	// nil uses the proxy from the environment
+	Proxy *url.URL
+	// the least time between starting two requests to the same host; a longer
+	// Crawl-delay in the host's robots.txt wins
+	HostDelay time.Duration
+	// how many requests to the same host can run at once
+	HostConcurrency int
+}
+
+type Client struct {
+	http         *http.Client
+	userAgent    string
+	maxBodyBytes int64
+	// the longest a request waits for its host's turn before giving up with a RetryAfterError
+	maxHostWait     time.Duration
+	hostDelay       time.Duration
+	hostConcurrency int
+
+	mu    sync.Mutex
+	hosts map[string]*host
+}
+
+type host struct {
+	// a token per request allowed to run at once
+	slots chan struct{}
+
+	mu sync.Mutex
+	// when the next request may start
+	next time.Time
+
+	robotsMu     sync.Mutex
+	robots       *robots
+	robotsExpiry time.Time
+}
+
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	// where the request ended up after redirects
+	URL *url.URL
+	// the last URL reached through permanent redirects (301 or 308) only, nil when
+	// the first redirect was temporary or there was none
+	MovedTo *url.URL
+	// only read for 2xx responses, decompressed
+	Body []byte
+	// from the Retry-After header of 429 and 503 responses
+	RetryAfter time.Duration
+}
+
+func DefaultOptions() Options {
+	return Options{
+		UserAgent:       DefaultUserAgent,
+		ConnectTimeout:  DefaultConnectTimeout,
+		Timeout:         DefaultTimeout,
+		MaxBodyBytes:    DefaultMaxBodyBytes,
+		HostDelay:       DefaultHostDelay,
+		HostConcurrency: DefaultHostConcurrency,
+	}
+}
+
+func OptionsFromConfig(cfg *config.HTTPConfig) (Options, error) {
+	// the defaults, with whatever the config's "http" section sets
+
+	opts := DefaultOptions()
+	if cfg == nil {
+		return opts, nil
+	}
+
+	if cfg.UserAgent != "" {
+		opts.UserAgent = cfg.UserAgent
+	}
+
+	for _, setting := range []struct {
+		name  string
+		value string
+		dest  *time.Duration
+	}{
+		{"connect_timeout", cfg.ConnectTimeout, &opts.ConnectTimeout},
+		{"timeout", cfg.Timeout, &opts.Timeout},
+		{"host_delay", cfg.HostDelay, &opts.HostDelay},
+	} {
+		if setting.value == "" {
+			continue
+		}
+		duration, err := time.ParseDuration(setting.value)
+		if err != nil || duration <= 0 {
+			return Options{}, fmt.Errorf("invalid http.%s %q, expected a duration like 10s", setting.name, setting.value)
+		}
+		*setting.dest = duration
+	}
+
+	if cfg.MaxBodyBytes < 0 {
+		return Options{}, errors.New("http.max_body_bytes must be positive")
+	}
+	if cfg.MaxBodyBytes > 0 {
+		opts.MaxBodyBytes = cfg.MaxBodyBytes
+	}
+
+	if cfg.HostConcurrency < 0 {
+		return Options{}, errors.New("http.host_concurrency must be positive")
+	}
+	if cfg.HostConcurrency > 0 {
+		opts.HostConcurrency = cfg.HostConcurrency
+	}
+
+	if cfg.Proxy != "" {
+		proxy, err := url.Parse(cfg.Proxy)
+		if err != nil || proxy.Host == "" {
+			return Options{}, fmt.Errorf("invalid http.proxy %q, expected a URL like http://proxy:3128", cfg.Proxy)
+		}
+		opts.Proxy = proxy
+	}
+
+	return opts, nil
+}
+
+func New(opts Options) *Client {
+	// one client is shared by every fetch so connections to the same host get reused
+
+	proxy := http.ProxyFromEnvironment
+	if opts.Proxy != nil {
+		proxy = http.ProxyURL(opts.Proxy)
+	}
+
+	dialer := &net.Dialer{
+		Timeout:   opts.ConnectTimeout,
+		KeepAlive: 30 * time.Second,
+	}
+
+	transport := &http.Transport{
+		Proxy:                 proxy,
+		DialContext:           dialer.DialContext,
+		TLSHandshakeTimeout:   opts.ConnectTimeout,
+		ResponseHeaderTimeout: opts.Timeout,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   4,
+		IdleConnTimeout:       90 * time.Second,
+		// compression is negotiated by Get, so deflate works too and the size limit
+		// applies to the decompressed body
+		DisableCompression: true,
+	}
+
+	c := &Client{
+		userAgent:       opts.UserAgent,
+		maxBodyBytes:    opts.MaxBodyBytes,
+		maxHostWait:     opts.Timeout,
+		hostDelay:       opts.HostDelay,
+		hostConcurrency: max(1, opts.HostConcurrency),
+		hosts:           map[string]*host{},
+	}
+	c.http = &http.Client{
+		Transport:     transport,
+		Timeout:       opts.Timeout,
+		CheckRedirect: c.checkRedirect,
+	}
+	return c
+}
+
+// the only headers a redirect to another host gets; Go already drops Authorization
+// and Cookie there, this keeps a feed's custom auth headers from leaking too
+var crossHostHeaders = map[string]bool{
+	"User-Agent":      true,
+	"Accept":          true,
+	"Accept-Encoding": true,
+	"Referer":         true,
+}
+
+func SameHost(a string, b string) bool {
+	// whether two URLs point at the same host and port, the line a feed's credentials
+	// never cross on their own
+
+	first, err := url.Parse(a)
+	if err != nil {
+		return false
+	}
+	second, err := url.Parse(b)
+	if err != nil {
+		return false
+	}
+
+	return strings.EqualFold(first.Host, second.Host)
+}
+
+// the hosts a request holds a slot of, so each redirect hop waits for its host like
+// the first request did; released once the request is done
+type hostSlots struct {
+	held     map[string]bool
+	releases []func()
+}
+
+type hostSlotsKey struct{}
+
+func (slots *hostSlots) release() {
+	for _, release := range slots.releases {
+		release()
+	}
+}
+
+func (c *Client) checkRedirect(request *http.Request, via []*http.Request) error {
+	// every hop is checked against its host's robots.txt and takes a turn with the
+	// host, the same as the request that started it
+
+	if len(via) >= 10 {
+		return errors.New("stopped after 10 redirects")
+	}
+
+	if !strings.EqualFold(request.URL.Host, via[0].URL.Host) {
+		for name := range request.Header {
+			if !crossHostHeaders[name] {
+				request.Header.Del(name)
+			}
+		}
+	}
+
+	// robots.txt itself is fetched without any, and its redirects just followed
+	slots, ok := request.Context().Value(hostSlotsKey{}).(*hostSlots)
+	if !ok {
+		return nil
+	}
+
+	ctx := request.Context()
+	hostName := strings.ToLower(request.URL.Host)
+	h := c.host(hostName)
+
+	rules, err := c.robotsFor(ctx, hostName, h, request.URL)
+	if err != nil {
+		return err
+	}
+	if !rules.allowed(request.URL) {
+		return fmt.Errorf("%w: redirect to %s", ErrDisallowed, request.URL)
+	}
+
+	delay := max(c.hostDelay, rules.crawlDelay)
+	if slots.held[hostName] {
+		// waiting for a second slot of a host it already holds could wait forever
+		return c.turn(ctx, hostName, h, delay)
+	}
+
+	release, err := c.acquire(ctx, hostName, h, delay)
+	if err != nil {
+		return err
+	}
+	slots.held[hostName] = true
+	slots.releases = append(slots.releases, release)
+	return nil
+}
+
+func (c *Client) host(name string) *host {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	h, ok := c.hosts[name]
+	if !ok {
+		h = &host{slots: make(chan struct{}, c.hostConcurrency)}
+		c.hosts[name] = h
+	}
+	return h
+}
+
+func (c *Client) acquire(ctx context.Context, name string, h *host, delay time.Duration) (release func(), err error) {
+	// waits for a free slot and for the host's turn, then books the next turn
+
+	select {
+	case h.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	release = func() { <-h.slots }
+
+	if err := c.turn(ctx, name, h, delay); err != nil {
+		release()
+		return nil, err
+	}
+
+	return release, nil
+}
+
+func (c *Client) turn(ctx context.Context, name string, h *host, delay time.Duration) error {
+	// waits for the host's turn and books the next one
+
+	h.mu.Lock()
+	now := time.Now()
+	start := now
+	if h.next.After(now) {
+		start = h.next
+	}
+	wait := start.Sub(now)
+	if wait > c.maxHostWait {
+		h.mu.Unlock()
+		return &RetryAfterError{Host: name, After: wait}
+	}
+	h.next = start.Add(delay)
+	h.mu.Unlock()
+
+	if wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return nil
+}
+
+func (h *host) backOff(until time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if until.After(h.next) {
+		h.next = until
+	}
+}
+
+func (c *Client) Get(ctx context.Context, rawURL string, accept string) (*Response, error) {
+	return c.GetWithHeader(ctx, rawURL, accept, nil)
+}
+
+func (c *Client) GetWithHeader(ctx context.Context, rawURL string, accept string, header http.Header) (*Response, error) {
+	// requests to the same host are spaced out and capped, and robots.txt is honored;
+	// header is added to the request, for authentication
+
+	request, err := http.NewRequestWithContext(ctx, "GET", rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	hostName := strings.ToLower(request.URL.Host)
+	h := c.host(hostName)
+
+	rules, err := c.robotsFor(ctx, hostName, h, request.URL)
+	if err != nil {
+		return nil, err
+	}
+	if !rules.allowed(request.URL) {
+		return nil, fmt.Errorf("%w: %s", ErrDisallowed, rawURL)
+	}
+
+	release, err := c.acquire(ctx, hostName, h, max(c.hostDelay, rules.crawlDelay))
+	if err != nil {
+		return nil, err
+	}
+	slots := &hostSlots{held: map[string]bool{hostName: true}, releases: []func(){release}}
+	defer slots.release()
+	request = request.WithContext(context.WithValue(ctx, hostSlotsKey{}, slots))
+
+	for name, values := range header {
+		request.Header[name] = values
+	}
+	request.Header.Set("User-Agent", c.userAgent)
+	request.Header.Set("Accept-Encoding", "gzip, deflate")
+	if accept != "" {
+		request.Header.Set("Accept", accept)
+	}
+
+	response, err := c.http.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	result := &Response{
+		StatusCode: response.StatusCode,
+		Header:     response.Header,
+		URL:        response.Request.URL,
+		MovedTo:    permanentLocation(response),
+	}
+
+	if response.StatusCode == http.StatusTooManyRequests || response.StatusCode == http.StatusServiceUnavailable {
+		// the whole host is left alone for as long as it asked
+		if after, ok := retryAfter(response.Header.Get("Retry-After")); ok {
+			result.RetryAfter = after
+			h.backOff(time.Now().Add(after))
+		}
+	}
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return result, nil
+	}
+
+	body, err := decode(response)
+	if err != nil {
+		return nil, err
+	}
+
+	// one byte over the limit is enough to know the body is too large
+	result.Body, err = io.ReadAll(io.LimitReader(body, c.maxBodyBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if int64(len(result.Body)) > c.maxBodyBytes {
+		return nil, fmt.Errorf("%w: more than %d bytes", ErrTooLarge, c.maxBodyBytes)
+	}
+
+	return result, nil
+}
+
+func retryAfter(value string) (time.Duration, bool) {
+	// Retry-After is either a number of seconds or an HTTP date
+
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Duration(max(seconds, 0)) * time.Second, true
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		return max(time.Until(date), 0), true
+	}
+
+	return 0, false
+}
+
+func (r *Response) MaxAge() (time.Duration, bool) {
+	// how long the response may be cached according to its Cache-Control header;
+	// no-cache and no-store count as zero
+
+	for _, directive := range strings.Split(r.Header.Get("Cache-Control"), ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		switch strings.ToLower(name) {
+		case "no-cache", "no-store":
+			return 0, true
+		case "max-age":
+			seconds, err := strconv.ParseInt(strings.Trim(value, `"`), 10, 64)
+			if err != nil || seconds < 0 {
+				return 0, false
+			}
+			return time.Duration(seconds) * time.Second, true
+		}
+	}
+
+	return 0, false
+}
+
+func permanentLocation(response *http.Response) *url.URL {
+	// each request made for a redirect keeps the response that caused it, so the
+	// chain can be walked back from the final request to the first one
+
+	var hops []*http.Request
+	for request := response.Request; request.Response != nil; request = request.Response.Request {
+		hops = append(hops, request)
+	}
+
+	var moved *url.URL
+	for i := len(hops) - 1; i >= 0; i-- {
+		status := hops[i].Response.StatusCode
+		if status != http.StatusMovedPermanently && status != http.StatusPermanentRedirect {
+			break
+		}
+		moved = hops[i].URL
+	}
+
+	return moved
+}
+
+func decode(response *http.Response) (io.Reader, error) {
+	switch strings.ToLower(strings.TrimSpace(response.Header.Get("Content-Encoding"))) {
+	case "", "identity":
+		return response.Body, nil
+	case "gzip", "x-gzip":
+		reader, err := gzip.NewReader(response.Body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress response: %w", err)
+		}
+		return reader, nil
+	case "deflate":
+		// deflate is meant to be zlib wrapped, but plenty of servers send it raw
+		buffered := bufio.NewReader(response.Body)
+		header, err := buffered.Peek(2)
+		if err == nil && header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+			reader, err := zlib.NewReader(buffered)
+			if err != nil {
+				return nil, fmt.Errorf("failed to decompress response: %w", err)
+			}
+			return reader, nil
+		}
+		return flate.NewReader(buffered), nil
+	}
+
+	return nil, fmt.Errorf("unsupported content encoding %s", response.Header.Get("Content-Encoding"))
+}
@@ -0,0 +1,333 @@
+package fetch
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"blog-aggregator/internal/config"
+)
+
+// testOptions are the defaults without the per-host delay, so tests don't wait on it
+func testOptions() Options {
+	opts := DefaultOptions()
+	opts.HostDelay = 0
+	return opts
+}
+
+// newServer serves handler, with no robots.txt so nothing is off limits
+func newServer(t *testing.T, handler http.HandlerFunc) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			http.NotFound(w, r)
+			return
+		}
+		handler(w, r)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestGetTimesOutOnSlowServer(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+	}{
+		{
+			name: "slow headers",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				select {
+				case <-time.After(5 * time.Second):
+				case <-r.Context().Done():
+				}
+			},
+		},
+		{
+			name: "slow body",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte("<rss>"))
+				w.(http.Flusher).Flush()
+				select {
+				case <-time.After(5 * time.Second):
+				case <-r.Context().Done():
+				}
+				w.Write([]byte("</rss>"))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newServer(t, tt.handler)
+
+			opts := testOptions()
+			opts.Timeout = 200 * time.Millisecond
+			client := New(opts)
+
+			start := time.Now()
+			_, err := client.Get(context.Background(), server.URL+"/feed.xml", "")
+			if err == nil {
+				t.Fatal("Get succeeded against a server that never finishes")
+			}
+			if elapsed := time.Since(start); elapsed > 2*time.Second {
+				t.Errorf("Get gave up after %v, want about %v", elapsed, opts.Timeout)
+			}
+		})
+	}
+}
+
+func TestGetLimitsBodySize(t *testing.T) {
+	const limit = 1024
+
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	writer.Write(bytes.Repeat([]byte("a"), 100*limit))
+	writer.Close()
+
+	tests := []struct {
+		name     string
+		body     []byte
+		encoding string
+		wantErr  bool
+	}{
+		{name: "at the limit", body: bytes.Repeat([]byte("a"), limit)},
+		{name: "one byte over", body: bytes.Repeat([]byte("a"), limit+1), wantErr: true},
+		{name: "far over", body: bytes.Repeat([]byte("a"), 100*limit), wantErr: true},
+		// small on the wire, but the limit is on what it decompresses to
+		{name: "compressed over", body: compressed.Bytes(), encoding: "gzip", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newServer(t, func(w http.ResponseWriter, r *http.Request) {
+				if tt.encoding != "" {
+					w.Header().Set("Content-Encoding", tt.encoding)
+				}
+				w.Write(tt.body)
+			})
+
+			opts := testOptions()
+			opts.MaxBodyBytes = limit
+			response, err := New(opts).Get(context.Background(), server.URL+"/feed.xml", "")
+
+			if tt.wantErr {
+				if !errors.Is(err, ErrTooLarge) {
+					t.Fatalf("Get error = %v, want ErrTooLarge", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Get: %v", err)
+			}
+			if len(response.Body) != limit {
+				t.Errorf("body is %d bytes, want %d", len(response.Body), limit)
+			}
+		})
+	}
+}
+
+func TestGetDecodesCompressedBodies(t *testing.T) {
+	const feed = `<?xml version="1.0"?><rss version="2.0"><channel><title>Test</title></channel></rss>`
+
+	compress := func(newWriter func(io.Writer) io.WriteCloser) []byte {
+		var buffer bytes.Buffer
+		writer := newWriter(&buffer)
+		writer.Write([]byte(feed))
+		writer.Close()
+		return buffer.Bytes()
+	}
+
+	tests := []struct {
+		encoding string
+		body     []byte
+	}{
+		{"", []byte(feed)},
+		{"identity", []byte(feed)},
+		{"gzip", compress(func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) })},
+		{"x-gzip", compress(func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) })},
+		{"deflate", compress(func(w io.Writer) io.WriteCloser { return zlib.NewWriter(w) })},
+		// raw deflate without the zlib wrapper, which some servers send
+		{"deflate", compress(func(w io.Writer) io.WriteCloser {
+			writer, _ := flate.NewWriter(w, flate.DefaultCompression)
+			return writer
+		})},
+	}
+
+	for _, tt := range tests {
+		var acceptEncoding string
+		server := newServer(t, func(w http.ResponseWriter, r *http.Request) {
+			acceptEncoding = r.Header.Get("Accept-Encoding")
+			if tt.encoding != "" {
+				w.Header().Set("Content-Encoding", tt.encoding)
+			}
+			w.Write(tt.body)
+		})
+
+		response, err := New(testOptions()).Get(context.Background(), server.URL+"/feed.xml", "")
+		if err != nil {
+			t.Errorf("%s: Get: %v", tt.encoding, err)
+			continue
+		}
+		if string(response.Body) != feed {
+			t.Errorf("%s: body = %q, want %q", tt.encoding, response.Body, feed)
+		}
+		if acceptEncoding != "gzip, deflate" {
+			t.Errorf("%s: Accept-Encoding = %q, want gzip, deflate", tt.encoding, acceptEncoding)
+		}
+	}
+
+	server := newServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "br")
+		w.Write([]byte(feed))
+	})
+	if _, err := New(testOptions()).Get(context.Background(), server.URL+"/feed.xml", ""); err == nil {
+		t.Error("Get succeeded with an encoding it didn't ask for")
+	}
+}
+
+func TestGetUsesProxy(t *testing.T) {
+	// the feed's host doesn't resolve, so it can only be reached through the proxy
+	var mu sync.Mutex
+	var proxied []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		proxied = append(proxied, r.URL.String())
+		mu.Unlock()
+
+		if r.URL.Path == "/robots.txt" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("<rss/>"))
+	}))
+	defer proxy.Close()
+
+	proxyURL, err := url.Parse(proxy.URL)
+	if err != nil {
+		t.Fatalf("failed to parse proxy URL: %v", err)
+	}
+
+	opts := testOptions()
+	opts.Proxy = proxyURL
+	response, err := New(opts).Get(context.Background(), "http://feeds.example.invalid/feed.xml", "")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if string(response.Body) != "<rss/>" {
+		t.Errorf("body = %q, want the proxy's answer", response.Body)
+	}
+
+	want := []string{"http://feeds.example.invalid/robots.txt", "http://feeds.example.invalid/feed.xml"}
+	if strings.Join(proxied, " ") != strings.Join(want, " ") {
+		t.Errorf("proxy got %v, want %v", proxied, want)
+	}
+}
+
+func TestOptionsFromConfigProxy(t *testing.T) {
+	opts, err := OptionsFromConfig(&config.HTTPConfig{Proxy: "http://proxy.internal:3128"})
+	if err != nil {
+		t.Fatalf("OptionsFromConfig: %v", err)
+	}
+	if opts.Proxy == nil || opts.Proxy.Host != "proxy.internal:3128" {
+		t.Errorf("proxy = %v, want http://proxy.internal:3128", opts.Proxy)
+	}
+
+	if _, err := OptionsFromConfig(&config.HTTPConfig{Proxy: "proxy.internal"}); err == nil {
+		t.Error("OptionsFromConfig accepted a proxy without a scheme")
+	}
+}
+
+func TestRedirectStripsHeadersAcrossHosts(t *testing.T) {
+	// two test servers listen on different ports, so they are different hosts
+	received := make(chan http.Header, 1)
+	target := newServer(t, func(w http.ResponseWriter, r *http.Request) {
+		received <- r.Header.Clone()
+		w.Write([]byte("<rss/>"))
+	})
+
+	tests := []struct {
+		name     string
+		location string
+		wantKept []string
+		wantGone []string
+	}{
+		{
+			name:     "same host",
+			location: "/feed.xml",
+			wantKept: []string{"User-Agent", "Accept", "X-Api-Key", "Authorization"},
+		},
+		{
+			name:     "other host",
+			location: target.URL + "/feed.xml",
+			wantKept: []string{"User-Agent", "Accept"},
+			wantGone: []string{"X-Api-Key", "Authorization", "Cookie"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			origin := newServer(t, func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/feed.xml" {
+					received <- r.Header.Clone()
+					w.Write([]byte("<rss/>"))
+					return
+				}
+				http.Redirect(w, r, tt.location, http.StatusFound)
+			})
+
+			header := http.Header{}
+			header.Set("X-Api-Key", "secret")
+			header.Set("Authorization", "Bearer secret")
+			header.Set("Cookie", "session=secret")
+
+			_, err := New(testOptions()).GetWithHeader(context.Background(), origin.URL+"/old-feed", "application/rss+xml", header)
+			if err != nil {
+				t.Fatalf("GetWithHeader: %v", err)
+			}
+
+			got := <-received
+			for _, name := range tt.wantKept {
+				if got.Get(name) == "" {
+					t.Errorf("%s was dropped", name)
+				}
+			}
+			for _, name := range tt.wantGone {
+				if value := got.Get(name); value != "" {
+					t.Errorf("%s = %q reached the other host", name, value)
+				}
+			}
+		})
+	}
+}
+
+func TestSameHost(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"https://example.com/feed.xml", "https://example.com/new/feed.xml", true},
+		{"http://example.com/feed.xml", "https://example.com/feed.xml", true},
+		{"https://Example.com/feed.xml", "https://example.COM/feed", true},
+		{"https://example.com/feed.xml", "https://feeds.example.com/feed.xml", false},
+		{"https://example.com/feed.xml", "https://example.com:8443/feed.xml", false},
+		{"https://example.com/feed.xml", "https://attacker.example/feed.xml", false},
+		{"https://example.com/feed.xml", "://", false},
+	}
+
+	for _, tt := range tests {
+		if got := SameHost(tt.a, tt.b); got != tt.want {
+			t.Errorf("SameHost(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,248 @@
+package fetch
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// robots.txt files are cached this long, or robotsRetry when the server failed to serve them
+	robotsTTL   = 24 * time.Hour
+	robotsRetry = 5 * time.Minute
+	// the most of a robots.txt that is read, as Google does
+	maxRobotsBytes = 500 * 1024
+)
+
+var productToken = regexp.MustCompile(`^[A-Za-z0-9_-]+`)
+
+type robotsRule struct {
+	allow   bool
+	pattern string
+}
+
+type robots struct {
+	rules      []robotsRule
+	crawlDelay time.Duration
+}
+
+// allows everything, for hosts without a robots.txt
+var allowAll = &robots{}
+
+// disallows everything, for hosts whose server fails to serve robots.txt
+var disallowAll = &robots{rules: []robotsRule{{allow: false, pattern: "/"}}}
+
+// the server failed to serve robots.txt (5xx) or asked to be left alone (429);
+// retryAfter is from its Retry-After header, zero without one
+type robotsUnreachableError struct {
+	status     int
+	retryAfter time.Duration
+}
+
+func (e *robotsUnreachableError) Error() string {
+	return fmt.Sprintf("robots.txt answered HTTP %d", e.status)
+}
+
+func (r *robots) allowed(u *url.URL) bool {
+	// the longest matching rule wins, allow over disallow on a tie
+
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	if u.RawQuery != "" {
+		path += "?" + u.RawQuery
+	}
+
+	allowed, longest := true, -1
+	for _, rule := range r.rules {
+		if !matchRobotsPattern(rule.pattern, path) {
+			continue
+		}
+		if len(rule.pattern) > longest || len(rule.pattern) == longest && rule.allow {
+			allowed, longest = rule.allow, len(rule.pattern)
+		}
+	}
+
+	return allowed
+}
+
+func matchRobotsPattern(pattern string, path string) bool {
+	// patterns match a path prefix, with * for any characters and a trailing $ for the end
+
+	anchored := strings.HasSuffix(pattern, "$")
+	pattern = strings.TrimSuffix(pattern, "$")
+
+	parts := strings.Split(pattern, "*")
+	if !strings.HasPrefix(path, parts[0]) {
+		return false
+	}
+	rest := path[len(parts[0]):]
+
+	for i, part := range parts[1:] {
+		// the last part has to end the path when anchored, so it is matched from the end
+		if anchored && i == len(parts)-2 {
+			return strings.HasSuffix(rest, part)
+		}
+		index := strings.Index(rest, part)
+		if index < 0 {
+			return false
+		}
+		rest = rest[index+len(part):]
+	}
+
+	return !anchored || rest == ""
+}
+
+func parseRobots(body []byte, userAgent string) *robots {
+	// keeps the rules of the groups naming our product token, or of the * groups if none do
+
+	token := strings.ToLower(productToken.FindString(userAgent))
+
+	var specific, wildcard robots
+	var foundSpecific bool
+
+	// the groups the current lines apply to; consecutive user-agent lines share one group
+	var inSpecific, inWildcard, readingAgents bool
+
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		field, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		field = strings.ToLower(strings.TrimSpace(field))
+		value = strings.TrimSpace(value)
+
+		if field == "user-agent" {
+			if !readingAgents {
+				inSpecific, inWildcard = false, false
+			}
+			readingAgents = true
+
+			agent := strings.ToLower(value)
+			switch {
+			case agent == "*":
+				inWildcard = true
+			case token != "" && agent == token:
+				inSpecific, foundSpecific = true, true
+			}
+			continue
+		}
+		readingAgents = false
+
+		var target []*robots
+		if inSpecific {
+			target = append(target, &specific)
+		}
+		if inWildcard {
+			target = append(target, &wildcard)
+		}
+
+		for _, group := range target {
+			switch field {
+			case "allow", "disallow":
+				// an empty disallow allows everything, which is the default anyway
+				if value != "" {
+					group.rules = append(group.rules, robotsRule{allow: field == "allow", pattern: value})
+				}
+			case "crawl-delay":
+				if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds > 0 {
+					group.crawlDelay = time.Duration(seconds * float64(time.Second))
+				}
+			}
+		}
+	}
+
+	if foundSpecific {
+		return &specific
+	}
+	return &wildcard
+}
+
+func (c *Client) robotsFor(ctx context.Context, name string, h *host, u *url.URL) (*robots, error) {
+	// the host's robots.txt, fetched at most once per robotsTTL and through the host's
+	// limiter like any other request; a missing file allows everything, one the server
+	// fails to serve or is too busy to serve disallows everything until it is tried
+	// again after robotsRetry or its Retry-After (RFC 9309 2.3.1.4), unless an
+	// earlier copy can stand in for it
+
+	h.robotsMu.Lock()
+	defer h.robotsMu.Unlock()
+
+	if h.robots == nil || !time.Now().Before(h.robotsExpiry) {
+		rules, err := c.fetchRobots(ctx, name, h, u)
+		var unreachable *robotsUnreachableError
+		switch {
+		case errors.As(err, &unreachable):
+			if h.robots == nil {
+				h.robots = disallowAll
+			}
+			h.robotsExpiry = time.Now().Add(max(robotsRetry, unreachable.retryAfter))
+		case err != nil:
+			return nil, err
+		default:
+			h.robots, h.robotsExpiry = rules, time.Now().Add(robotsTTL)
+		}
+	}
+
+	if h.robots == disallowAll {
+		retry := &RetryAfterError{Host: name, After: time.Until(h.robotsExpiry)}
+		return nil, fmt.Errorf("robots.txt is unavailable, so everything is disallowed: %w", retry)
+	}
+
+	return h.robots, nil
+}
+
+func (c *Client) fetchRobots(ctx context.Context, name string, h *host, u *url.URL) (*robots, error) {
+	release, err := c.acquire(ctx, name, h, c.hostDelay)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	robotsURL := &url.URL{Scheme: u.Scheme, Host: u.Host, Path: "/robots.txt"}
+	request, err := http.NewRequestWithContext(ctx, "GET", robotsURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("User-Agent", c.userAgent)
+
+	// without an answer nothing is cached, and the request waiting on it fails like
+	// the host's feeds would
+	response, err := c.http.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch robots.txt: %w", err)
+	}
+	defer response.Body.Close()
+
+	switch {
+	case response.StatusCode >= 200 && response.StatusCode <= 299:
+		body, err := io.ReadAll(io.LimitReader(response.Body, maxRobotsBytes))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read robots.txt: %w", err)
+		}
+		return parseRobots(body, c.userAgent), nil
+	case response.StatusCode == http.StatusTooManyRequests || response.StatusCode >= 500:
+		// the whole host is left alone for as long as it asked, as for any other request
+		unreachable := &robotsUnreachableError{status: response.StatusCode}
+		if after, ok := retryAfter(response.Header.Get("Retry-After")); ok {
+			unreachable.retryAfter = after
+			h.backOff(time.Now().Add(after))
+		}
+		return nil, unreachable
+	}
+
+	// no robots.txt, nothing is off limits
+	return allowAll, nil
+}
@@ -0,0 +1,368 @@
+package fetch
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// robotsServer serves robots.txt with whatever status and body it is set to, and
+// counts the requests for it and for everything else
+type robotsServer struct {
+	*httptest.Server
+
+	mu     sync.Mutex
+	status int
+	body   string
+	// sent as Retry-After with robots.txt when set
+	retryAfter string
+	robots     []time.Time
+	requests   []time.Time
+}
+
+func newRobotsServer(t *testing.T, status int, body string) *robotsServer {
+	s := &robotsServer{status: status, body: body}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		if r.URL.Path == "/robots.txt" {
+			s.robots = append(s.robots, time.Now())
+			if s.retryAfter != "" {
+				w.Header().Set("Retry-After", s.retryAfter)
+			}
+			w.WriteHeader(s.status)
+			w.Write([]byte(s.body))
+			return
+		}
+		s.requests = append(s.requests, time.Now())
+		w.Write([]byte("<rss/>"))
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *robotsServer) serve(status int, body string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status, s.body = status, body
+}
+
+func (s *robotsServer) counts() (robots int, requests int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.robots), len(s.requests)
+}
+
+// expireRobots makes the client fetch the server's robots.txt again on the next request
+func expireRobots(t *testing.T, client *Client, server *httptest.Server) {
+	serverURL, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatalf("failed to parse server URL: %v", err)
+	}
+
+	h := client.host(strings.ToLower(serverURL.Host))
+	h.robotsMu.Lock()
+	h.robotsExpiry = time.Now().Add(-time.Second)
+	h.robotsMu.Unlock()
+}
+
+func TestRobotsServerErrorDisallowsAll(t *testing.T) {
+	server := newRobotsServer(t, http.StatusServiceUnavailable, "")
+	client := New(testOptions())
+
+	for i := 0; i < 2; i++ {
+		_, err := client.Get(context.Background(), server.URL+"/feed.xml", "")
+
+		var retry *RetryAfterError
+		if !errors.As(err, &retry) {
+			t.Fatalf("Get error = %v, want a RetryAfterError", err)
+		}
+		if retry.After <= 0 || retry.After > robotsRetry {
+			t.Errorf("retry in %v, want at most %v", retry.After, robotsRetry)
+		}
+	}
+
+	// the failure is cached for a short while like a robots.txt would be
+	if robots, requests := server.counts(); robots != 1 || requests != 0 {
+		t.Fatalf("server got %d robots.txt and %d other requests, want 1 and 0", robots, requests)
+	}
+
+	// once it is served again, so is everything else
+	server.serve(http.StatusNotFound, "")
+	expireRobots(t, client, server.Server)
+	if _, err := client.Get(context.Background(), server.URL+"/feed.xml", ""); err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if _, requests := server.counts(); requests != 1 {
+		t.Errorf("server got %d requests for the feed, want 1", requests)
+	}
+}
+
+func TestRobotsTooManyRequestsDisallowsAll(t *testing.T) {
+	// a host too busy to serve robots.txt is treated like one that is down, for at
+	// least as long as it asked
+	tests := []struct {
+		name       string
+		status     int
+		retryAfter string
+		wantAfter  time.Duration
+	}{
+		{name: "429", status: http.StatusTooManyRequests, wantAfter: robotsRetry},
+		{name: "429 with Retry-After", status: http.StatusTooManyRequests, retryAfter: "3600", wantAfter: time.Hour},
+		{name: "short Retry-After", status: http.StatusTooManyRequests, retryAfter: "10", wantAfter: robotsRetry},
+		{name: "503 with Retry-After", status: http.StatusServiceUnavailable, retryAfter: "7200", wantAfter: 2 * time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newRobotsServer(t, tt.status, "")
+			server.retryAfter = tt.retryAfter
+			client := New(testOptions())
+
+			for i := 0; i < 2; i++ {
+				_, err := client.Get(context.Background(), server.URL+"/feed.xml", "")
+
+				var retry *RetryAfterError
+				if !errors.As(err, &retry) {
+					t.Fatalf("Get error = %v, want a RetryAfterError", err)
+				}
+				if retry.After < tt.wantAfter-time.Minute || retry.After > tt.wantAfter {
+					t.Errorf("retry in %v, want about %v", retry.After, tt.wantAfter)
+				}
+			}
+
+			if robots, requests := server.counts(); robots != 1 || requests != 0 {
+				t.Errorf("server got %d robots.txt and %d other requests, want 1 and 0", robots, requests)
+			}
+		})
+	}
+}
+
+func TestRobotsTooManyRequestsKeepsEarlierCopy(t *testing.T) {
+	// an earlier copy still stands in, but the host is left alone as long as it asked
+	server := newRobotsServer(t, http.StatusOK, "User-agent: *\nAllow: /\n")
+	client := New(testOptions())
+
+	if _, err := client.Get(context.Background(), server.URL+"/feed.xml", ""); err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+
+	server.serve(http.StatusTooManyRequests, "")
+	server.mu.Lock()
+	server.retryAfter = "600"
+	server.mu.Unlock()
+	expireRobots(t, client, server.Server)
+
+	_, err := client.Get(context.Background(), server.URL+"/feed.xml", "")
+	var retry *RetryAfterError
+	if !errors.As(err, &retry) || retry.After < 9*time.Minute {
+		t.Fatalf("Get error = %v, want a RetryAfterError of about 10m", err)
+	}
+	if robots, requests := server.counts(); robots != 2 || requests != 1 {
+		t.Errorf("server got %d robots.txt and %d other requests, want 2 and 1", robots, requests)
+	}
+}
+
+func TestRobotsServerErrorKeepsEarlierCopy(t *testing.T) {
+	server := newRobotsServer(t, http.StatusOK, "User-agent: *\nDisallow: /private/\n")
+	client := New(testOptions())
+
+	if _, err := client.Get(context.Background(), server.URL+"/feed.xml", ""); err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+
+	server.serve(http.StatusInternalServerError, "")
+	expireRobots(t, client, server.Server)
+
+	if _, err := client.Get(context.Background(), server.URL+"/feed.xml", ""); err != nil {
+		t.Errorf("Get of an allowed page: %v", err)
+	}
+	if _, err := client.Get(context.Background(), server.URL+"/private/feed.xml", ""); !errors.Is(err, ErrDisallowed) {
+		t.Errorf("Get of a disallowed page: error = %v, want ErrDisallowed", err)
+	}
+	if robots, _ := server.counts(); robots != 2 {
+		t.Errorf("server got %d robots.txt requests, want 2", robots)
+	}
+}
+
+func TestRobotsNetworkErrorIsNotCached(t *testing.T) {
+	server := newRobotsServer(t, http.StatusNotFound, "")
+	serverURL := server.URL
+	server.Close()
+
+	client := New(testOptions())
+	_, err := client.Get(context.Background(), serverURL+"/feed.xml", "")
+	if err == nil {
+		t.Fatal("Get succeeded against a closed server")
+	}
+
+	var retry *RetryAfterError
+	if errors.Is(err, ErrDisallowed) || errors.As(err, &retry) {
+		t.Errorf("Get error = %v, want the connection error", err)
+	}
+
+	parsed, _ := url.Parse(serverURL)
+	if h := client.host(parsed.Host); h.robots != nil {
+		t.Errorf("robots.txt cached after a connection error")
+	}
+}
+
+func TestRobotsFetchWaitsForHost(t *testing.T) {
+	// robots.txt counts as a request to the host, so the feed waits its turn after it
+	server := newRobotsServer(t, http.StatusOK, "User-agent: *\nAllow: /\n")
+
+	opts := testOptions()
+	opts.HostDelay = 300 * time.Millisecond
+	client := New(opts)
+
+	if _, err := client.Get(context.Background(), server.URL+"/feed.xml", ""); err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	if len(server.robots) != 1 || len(server.requests) != 1 {
+		t.Fatalf("server got %d robots.txt and %d other requests, want 1 and 1", len(server.robots), len(server.requests))
+	}
+	if gap := server.requests[0].Sub(server.robots[0]); gap < opts.HostDelay-10*time.Millisecond {
+		t.Errorf("feed requested %v after robots.txt, want at least %v", gap, opts.HostDelay)
+	}
+}
+
+func TestRedirectChecksEveryHop(t *testing.T) {
+	target := newRobotsServer(t, http.StatusOK, "User-agent: *\nDisallow: /private/\n")
+
+	tests := []struct {
+		name         string
+		location     string
+		wantErr      error
+		wantRequests int
+	}{
+		{name: "allowed on the other host", location: target.URL + "/feed.xml", wantRequests: 1},
+		{name: "disallowed on the other host", location: target.URL + "/private/feed.xml", wantErr: ErrDisallowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			origin := newServer(t, func(w http.ResponseWriter, r *http.Request) {
+				http.Redirect(w, r, tt.location, http.StatusMovedPermanently)
+			})
+
+			_, before := target.counts()
+			_, err := New(testOptions()).Get(context.Background(), origin.URL+"/feed.xml", "")
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Get error = %v, want %v", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatalf("Get: %v", err)
+			}
+
+			if _, after := target.counts(); after-before != tt.wantRequests {
+				t.Errorf("other host got %d requests, want %d", after-before, tt.wantRequests)
+			}
+		})
+	}
+}
+
+func TestRedirectWaitsForEachHost(t *testing.T) {
+	// a hop to another host takes a turn with that host like any request to it,
+	// keeping to the other host's Crawl-delay
+	const crawlDelay = 500 * time.Millisecond
+	target := newRobotsServer(t, http.StatusOK, "User-agent: *\nCrawl-delay: 0.5\n")
+	origin := newServer(t, func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, target.URL+"/feed.xml", http.StatusFound)
+	})
+
+	client := New(testOptions())
+
+	if _, err := client.Get(context.Background(), target.URL+"/other.xml", ""); err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if _, err := client.Get(context.Background(), origin.URL+"/feed.xml", ""); err != nil {
+		t.Fatalf("Get through a redirect: %v", err)
+	}
+
+	target.mu.Lock()
+	defer target.mu.Unlock()
+	if len(target.requests) != 2 {
+		t.Fatalf("other host got %d requests, want 2", len(target.requests))
+	}
+	if gap := target.requests[1].Sub(target.requests[0]); gap < crawlDelay-10*time.Millisecond {
+		t.Errorf("redirect reached the other host %v after its last request, want at least %v", gap, crawlDelay)
+	}
+}
+
+func TestRedirectOnSameHostWithOneSlot(t *testing.T) {
+	// the hop doesn't wait for a second slot of the host the request already holds
+	server := newServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/old-feed" {
+			http.Redirect(w, r, "/feed.xml", http.StatusMovedPermanently)
+			return
+		}
+		w.Write([]byte("<rss/>"))
+	})
+
+	opts := testOptions()
+	opts.HostConcurrency = 1
+	opts.Timeout = 2 * time.Second
+
+	response, err := New(opts).Get(context.Background(), server.URL+"/old-feed", "")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if string(response.Body) != "<rss/>" {
+		t.Errorf("body = %q", response.Body)
+	}
+}
+
+func TestRobotsAllowed(t *testing.T) {
+	rules := parseRobots([]byte(`
+User-agent: *
+Disallow: /
+
+User-agent: gator
+Disallow: /private/
+Allow: /private/feed.xml
+Disallow: /*.pdf$
+Crawl-delay: 2
+`), "gator/1.0 (+https://example.com)")
+
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"/", true},
+		{"/feed.xml", true},
+		{"/private/", false},
+		{"/private/notes.html", false},
+		{"/private/feed.xml", true},
+		{"/docs/guide.pdf", false},
+		{"/docs/guide.pdf?download=1", true},
+	}
+
+	for _, tt := range tests {
+		u, err := url.Parse("https://example.com" + tt.path)
+		if err != nil {
+			t.Fatalf("failed to parse %s: %v", tt.path, err)
+		}
+		if got := rules.allowed(u); got != tt.want {
+			t.Errorf("allowed(%s) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+
+	if rules.crawlDelay != 2*time.Second {
+		t.Errorf("crawl delay = %v, want 2s", rules.crawlDelay)
+	}
+
+	if disallowAll.allowed(&url.URL{Scheme: "https", Host: "example.com"}) {
+		t.Error("disallowAll allows the root")
+	}
+}
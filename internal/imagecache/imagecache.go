@@ -0,0 +1,196 @@
+package imagecache
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"blog-aggregator/internal/database"
+)
+
+const (
+	// thumbnails are scaled down so their longer side is at most this many pixels
+	MaxThumbnailSize = 320
+
+	// images bigger than this are not worth downloading for a thumbnail
+	maxImageBytes  = 10 * 1024 * 1024
+	maxImagePixels = 40_000_000
+
+	batchSize = 100
+)
+
+var DefaultClient = &http.Client{Timeout: 20 * time.Second}
+
+var ErrTooLarge = errors.New("image too large")
+
+func Path(dir string, imageURL string) string {
+	// thumbnails are named after the hash of their URL, spread over 256 subdirectories
+	sum := sha256.Sum256([]byte(imageURL))
+	name := hex.EncodeToString(sum[:])
+	return filepath.Join(dir, name[:2], name+".jpg")
+}
+
+func Lookup(dir string, imageURL string) (string, bool) {
+	path := Path(dir, imageURL)
+	if _, err := os.Stat(path); err != nil {
+		return "", false
+	}
+	return path, true
+}
+
+func Store(ctx context.Context, client *http.Client, dir string, imageURL string) (string, error) {
+	// downloads an image and saves a JPEG thumbnail of it
+
+	request, err := http.NewRequestWithContext(ctx, "GET", imageURL, nil)
+	if err != nil {
+		return "", err
+	}
+	request.Header.Set("User-Agent", "gator")
+	request.Header.Set("Accept", "image/jpeg,image/png,image/gif")
+
+	response, err := client.Do(request)
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("HTTP Error: %d", response.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(response.Body, maxImageBytes+1))
+	if err != nil {
+		return "", err
+	}
+	if len(data) > maxImageBytes {
+		return "", ErrTooLarge
+	}
+
+	// checked before decoding, a small file can still decode into a huge image
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return "", fmt.Errorf("failed to read image: %w", err)
+	}
+	if config.Width*config.Height > maxImagePixels {
+		return "", ErrTooLarge
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return "", fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	var out bytes.Buffer
+	if err := jpeg.Encode(&out, thumbnail(img, MaxThumbnailSize), &jpeg.Options{Quality: 80}); err != nil {
+		return "", fmt.Errorf("failed to encode thumbnail: %w", err)
+	}
+
+	path := Path(dir, imageURL)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	// written next to its final name first, so a half written file is never picked up
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, out.Bytes(), 0o644); err != nil {
+		return "", fmt.Errorf("failed to write thumbnail: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return "", fmt.Errorf("failed to write thumbnail: %w", err)
+	}
+
+	return path, nil
+}
+
+func thumbnail(src image.Image, maxSize int) image.Image {
+	// scales down by averaging the source pixels each target pixel covers,
+	// flattening transparency onto white since JPEG has none
+
+	bounds := src.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+
+	targetWidth, targetHeight := width, height
+	if width > maxSize || height > maxSize {
+		if width >= height {
+			targetWidth, targetHeight = maxSize, max(1, height*maxSize/width)
+		} else {
+			targetWidth, targetHeight = max(1, width*maxSize/height), maxSize
+		}
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, targetWidth, targetHeight))
+
+	for y := 0; y < targetHeight; y++ {
+		y0 := bounds.Min.Y + y*height/targetHeight
+		y1 := max(y0+1, bounds.Min.Y+(y+1)*height/targetHeight)
+
+		for x := 0; x < targetWidth; x++ {
+			x0 := bounds.Min.X + x*width/targetWidth
+			x1 := max(x0+1, bounds.Min.X+(x+1)*width/targetWidth)
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					r, g, b, a = r+uint64(cr), g+uint64(cg), b+uint64(cb), a+uint64(ca)
+					n++
+				}
+			}
+
+			// colors are premultiplied, so adding the missing alpha blends in white
+			white := 0xffff - a/n
+			dst.SetRGBA(x, y, color.RGBA{
+				R: uint8((r/n + white) >> 8),
+				G: uint8((g/n + white) >> 8),
+				B: uint8((b/n + white) >> 8),
+				A: 0xff,
+			})
+		}
+	}
+
+	return dst
+}
+
+func CacheRecent(ctx context.Context, db *database.Queries, client *http.Client, dir string, since time.Time) (cached int, failed int, err error) {
+	// caches thumbnails for the images of posts collected since the given time
+	// that aren't cached yet
+
+	posts, err := db.GetPostImagesSince(ctx, database.GetPostImagesSinceParams{
+		CreatedAt: since,
+		Limit:     batchSize,
+	})
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get post images: %w", err)
+	}
+
+	for _, post := range posts {
+		if ctx.Err() != nil {
+			break
+		}
+		if _, ok := Lookup(dir, post.ImageUrl.String); ok {
+			continue
+		}
+
+		if _, err := Store(ctx, client, dir, post.ImageUrl.String); err != nil {
+			fmt.Printf("Error caching image %s: %v\n", post.ImageUrl.String, err)
+			failed++
+			continue
+		}
+		cached++
+	}
+
+	return cached, failed, nil
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 
+	"blog-aggregator/internal/auth"
 	"blog-aggregator/internal/commands"
 	"blog-aggregator/internal/database"
 	"blog-aggregator/internal/state"
@@ -24,6 +25,22 @@ func MiddlewareLoggedIn(handler func(s *state.State, cmd commands.Command, user
 			return fmt.Errorf("failed to get user %s: %w", currentUser, err)
 		}
 
+		if user.DisabledAt.Valid {
+			return fmt.Errorf("user %s is disabled", currentUser)
+		}
+
 		return handler(s, cmd, user)
 	}
-}
\ No newline at end of file
+}
+
+func MiddlewareRole(role string, handler func(s *state.State, cmd commands.Command, user database.User) error) func(*state.State, commands.Command) error {
+	// same as MiddlewareLoggedIn, but the current user must also hold at least the given role
+
+	return MiddlewareLoggedIn(func(s *state.State, cmd commands.Command, user database.User) error {
+		if !auth.Allows(user.Role, role) {
+			return fmt.Errorf("command %s requires the %s role, user %s is %s", cmd.Name, role, user.Name, user.Role)
+		}
+
+		return handler(s, cmd, user)
+	})
+}
@@ -0,0 +1,166 @@
+package podcast
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// unfinished downloads are kept under this suffix so the next run can resume them
+const partSuffix = ".part"
+
+// episodes can take a long time to download, so only waiting for the server is limited
+var DefaultClient = &http.Client{
+	Transport: &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		ResponseHeaderTimeout: 30 * time.Second,
+	},
+}
+
+var unsafeChars = regexp.MustCompile(`[^\p{L}\p{N} ._()&,'-]+`)
+
+func FileName(title string, published time.Time, enclosureURL string, mimeType string) string {
+	// "2024-05-01 Episode title.mp3", safe on every common filesystem
+
+	name := strings.Join(strings.Fields(unsafeChars.ReplaceAllString(title, " ")), " ")
+	if len(name) > 120 {
+		name = strings.TrimSpace(name[:120])
+	}
+	if name == "" {
+		name = "episode"
+	}
+	if !published.IsZero() {
+		name = published.Format("2006-01-02") + " " + name
+	}
+
+	return name + extension(enclosureURL, mimeType)
+}
+
+func DirName(feedName string) string {
+	name := strings.Join(strings.Fields(unsafeChars.ReplaceAllString(feedName, " ")), " ")
+	name = strings.Trim(name, ".")
+	if name == "" {
+		return "feed"
+	}
+	return name
+}
+
+func extension(enclosureURL string, mimeType string) string {
+	if parsed, err := url.Parse(enclosureURL); err == nil {
+		if ext := path.Ext(parsed.Path); len(ext) > 1 && len(ext) <= 5 {
+			return strings.ToLower(ext)
+		}
+	}
+
+	if exts, err := mime.ExtensionsByType(mimeType); err == nil && len(exts) > 0 {
+		return exts[0]
+	}
+
+	return ".bin"
+}
+
+func Download(ctx context.Context, client *http.Client, enclosureURL string, dest string) (int64, error) {
+	// downloads into dest.part, resuming it with a Range request if an earlier
+	// run was interrupted, and renames it to dest once complete
+
+	if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+		return 0, fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	partPath := dest + partSuffix
+
+	var offset int64
+	if info, err := os.Stat(partPath); err == nil {
+		offset = info.Size()
+	}
+
+	request, err := http.NewRequestWithContext(ctx, "GET", enclosureURL, nil)
+	if err != nil {
+		return 0, err
+	}
+	request.Header.Set("User-Agent", "gator")
+	if offset > 0 {
+		request.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	response, err := client.Do(request)
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+
+	flags := os.O_CREATE | os.O_WRONLY
+	switch {
+	case response.StatusCode == http.StatusPartialContent && resumesAt(response, offset):
+		flags |= os.O_APPEND
+	case response.StatusCode == http.StatusRequestedRangeNotSatisfiable && offset > 0:
+		// the part file already has every byte
+		return offset, os.Rename(partPath, dest)
+	case response.StatusCode == http.StatusPartialContent:
+		os.Remove(partPath)
+		return 0, errors.New("server resumed at the wrong offset, the next run starts over")
+	case response.StatusCode == http.StatusOK:
+		// the server ignored the range, start over
+		flags |= os.O_TRUNC
+		offset = 0
+	default:
+		return 0, fmt.Errorf("HTTP Error: %d", response.StatusCode)
+	}
+
+	file, err := os.OpenFile(partPath, flags, 0o644)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open %s: %w", partPath, err)
+	}
+
+	written, err := io.Copy(file, response.Body)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return 0, fmt.Errorf("download interrupted after %d bytes, run it again to resume: %w", offset+written, err)
+	}
+
+	if err := os.Rename(partPath, dest); err != nil {
+		return 0, fmt.Errorf("failed to move %s into place: %w", partPath, err)
+	}
+
+	return offset + written, nil
+}
+
+func resumesAt(response *http.Response, offset int64) bool {
+	// Content-Range: bytes <start>-<end>/<size>
+
+	contentRange := response.Header.Get("Content-Range")
+	rest, ok := strings.CutPrefix(contentRange, "bytes ")
+	if !ok {
+		return false
+	}
+
+	start, _, ok := strings.Cut(rest, "-")
+	if !ok {
+		return false
+	}
+
+	n, err := strconv.ParseInt(start, 10, 64)
+	return err == nil && n == offset
+}
+
+func Remove(path string) error {
+	// a file the user already deleted by hand is not an error
+	err := os.Remove(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
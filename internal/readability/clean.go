@@ -0,0 +1,276 @@
+package readability
+
+import (
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// tags kept in the cleaned HTML; everything else is unwrapped down to its children
+var allowedTags = map[atom.Atom]bool{
+	atom.P: true, atom.Br: true, atom.Hr: true,
+	atom.H1: true, atom.H2: true, atom.H3: true, atom.H4: true, atom.H5: true, atom.H6: true,
+	atom.Ul: true, atom.Ol: true, atom.Li: true, atom.Dl: true, atom.Dt: true, atom.Dd: true,
+	atom.Blockquote: true, atom.Pre: true, atom.Code: true,
+	atom.Em: true, atom.Strong: true, atom.B: true, atom.I: true, atom.U: true, atom.S: true,
+	atom.Sub: true, atom.Sup: true, atom.A: true, atom.Img: true,
+	atom.Figure: true, atom.Figcaption: true,
+	atom.Table: true, atom.Thead: true, atom.Tbody: true, atom.Tr: true, atom.Th: true, atom.Td: true,
+}
+
+var voidTags = map[atom.Atom]bool{
+	atom.Br:  true,
+	atom.Hr:  true,
+	atom.Img: true,
+}
+
+// tags that start a new block in the plain text version
+var blockTags = map[atom.Atom]bool{
+	atom.P: true, atom.Div: true, atom.Section: true, atom.Article: true, atom.Main: true,
+	atom.H1: true, atom.H2: true, atom.H3: true, atom.H4: true, atom.H5: true, atom.H6: true,
+	atom.Ul: true, atom.Ol: true, atom.Dl: true, atom.Blockquote: true, atom.Pre: true,
+	atom.Figure: true, atom.Table: true, atom.Tr: true, atom.Hr: true,
+}
+
+var (
+	spaces     = regexp.MustCompile(`[ \t\r\f\v]+`)
+	blankLines = regexp.MustCompile(`\n{3,}`)
+	cdata      = regexp.MustCompile(`<!\[CDATA\[|\]\]>`)
+)
+
+// hosts that only serve tracking pixels and counters
+var trackerHosts = map[string]bool{
+	"pixel.wp.com":              true,
+	"stats.wordpress.com":       true,
+	"www.google-analytics.com":  true,
+	"google-analytics.com":      true,
+	"ad.doubleclick.net":        true,
+	"feedads.g.doubleclick.net": true,
+	"pixel.quantserve.com":      true,
+	"sb.scorecardresearch.com":  true,
+	"feeds.feedblitz.com":       true,
+	"www.facebook.com/tr":       true,
+}
+
+// feedburner serves real feeds too, only these paths are its pixels and share buttons
+var trackerPaths = []string{
+	"feeds.feedburner.com/~r/",
+	"feeds.feedburner.com/~ff/",
+	"feeds.feedburner.com/~a/",
+}
+
+// query parameters that only identify the campaign or click a link came from
+var trackingParams = map[string]bool{
+	"fbclid": true,
+	"gclid":  true,
+	"mc_cid": true,
+	"mc_eid": true,
+	"_hsenc": true,
+	"_hsmi":  true,
+}
+
+func writeClean(out *strings.Builder, node *html.Node, base *url.URL) {
+	switch node.Type {
+	case html.TextNode:
+		out.WriteString(html.EscapeString(node.Data))
+		return
+	case html.ElementNode:
+	default:
+		return
+	}
+
+	if !allowedTags[node.DataAtom] {
+		// a bare container of text reads best as a paragraph
+		if blockTags[node.DataAtom] && !hasBlockChild(node) && textContent(node) != "" {
+			out.WriteString("<p>")
+			writeChildren(out, node, base)
+			out.WriteString("</p>")
+			return
+		}
+
+		writeChildren(out, node, base)
+		return
+	}
+
+	attrs := ""
+	switch node.DataAtom {
+	case atom.A:
+		href := resolve(base, attr(node, "href"), "http", "https", "mailto")
+		if isTracker(href) {
+			writeChildren(out, node, base)
+			return
+		}
+		if href != "" {
+			attrs = ` href="` + html.EscapeString(stripTrackingParams(href)) + `"`
+		}
+	case atom.Img:
+		src := resolve(base, attr(node, "src"), "http", "https")
+		if src == "" {
+			// lazy loaded images keep the real URL elsewhere
+			src = resolve(base, attr(node, "data-src"), "http", "https")
+		}
+		if src == "" || isTracker(src) || isPixel(node) {
+			return
+		}
+		attrs = ` src="` + html.EscapeString(src) + `"`
+		if alt := attr(node, "alt"); alt != "" {
+			attrs += ` alt="` + html.EscapeString(alt) + `"`
+		}
+	}
+
+	out.WriteString("<" + node.Data + attrs + ">")
+
+	if voidTags[node.DataAtom] {
+		return
+	}
+
+	writeChildren(out, node, base)
+	out.WriteString("</" + node.Data + ">")
+}
+
+func isTracker(link string) bool {
+	parsed, err := url.Parse(link)
+	if err != nil || parsed.Host == "" {
+		return false
+	}
+
+	hostPath := strings.ToLower(parsed.Host) + parsed.Path
+	if trackerHosts[strings.ToLower(parsed.Host)] || trackerHosts[hostPath] {
+		return true
+	}
+	for _, prefix := range trackerPaths {
+		if strings.HasPrefix(hostPath, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isPixel(node *html.Node) bool {
+	// images sized 1x1 (or 0) exist to count readers, not to be looked at
+	for _, key := range []string{"width", "height"} {
+		value := strings.TrimSuffix(strings.TrimSpace(attr(node, key)), "px")
+		if n, err := strconv.Atoi(value); err == nil && n <= 1 {
+			return true
+		}
+	}
+	return false
+}
+
+func stripTrackingParams(link string) string {
+	parsed, err := url.Parse(link)
+	if err != nil || parsed.RawQuery == "" {
+		return link
+	}
+
+	query := parsed.Query()
+	changed := false
+	for key := range query {
+		if strings.HasPrefix(strings.ToLower(key), "utm_") || trackingParams[strings.ToLower(key)] {
+			query.Del(key)
+			changed = true
+		}
+	}
+	if !changed {
+		return link
+	}
+
+	parsed.RawQuery = query.Encode()
+	return parsed.String()
+}
+
+func writeChildren(out *strings.Builder, node *html.Node, base *url.URL) {
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		writeClean(out, child, base)
+	}
+}
+
+func hasBlockChild(node *html.Node) bool {
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type == html.ElementNode && (blockTags[child.DataAtom] || child.DataAtom == atom.Li) {
+			return true
+		}
+	}
+	return false
+}
+
+func resolve(base *url.URL, ref string, schemes ...string) string {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return ""
+	}
+
+	parsed, err := url.Parse(ref)
+	if err != nil {
+		return ""
+	}
+
+	if base != nil {
+		parsed = base.ResolveReference(parsed)
+	}
+
+	for _, scheme := range schemes {
+		if parsed.Scheme == scheme {
+			return parsed.String()
+		}
+	}
+
+	return ""
+}
+
+func writeText(out *strings.Builder, node *html.Node) {
+	switch node.Type {
+	case html.TextNode:
+		if node.Parent != nil && node.Parent.DataAtom == atom.Pre {
+			out.WriteString(node.Data)
+			return
+		}
+		out.WriteString(whitespace.ReplaceAllString(node.Data, " "))
+		return
+	case html.ElementNode:
+	default:
+		return
+	}
+
+	switch node.DataAtom {
+	case atom.Br:
+		out.WriteString("\n")
+		return
+	case atom.Img:
+		if alt := attr(node, "alt"); alt != "" {
+			out.WriteString("[" + alt + "]")
+		}
+		return
+	case atom.Li:
+		out.WriteString("\n- ")
+	case atom.Td, atom.Th:
+		out.WriteString(" ")
+	}
+
+	block := blockTags[node.DataAtom]
+	if block {
+		out.WriteString("\n\n")
+	}
+
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		writeText(out, child)
+	}
+
+	if block {
+		out.WriteString("\n\n")
+	}
+}
+
+func tidyText(text string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(spaces.ReplaceAllString(line, " "))
+	}
+
+	return strings.TrimSpace(blankLines.ReplaceAllString(strings.Join(lines, "\n"), "\n\n"))
+}
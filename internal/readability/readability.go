@@ -0,0 +1,400 @@
+package readability
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"blog-aggregator/internal/fetch"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"golang.org/x/net/html/charset"
+)
+
+const (
+	// pages bigger than this are cut off, articles are never anywhere near it
+	maxPageSize = 5 * 1024 * 1024
+
+	// blocks shorter than this don't count as article text
+	minParagraphLength = 25
+
+	// extractions with less text than this are treated as failures
+	minArticleLength = 200
+)
+
+var ErrNoArticle = errors.New("no article content found")
+
+var (
+	unlikelyCandidates = regexp.MustCompile(`(?i)banner|breadcrumb|combx|comment|community|cookie|disqus|extra|footer|header|menu|modal|nav|newsletter|pager|pagination|popup|promo|related|remark|rss|share|shoutbox|sidebar|skyscraper|social|sponsor|subscribe|tags|tool|widget|ad-break|advert`)
+	maybeCandidate     = regexp.MustCompile(`(?i)and|article|body|column|content|main|shadow`)
+	positiveNames      = regexp.MustCompile(`(?i)article|body|content|entry|hentry|h-entry|main|page|post|story|text|blog`)
+	negativeNames      = regexp.MustCompile(`(?i)hidden|banner|combx|comment|com-|contact|footer|footnote|masthead|media|meta|outbrain|promo|related|scroll|share|shoutbox|sidebar|skyscraper|sponsor|shopping|tags|tool|widget|byline|author`)
+	whitespace         = regexp.MustCompile(`\s+`)
+)
+
+// elements that never hold article text
+var removedTags = map[atom.Atom]bool{
+	atom.Script:   true,
+	atom.Style:    true,
+	atom.Noscript: true,
+	atom.Iframe:   true,
+	atom.Form:     true,
+	atom.Button:   true,
+	atom.Input:    true,
+	atom.Select:   true,
+	atom.Textarea: true,
+	atom.Svg:      true,
+	atom.Nav:      true,
+	atom.Aside:    true,
+	atom.Footer:   true,
+	atom.Header:   true,
+	atom.Object:   true,
+	atom.Embed:    true,
+	atom.Link:     true,
+	atom.Meta:     true,
+}
+
+type Article struct {
+	HTML string
+	Text string
+}
+
+func Fetch(ctx context.Context, client *fetch.Client, pageURL string) (Article, error) {
+	// downloads a page and extracts its article
+
+	response, err := client.Get(ctx, pageURL, "text/html,application/xhtml+xml")
+	if err != nil {
+		return Article{}, err
+	}
+
+	if response.StatusCode != http.StatusOK {
+		return Article{}, fmt.Errorf("HTTP Error: %d", response.StatusCode)
+	}
+
+	if contentType := response.Header.Get("Content-Type"); contentType != "" {
+		mediaType, _, _ := mime.ParseMediaType(contentType)
+		if mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+			return Article{}, fmt.Errorf("page is %s, not HTML", mediaType)
+		}
+	}
+
+	page := response.Body[:min(len(response.Body), maxPageSize)]
+
+	// pages in other encodings are transcoded, going by the header, BOM or <meta charset>
+	body, err := charset.NewReader(bytes.NewReader(page), response.Header.Get("Content-Type"))
+	if err != nil {
+		return Article{}, fmt.Errorf("failed to decode page: %w", err)
+	}
+
+	// redirects change the base relative links resolve against
+	return Extract(body, response.URL)
+}
+
+func Extract(r io.Reader, base *url.URL) (Article, error) {
+	// a readability-style extraction: score blocks of text by length and commas,
+	// credit their containers, and keep the best container plus related siblings
+
+	doc, err := html.Parse(r)
+	if err != nil {
+		return Article{}, fmt.Errorf("failed to parse page: %w", err)
+	}
+
+	body := findFirst(doc, atom.Body)
+	if body == nil {
+		return Article{}, ErrNoArticle
+	}
+
+	prune(body, true)
+
+	scores := map[*html.Node]float64{}
+	var candidates []*html.Node
+
+	addScore := func(node *html.Node, score float64) {
+		if node == nil || node.Type != html.ElementNode {
+			return
+		}
+		if _, ok := scores[node]; !ok {
+			scores[node] = initialScore(node)
+			candidates = append(candidates, node)
+		}
+		scores[node] += score
+	}
+
+	walk(body, func(node *html.Node) {
+		switch node.DataAtom {
+		case atom.P, atom.Pre, atom.Td, atom.Blockquote, atom.Li:
+		default:
+			return
+		}
+
+		text := textContent(node)
+		if len(text) < minParagraphLength {
+			return
+		}
+
+		score := 1 + float64(strings.Count(text, ",")) + math.Min(float64(len(text))/100, 3)
+		addScore(node.Parent, score)
+		if node.Parent != nil {
+			addScore(node.Parent.Parent, score/2)
+		}
+	})
+
+	var top *html.Node
+	topScore := 0.0
+	for _, candidate := range candidates {
+		// link heavy containers are menus and link lists, not articles
+		score := scores[candidate] * (1 - linkDensity(candidate))
+		scores[candidate] = score
+		if top == nil || score > topScore {
+			top, topScore = candidate, score
+		}
+	}
+
+	// a page of nothing but link lists has no article, however much text they hold
+	if top == nil || linkDensity(top) > 0.5 {
+		return Article{}, ErrNoArticle
+	}
+
+	var parts []*html.Node
+	if top.Parent == nil {
+		parts = []*html.Node{top}
+	} else {
+		threshold := math.Max(10, topScore*0.2)
+		for sibling := top.Parent.FirstChild; sibling != nil; sibling = sibling.NextSibling {
+			if sibling == top || keepSibling(sibling, scores, threshold) {
+				parts = append(parts, sibling)
+			}
+		}
+	}
+
+	var out strings.Builder
+	for _, part := range parts {
+		writeClean(&out, part, base)
+	}
+
+	article := Article{HTML: strings.TrimSpace(out.String())}
+
+	var text strings.Builder
+	for _, part := range parts {
+		writeText(&text, part)
+	}
+	article.Text = tidyText(text.String())
+
+	if len(article.Text) < minArticleLength {
+		return Article{}, ErrNoArticle
+	}
+
+	return article, nil
+}
+
+func Clean(r io.Reader, base *url.URL) (Article, error) {
+	// sanitizes HTML that a feed ships itself, like content:encoded, keeping all of it
+
+	doc, err := html.Parse(r)
+	if err != nil {
+		return Article{}, fmt.Errorf("failed to parse content: %w", err)
+	}
+
+	body := findFirst(doc, atom.Body)
+	if body == nil {
+		return Article{}, ErrNoArticle
+	}
+
+	prune(body, false)
+
+	var out, text strings.Builder
+	for child := body.FirstChild; child != nil; child = child.NextSibling {
+		writeClean(&out, child, base)
+		writeText(&text, child)
+	}
+
+	article := Article{
+		HTML: strings.TrimSpace(out.String()),
+		Text: tidyText(text.String()),
+	}
+	if article.Text == "" {
+		return Article{}, ErrNoArticle
+	}
+
+	return article, nil
+}
+
+func Sanitize(fragment string, base *url.URL) string {
+	// cleans an HTML snippet from a feed, like an item's description, down to the
+	// allowed tags without tracking pixels or campaign parameters; plain text comes
+	// back escaped, so the result is always safe to embed in a page
+
+	if strings.TrimSpace(fragment) == "" {
+		return ""
+	}
+
+	// double-escaped feeds leave CDATA markers behind, which HTML would read as a comment
+	fragment = cdata.ReplaceAllString(fragment, "")
+
+	nodes, err := html.ParseFragment(strings.NewReader(fragment), &html.Node{
+		Type:     html.ElementNode,
+		Data:     "body",
+		DataAtom: atom.Body,
+	})
+	if err != nil {
+		return ""
+	}
+
+	container := &html.Node{Type: html.ElementNode, Data: "div", DataAtom: atom.Div}
+	for _, node := range nodes {
+		container.AppendChild(node)
+	}
+	prune(container, false)
+
+	var out strings.Builder
+	writeChildren(&out, container, base)
+
+	return strings.TrimSpace(out.String())
+}
+
+func prune(root *html.Node, unlikely bool) {
+	// drops scripts and navigation, and with unlikely set anything whose class or id
+	// says it isn't content
+
+	var remove []*html.Node
+	walk(root, func(node *html.Node) {
+		if node.Type == html.CommentNode {
+			remove = append(remove, node)
+			return
+		}
+		if node.Type != html.ElementNode || node == root {
+			return
+		}
+		if removedTags[node.DataAtom] {
+			remove = append(remove, node)
+			return
+		}
+		if !unlikely || node.DataAtom == atom.Article || node.DataAtom == atom.Main {
+			return
+		}
+
+		names := attr(node, "class") + " " + attr(node, "id")
+		if unlikelyCandidates.MatchString(names) && !maybeCandidate.MatchString(names) {
+			remove = append(remove, node)
+		}
+	})
+
+	for _, node := range remove {
+		if node.Parent != nil {
+			node.Parent.RemoveChild(node)
+		}
+	}
+}
+
+func initialScore(node *html.Node) float64 {
+	score := 0.0
+
+	switch node.DataAtom {
+	case atom.Article, atom.Main:
+		score += 10
+	case atom.Div:
+		score += 5
+	case atom.Pre, atom.Td, atom.Blockquote:
+		score += 3
+	case atom.Address, atom.Ol, atom.Ul, atom.Dl, atom.Dd, atom.Dt, atom.Li, atom.Form:
+		score -= 3
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6, atom.Th:
+		score -= 5
+	}
+
+	for _, name := range []string{attr(node, "class"), attr(node, "id")} {
+		if name == "" {
+			continue
+		}
+		if negativeNames.MatchString(name) {
+			score -= 25
+		}
+		if positiveNames.MatchString(name) {
+			score += 25
+		}
+	}
+
+	return score
+}
+
+func keepSibling(node *html.Node, scores map[*html.Node]float64, threshold float64) bool {
+	if node.Type != html.ElementNode {
+		return false
+	}
+
+	if score, ok := scores[node]; ok && score >= threshold {
+		return true
+	}
+
+	if node.DataAtom != atom.P {
+		return false
+	}
+
+	// loose paragraphs next to the article are usually part of it
+	text := textContent(node)
+	density := linkDensity(node)
+	return (len(text) > 80 && density < 0.25) || (len(text) > 0 && density == 0 && strings.Contains(text, ". "))
+}
+
+func linkDensity(node *html.Node) float64 {
+	total := len(textContent(node))
+	if total == 0 {
+		return 0
+	}
+
+	links := 0
+	walk(node, func(n *html.Node) {
+		if n.DataAtom == atom.A {
+			links += len(textContent(n))
+		}
+	})
+
+	return float64(links) / float64(total)
+}
+
+func walk(node *html.Node, fn func(*html.Node)) {
+	fn(node)
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		walk(child, fn)
+	}
+}
+
+func findFirst(node *html.Node, a atom.Atom) *html.Node {
+	if node.Type == html.ElementNode && node.DataAtom == a {
+		return node
+	}
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		if found := findFirst(child, a); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+func attr(node *html.Node, key string) string {
+	for _, a := range node.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func textContent(node *html.Node) string {
+	var b strings.Builder
+	walk(node, func(n *html.Node) {
+		if n.Type == html.TextNode {
+			b.WriteString(n.Data)
+		}
+	})
+	return strings.TrimSpace(whitespace.ReplaceAllString(b.String(), " "))
+}
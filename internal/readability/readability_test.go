@@ -0,0 +1,305 @@
+package readability
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"blog-aggregator/internal/fetch"
+)
+
+// go test ./internal/readability -update rewrites the expected extractions in testdata
+var update = flag.Bool("update", false, "rewrite the expected extractions in testdata")
+
+// every saved page is extracted as if it had been fetched from here
+const pageURL = "https://blog.example.com/posts/2026/scheduler/"
+
+func mustParse(t *testing.T, raw string) *url.URL {
+	t.Helper()
+
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		t.Fatalf("failed to parse %s: %v", raw, err)
+	}
+	return parsed
+}
+
+func extractFile(t *testing.T, name string, base *url.URL) (Article, error) {
+	t.Helper()
+
+	page, err := os.Open(filepath.Join("testdata", name+".html"))
+	if err != nil {
+		t.Fatalf("failed to open saved page: %v", err)
+	}
+	defer page.Close()
+
+	return Extract(page, base)
+}
+
+func golden(t *testing.T, path string, got string) {
+	t.Helper()
+
+	if *update {
+		if err := os.WriteFile(path, []byte(got+"\n"), 0o644); err != nil {
+			t.Fatalf("failed to write %s: %v", path, err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read %s, run with -update to create it: %v", path, err)
+	}
+	if got != strings.TrimSuffix(string(want), "\n") {
+		t.Errorf("extraction differs from %s:\n%s", path, got)
+	}
+}
+
+func TestExtractCorpus(t *testing.T) {
+	// saved pages with the extraction each should give in testdata/<page>.want.html and
+	// .want.txt; the checks below hold whatever the expected files say
+	tests := []struct {
+		page    string
+		keep    []string
+		drop    []string
+		wantErr error
+	}{
+		{
+			page: "blog-post",
+			keep: []string{
+				"Our job scheduler started life as a cron file",
+				"especially the folks on call during the cutover weekend",
+				`<a href="https://blog.example.com/posts/2026/design-doc/">`,
+				`<a href="https://blog.example.com/tag/go/">`,
+				`<img src="https://blog.example.com/posts/2026/scheduler/images/old-architecture.png"`,
+				`href="https://github.com/example/scheduler-bench"`,
+			},
+			drop: []string{
+				"cookies", "Recent posts", "Share on Twitter", "3 comments", "workflow engine",
+				"All rights reserved", "dataLayer", "font-family", "pixel.wp.com", "youtube.com",
+				"utm_source", "fbclid", "<script", "<style", "<iframe",
+			},
+		},
+		{
+			page: "news-article",
+			keep: []string{
+				"The city council voted seven to two",
+				"Opponents, mostly business owners on market street",
+				"Construction starts in the spring",
+				`<img src="https://blog.example.com/images/2026/10/bike-lanes.jpg"`,
+			},
+			drop: []string{
+				"Advertisement", "morning briefing", "Parking review delayed", "Terms",
+				"scorecardresearch", "NewsArticle", "data:image",
+			},
+		},
+		{
+			page: "docs-page",
+			keep: []string{
+				"Failed deliveries are retried with exponential backoff",
+				"&#34;max_attempts&#34;: 8,",
+				"caps the wait between two attempts",
+				"so you can fix the receiver and send it again",
+				`<a href="https://blog.example.com/posts/2026/config/">`,
+			},
+			drop: []string{"Previous: Install", "Next: Webhooks"},
+		},
+		{page: "noarticle-index", wantErr: ErrNoArticle},
+		{page: "noarticle-short", wantErr: ErrNoArticle},
+	}
+
+	base := mustParse(t, pageURL)
+	for _, tt := range tests {
+		t.Run(tt.page, func(t *testing.T) {
+			article, err := extractFile(t, tt.page, base)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Extract error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Extract: %v", err)
+			}
+
+			golden(t, filepath.Join("testdata", tt.page+".want.html"), article.HTML)
+			golden(t, filepath.Join("testdata", tt.page+".want.txt"), article.Text)
+
+			for _, want := range tt.keep {
+				if !strings.Contains(article.HTML, want) {
+					t.Errorf("extraction is missing %q", want)
+				}
+			}
+			for _, unwanted := range tt.drop {
+				if strings.Contains(article.HTML, unwanted) || strings.Contains(article.Text, unwanted) {
+					t.Errorf("extraction kept %q", unwanted)
+				}
+			}
+		})
+	}
+}
+
+func TestExtractResolvesAgainstBase(t *testing.T) {
+	// the same page saved under another address links somewhere else
+	article, err := extractFile(t, "blog-post", mustParse(t, "https://mirror.example.org/archive/scheduler.html"))
+	if err != nil {
+		t.Fatalf("Extract: %v", err)
+	}
+
+	for _, want := range []string{
+		`href="https://mirror.example.org/design-doc/"`,
+		`href="https://mirror.example.org/tag/go/"`,
+		`src="https://mirror.example.org/archive/images/old-architecture.png"`,
+		// fragment links stay on the page they came from
+		`href="https://mirror.example.org/archive/scheduler.html#results"`,
+	} {
+		if !strings.Contains(article.HTML, want) {
+			t.Errorf("extraction is missing %s:\n%s", want, article.HTML)
+		}
+	}
+}
+
+func TestExtractWithoutBody(t *testing.T) {
+	for _, page := range []string{
+		"",
+		"<html><body></body></html>",
+		"<html><body><p>Too short to be an article.</p></body></html>",
+		"<html><body><ul><li><a href='/a'>A link that is long enough to count as text</a></li></ul></body></html>",
+	} {
+		if _, err := Extract(strings.NewReader(page), mustParse(t, pageURL)); !errors.Is(err, ErrNoArticle) {
+			t.Errorf("Extract(%q) error = %v, want ErrNoArticle", page, err)
+		}
+	}
+}
+
+func TestSanitize(t *testing.T) {
+	base := mustParse(t, "https://example.com/blog/post")
+
+	tests := []struct {
+		name     string
+		fragment string
+		want     string
+	}{
+		{
+			name:     "script",
+			fragment: `<p>Hello</p><script>alert("hi")</script>`,
+			want:     `<p>Hello</p>`,
+		},
+		{
+			name:     "style",
+			fragment: `<style>p { color: red }</style><p>Hello</p>`,
+			want:     `<p>Hello</p>`,
+		},
+		{
+			name:     "iframe",
+			fragment: `<p>Watch this</p><iframe src="https://evil.example.net/"></iframe>`,
+			want:     `<p>Watch this</p>`,
+		},
+		{
+			name:     "event handlers and styles",
+			fragment: `<p onclick="steal()" style="color: red">Hello</p>`,
+			want:     `<p>Hello</p>`,
+		},
+		{
+			name:     "javascript link",
+			fragment: `<a href="javascript:alert(1)">click</a>`,
+			want:     `<a>click</a>`,
+		},
+		{
+			name:     "relative link",
+			fragment: `<a href="../about">About</a> and <img src="img/cat.png" alt="cat">`,
+			want:     `<a href="https://example.com/about">About</a> and <img src="https://example.com/blog/img/cat.png" alt="cat">`,
+		},
+		{
+			name:     "tracking pixel",
+			fragment: `<p>Hello</p><img src="https://feeds.feedburner.com/~r/example/~4/abc" width="1" height="1">`,
+			want:     `<p>Hello</p>`,
+		},
+		{
+			name:     "campaign parameters",
+			fragment: `<a href="https://example.com/post?id=7&fbclid=abc&gclid=def">post</a>`,
+			want:     `<a href="https://example.com/post?id=7">post</a>`,
+		},
+		{
+			name:     "plain text is escaped",
+			fragment: `Fish & chips < 5 pounds`,
+			want:     `Fish &amp; chips &lt; 5 pounds`,
+		},
+		{
+			name:     "cdata markers",
+			fragment: `<![CDATA[<p>Hello</p>]]>`,
+			want:     `<p>Hello</p>`,
+		},
+		{
+			name:     "empty",
+			fragment: "   ",
+			want:     "",
+		},
+	}
+
+	for _, tt := range tests {
+		if got := Sanitize(tt.fragment, base); got != tt.want {
+			t.Errorf("%s: Sanitize(%q) = %q, want %q", tt.name, tt.fragment, got, tt.want)
+		}
+	}
+}
+
+func TestClean(t *testing.T) {
+	// content:encoded is kept whole, however short, but still sanitized
+	content := `<p>A short note, see <a href="/notes/2">the next one</a>.</p><script>track()</script><iframe src="https://example.net/"></iframe>`
+
+	article, err := Clean(strings.NewReader(content), mustParse(t, "https://example.com/notes/1"))
+	if err != nil {
+		t.Fatalf("Clean: %v", err)
+	}
+
+	want := `<p>A short note, see <a href="https://example.com/notes/2">the next one</a>.</p>`
+	if article.HTML != want {
+		t.Errorf("Clean HTML = %q, want %q", article.HTML, want)
+	}
+	if article.Text != "A short note, see the next one." {
+		t.Errorf("Clean text = %q", article.Text)
+	}
+
+	if _, err := Clean(strings.NewReader("<script>track()</script>"), nil); !errors.Is(err, ErrNoArticle) {
+		t.Errorf("Clean of a script alone: error = %v, want ErrNoArticle", err)
+	}
+}
+
+func TestFetchResolvesAgainstFinalURL(t *testing.T) {
+	// a redirected page links relative to where it ended up, not where it was asked for
+	page, err := os.ReadFile(filepath.Join("testdata", "blog-post.html"))
+	if err != nil {
+		t.Fatalf("failed to read saved page: %v", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/old/scheduler", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/posts/2026/scheduler/", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/posts/2026/scheduler/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(page)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	opts := fetch.DefaultOptions()
+	opts.HostDelay = 0
+	article, err := Fetch(context.Background(), fetch.New(opts), server.URL+"/old/scheduler")
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+
+	want := `src="` + server.URL + `/posts/2026/scheduler/images/old-architecture.png"`
+	if !strings.Contains(article.HTML, want) {
+		t.Errorf("extraction is missing %s:\n%s", want, article.HTML)
+	}
+}
@@ -0,0 +1,339 @@
+package render
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+const (
+	DefaultWidth = 80
+
+	// narrower than this, wrapping does more harm than good
+	minWidth = 20
+)
+
+// elements whose content is never shown
+var skippedTags = map[atom.Atom]bool{
+	atom.Script:   true,
+	atom.Style:    true,
+	atom.Noscript: true,
+	atom.Head:     true,
+	atom.Template: true,
+}
+
+var blockTags = map[atom.Atom]bool{
+	atom.P: true, atom.Div: true, atom.Section: true, atom.Article: true, atom.Main: true,
+	atom.Header: true, atom.Footer: true, atom.Aside: true, atom.Nav: true,
+	atom.Figure: true, atom.Figcaption: true, atom.Table: true, atom.Dl: true, atom.Dt: true, atom.Dd: true,
+	atom.Address: true, atom.Details: true, atom.Summary: true,
+}
+
+var whitespace = regexp.MustCompile(`\s+`)
+
+type block struct {
+	// first is put before the first line, indent before the others
+	first, indent string
+	text          string
+	pre           bool
+	// list items follow each other without a blank line
+	tight bool
+}
+
+type renderer struct {
+	blocks []block
+	inline strings.Builder
+	links  []string
+
+	// the prefixes of the block being collected, set by the enclosing lists and quotes
+	first, indent string
+	tight         bool
+}
+
+func TerminalWidth() int {
+	// shells export COLUMNS; without it, assume a classic terminal
+	if columns, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && columns > 0 {
+		return columns
+	}
+	return DefaultWidth
+}
+
+func Text(fragment string, width int) string {
+	// renders an HTML snippet for the terminal: paragraphs and lists wrapped to
+	// width, quotes and code kept apart, and links numbered as footnotes
+
+	if width < minWidth {
+		width = minWidth
+	}
+
+	nodes, err := html.ParseFragment(strings.NewReader(fragment), &html.Node{
+		Type:     html.ElementNode,
+		Data:     "body",
+		DataAtom: atom.Body,
+	})
+	if err != nil {
+		return strings.TrimSpace(fragment)
+	}
+
+	r := &renderer{}
+	for _, node := range nodes {
+		r.walk(node)
+	}
+	r.flush()
+
+	var out strings.Builder
+	for i, b := range r.blocks {
+		if i > 0 {
+			if b.tight && r.blocks[i-1].tight {
+				out.WriteString("\n")
+			} else {
+				out.WriteString("\n\n")
+			}
+		}
+		if b.pre {
+			writePre(&out, b)
+		} else {
+			writeWrapped(&out, b, width)
+		}
+	}
+
+	if len(r.links) > 0 {
+		out.WriteString("\n\n")
+		for i, link := range r.links {
+			if i > 0 {
+				out.WriteString("\n")
+			}
+			fmt.Fprintf(&out, "[%d] %s", i+1, link)
+		}
+	}
+
+	return out.String()
+}
+
+func (r *renderer) flush() {
+	// ends the block being collected, if it has any text
+
+	text := strings.TrimSpace(r.inline.String())
+	r.inline.Reset()
+	if text == "" {
+		return
+	}
+
+	r.blocks = append(r.blocks, block{first: r.first, indent: r.indent, text: text, tight: r.tight})
+
+	// the marker of a list item only goes before its first block
+	r.first = r.indent
+}
+
+func (r *renderer) nested(first, indent string, tight bool, fn func()) {
+	// runs fn with the given prefixes added to the current ones, restoring them afterwards
+
+	r.flush()
+	savedFirst, savedIndent, savedTight := r.first, r.indent, r.tight
+	r.first, r.indent, r.tight = r.indent+first, r.indent+indent, tight
+
+	fn()
+
+	r.flush()
+	r.first, r.indent, r.tight = savedFirst, savedIndent, savedTight
+}
+
+func (r *renderer) children(node *html.Node) {
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		r.walk(child)
+	}
+}
+
+func (r *renderer) walk(node *html.Node) {
+	switch node.Type {
+	case html.TextNode:
+		// runs of spaces are dropped again when wrapping
+		r.inline.WriteString(whitespace.ReplaceAllString(node.Data, " "))
+		return
+	case html.ElementNode, html.DocumentNode:
+	default:
+		return
+	}
+
+	if skippedTags[node.DataAtom] {
+		return
+	}
+
+	switch node.DataAtom {
+	case atom.Br:
+		r.inline.WriteString("\n")
+	case atom.Hr:
+		r.flush()
+		r.blocks = append(r.blocks, block{first: r.indent, indent: r.indent, text: "----"})
+	case atom.Img:
+		if alt := strings.TrimSpace(attr(node, "alt")); alt != "" {
+			r.inline.WriteString("[image: " + alt + "] ")
+		}
+	case atom.A:
+		r.children(node)
+		r.link(node)
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		r.flush()
+		r.children(node)
+		heading := strings.TrimSpace(r.inline.String())
+		r.flush()
+		if heading != "" {
+			r.blocks[len(r.blocks)-1].text = heading + "\n" + strings.Repeat("-", min(utf8.RuneCountInString(heading), 60))
+		}
+	case atom.Ul, atom.Ol:
+		r.list(node)
+	case atom.Blockquote:
+		r.nested("> ", "> ", false, func() { r.children(node) })
+	case atom.Pre:
+		r.flush()
+		r.blocks = append(r.blocks, block{first: r.indent + "    ", indent: r.indent + "    ", text: strings.Trim(rawText(node), "\n"), pre: true})
+	case atom.Tr:
+		// a table row per line
+		r.flush()
+		count := len(r.blocks)
+		r.children(node)
+		r.flush()
+		if len(r.blocks) > count {
+			r.blocks[len(r.blocks)-1].tight = true
+		}
+	case atom.Td, atom.Th:
+		if node.PrevSibling != nil {
+			r.inline.WriteString(" | ")
+		}
+		r.children(node)
+	default:
+		if blockTags[node.DataAtom] {
+			r.flush()
+			r.children(node)
+			r.flush()
+			return
+		}
+		r.children(node)
+	}
+}
+
+func (r *renderer) list(node *html.Node) {
+	number := 1
+	if start, err := strconv.Atoi(attr(node, "start")); err == nil {
+		number = start
+	}
+
+	r.flush()
+	for item := node.FirstChild; item != nil; item = item.NextSibling {
+		if item.Type != html.ElementNode || item.DataAtom != atom.Li {
+			continue
+		}
+
+		marker := "- "
+		if node.DataAtom == atom.Ol {
+			marker = fmt.Sprintf("%d. ", number)
+			number++
+		}
+
+		r.nested(marker, strings.Repeat(" ", len(marker)), true, func() { r.children(item) })
+	}
+}
+
+func (r *renderer) link(node *html.Node) {
+	// links become footnotes, unless the text already is the URL
+
+	href := strings.TrimSpace(attr(node, "href"))
+	if !strings.HasPrefix(href, "http://") && !strings.HasPrefix(href, "https://") && !strings.HasPrefix(href, "mailto:") {
+		return
+	}
+
+	text := strings.TrimSpace(textContent(node))
+	if text == href || "mailto:"+text == href {
+		return
+	}
+
+	index := -1
+	for i, link := range r.links {
+		if link == href {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		r.links = append(r.links, href)
+		index = len(r.links) - 1
+	}
+
+	current := strings.TrimRight(r.inline.String(), " ")
+	r.inline.Reset()
+	fmt.Fprintf(&r.inline, "%s [%d]", current, index+1)
+}
+
+func writeWrapped(out *strings.Builder, b block, width int) {
+	// wraps each hard line break separately, putting words that don't fit on their own line
+
+	prefix := b.first
+	for i, line := range strings.Split(b.text, "\n") {
+		if i > 0 {
+			out.WriteString("\n")
+		}
+
+		available := max(minWidth/2, width-utf8.RuneCountInString(b.indent))
+		length := 0
+		out.WriteString(prefix)
+		for _, word := range strings.Fields(line) {
+			wordLength := utf8.RuneCountInString(word)
+			if length > 0 && length+1+wordLength > available {
+				out.WriteString("\n" + b.indent)
+				length = 0
+			} else if length > 0 {
+				out.WriteString(" ")
+				length++
+			}
+			out.WriteString(word)
+			length += wordLength
+		}
+		prefix = b.indent
+	}
+}
+
+func writePre(out *strings.Builder, b block) {
+	for i, line := range strings.Split(b.text, "\n") {
+		if i > 0 {
+			out.WriteString("\n")
+		}
+		out.WriteString(strings.TrimRight(b.indent+line, " "))
+	}
+}
+
+func rawText(node *html.Node) string {
+	var b strings.Builder
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			b.WriteString(n.Data)
+		}
+		if n.Type == html.ElementNode && n.DataAtom == atom.Br {
+			b.WriteString("\n")
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(node)
+	return b.String()
+}
+
+func textContent(node *html.Node) string {
+	return strings.Join(strings.Fields(rawText(node)), " ")
+}
+
+func attr(node *html.Node, key string) string {
+	for _, a := range node.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
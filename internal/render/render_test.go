@@ -0,0 +1,108 @@
+package render
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// go test ./internal/render -update rewrites the expected renderings in testdata
+var update = flag.Bool("update", false, "rewrite the expected renderings in testdata")
+
+func golden(t *testing.T, path string, got string) {
+	t.Helper()
+
+	if *update {
+		if err := os.WriteFile(path, []byte(got+"\n"), 0o644); err != nil {
+			t.Fatalf("failed to write %s: %v", path, err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read %s, run with -update to create it: %v", path, err)
+	}
+	if got != strings.TrimSuffix(string(want), "\n") {
+		t.Errorf("rendering differs from %s:\n%s", path, got)
+	}
+}
+
+func TestText(t *testing.T) {
+	// each testdata/<name>.html is rendered at width and compared to <name>.want.txt
+	tests := []struct {
+		name  string
+		width int
+	}{
+		{"paragraphs", DefaultWidth},
+		{"lists", 40},
+		{"pre", 30},
+		{"links", DefaultWidth},
+		{"wrapping", 30},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fragment, err := os.ReadFile(filepath.Join("testdata", tt.name+".html"))
+			if err != nil {
+				t.Fatalf("failed to read fragment: %v", err)
+			}
+
+			got := Text(string(fragment), tt.width)
+			golden(t, filepath.Join("testdata", tt.name+".want.txt"), got)
+		})
+	}
+}
+
+func TestTextWrapsAtWidth(t *testing.T) {
+	fragment, err := os.ReadFile(filepath.Join("testdata", "wrapping.html"))
+	if err != nil {
+		t.Fatalf("failed to read fragment: %v", err)
+	}
+
+	for _, width := range []int{20, 30, 45, 80} {
+		for _, line := range strings.Split(Text(string(fragment), width), "\n") {
+			// only a word longer than the width may stick out, on a line of its own
+			if utf8.RuneCountInString(line) > width && len(strings.Fields(strings.TrimLeft(line, "-> "))) > 1 {
+				t.Errorf("width %d: line is %d long: %q", width, utf8.RuneCountInString(line), line)
+			}
+		}
+	}
+}
+
+func TestTextNarrowWidths(t *testing.T) {
+	// anything narrower than minWidth, including no width at all, wraps at minWidth
+	const fragment = "<p>The quick brown fox jumps over the lazy dog.</p>"
+	want := Text(fragment, minWidth)
+
+	for _, width := range []int{-1, 0, 1, minWidth - 1} {
+		if got := Text(fragment, width); got != want {
+			t.Errorf("Text at width %d = %q, want %q", width, got, want)
+		}
+	}
+	if want != "The quick brown fox\njumps over the lazy\ndog." {
+		t.Errorf("Text at width %d = %q", minWidth, want)
+	}
+}
+
+func TestTextPlain(t *testing.T) {
+	tests := []struct {
+		fragment string
+		want     string
+	}{
+		{"", ""},
+		{"   ", ""},
+		{"Just text, no markup", "Just text, no markup"},
+		{"Fish &amp; chips", "Fish & chips"},
+		{"<script>track()</script><style>p {}</style>", ""},
+	}
+
+	for _, tt := range tests {
+		if got := Text(tt.fragment, DefaultWidth); got != tt.want {
+			t.Errorf("Text(%q) = %q, want %q", tt.fragment, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package rules
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"blog-aggregator/internal/database"
+)
+
+const (
+	FieldTitle       = "title"
+	FieldDescription = "description"
+	FieldAuthor      = "author"
+	FieldCategory    = "category"
+	FieldURL         = "url"
+
+	MatchSubstring = "substring"
+	MatchRegex     = "regex"
+
+	ActionHide     = "hide"
+	ActionMarkRead = "mark-read"
+	ActionStar     = "star"
+	ActionTag      = "tag"
+)
+
+var (
+	Fields     = []string{FieldTitle, FieldDescription, FieldAuthor, FieldCategory, FieldURL}
+	MatchTypes = []string{MatchSubstring, MatchRegex}
+	Actions    = []string{ActionHide, ActionMarkRead, ActionStar, ActionTag}
+)
+
+// Post is the part of a post that rules can look at
+type Post struct {
+	ID          string
+	FeedID      string
+	Title       string
+	Description string
+	Author      string
+	Categories  []string
+	URL         string
+}
+
+func FromDatabasePost(post database.Post, authors []string, categories []string) Post {
+	return Post{
+		ID:          post.ID,
+		FeedID:      post.FeedID,
+		Title:       post.Title.String,
+		Description: post.Description.String,
+		Author:      strings.Join(authors, ", "),
+		Categories:  categories,
+		URL:         post.Url,
+	}
+}
+
+func FromFollowedPost(post database.GetFollowedPostsRow) Post {
+	return Post{
+		ID:          post.ID,
+		FeedID:      post.FeedID,
+		Title:       post.Title.String,
+		Description: post.Description.String,
+		Author:      strings.Join(post.Authors, ", "),
+		Categories:  post.Categories,
+		URL:         post.Url,
+	}
+}
+
+type Matcher struct {
+	Rule  database.Rule
+	regex *regexp.Regexp
+}
+
+func Validate(field, matchType, action, tag string) error {
+	if !slices.Contains(Fields, field) {
+		return fmt.Errorf("unknown field %s, expected one of %s", field, strings.Join(Fields, ", "))
+	}
+	if !slices.Contains(MatchTypes, matchType) {
+		return fmt.Errorf("unknown match type %s, expected one of %s", matchType, strings.Join(MatchTypes, ", "))
+	}
+	if !slices.Contains(Actions, action) {
+		return fmt.Errorf("unknown action %s, expected one of %s", action, strings.Join(Actions, ", "))
+	}
+	if action == ActionTag && tag == "" {
+		return fmt.Errorf("the %s action needs a tag", ActionTag)
+	}
+
+	return nil
+}
+
+func Compile(rule database.Rule) (Matcher, error) {
+	matcher := Matcher{Rule: rule}
+
+	if rule.MatchType == MatchRegex {
+		regex, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return Matcher{}, fmt.Errorf("invalid regex %s: %w", rule.Pattern, err)
+		}
+		matcher.regex = regex
+	}
+
+	return matcher, nil
+}
+
+func CompileAll(rules []database.Rule) ([]Matcher, error) {
+	matchers := make([]Matcher, 0, len(rules))
+	for _, rule := range rules {
+		matcher, err := Compile(rule)
+		if err != nil {
+			return nil, err
+		}
+		matchers = append(matchers, matcher)
+	}
+
+	return matchers, nil
+}
+
+func (m Matcher) Matches(post Post) bool {
+	if m.Rule.FeedID.Valid && m.Rule.FeedID.String != post.FeedID {
+		return false
+	}
+
+	switch m.Rule.Field {
+	case FieldTitle:
+		return m.matchString(post.Title)
+	case FieldDescription:
+		return m.matchString(post.Description)
+	case FieldAuthor:
+		return m.matchString(post.Author)
+	case FieldURL:
+		return m.matchString(post.URL)
+	case FieldCategory:
+		for _, category := range post.Categories {
+			if m.matchString(category) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func (p Post) Has(field string) bool {
+	// whether the post has anything for a rule on field to match; feeds often leave out
+	// authors and categories, and posts collected before they were stored have none
+	switch field {
+	case FieldAuthor:
+		return p.Author != ""
+	case FieldCategory:
+		return len(p.Categories) > 0
+	}
+
+	return true
+}
+
+func (m Matcher) matchString(value string) bool {
+	if value == "" {
+		return false
+	}
+
+	if m.regex != nil {
+		return m.regex.MatchString(value)
+	}
+
+	// substring matches ignore case
+	return strings.Contains(strings.ToLower(value), strings.ToLower(m.Rule.Pattern))
+}
+
+func (m Matcher) Apply(ctx context.Context, db *database.Queries, postID string) error {
+	// performs the rule's action on a post for the user who owns the rule
+
+	userID := m.Rule.UserID
+
+	switch m.Rule.Action {
+	case ActionHide:
+		return db.HidePost(ctx, database.HidePostParams{UserID: userID, PostID: postID})
+	case ActionMarkRead:
+		return db.MarkPostRead(ctx, database.MarkPostReadParams{UserID: userID, PostID: postID})
+	case ActionStar:
+		return db.StarPost(ctx, database.StarPostParams{UserID: userID, PostID: postID})
+	case ActionTag:
+		return db.TagPost(ctx, database.TagPostParams{
+			UserID:    userID,
+			PostID:    postID,
+			Tag:       m.Rule.Tag.String,
+			CreatedAt: time.Now(),
+		})
+	}
+
+	return fmt.Errorf("unknown action %s", m.Rule.Action)
+}
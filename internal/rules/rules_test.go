@@ -0,0 +1,163 @@
+package rules
+
+import (
+	"database/sql"
+	"testing"
+
+	"blog-aggregator/internal/database"
+)
+
+func testPost() Post {
+	return Post{
+		ID:          "post-1",
+		FeedID:      "feed-1",
+		Title:       "Go 1.30 Released",
+		Description: "The Go team is happy to announce a new release.",
+		Author:      "Alice Smith, Bob Jones",
+		Categories:  []string{"Releases", "Go"},
+		URL:         "https://go.dev/blog/go1.30",
+	}
+}
+
+func TestMatches(t *testing.T) {
+	tests := []struct {
+		name      string
+		field     string
+		matchType string
+		pattern   string
+		feedID    string
+		post      func(*Post)
+		want      bool
+	}{
+		{name: "title substring", field: FieldTitle, matchType: MatchSubstring, pattern: "1.30", want: true},
+		{name: "title substring ignores case", field: FieldTitle, matchType: MatchSubstring, pattern: "go 1.30 RELEASED", want: true},
+		{name: "title substring miss", field: FieldTitle, matchType: MatchSubstring, pattern: "rust", want: false},
+		{name: "description substring", field: FieldDescription, matchType: MatchSubstring, pattern: "happy to", want: true},
+		{name: "author substring", field: FieldAuthor, matchType: MatchSubstring, pattern: "bob", want: true},
+		{name: "category substring", field: FieldCategory, matchType: MatchSubstring, pattern: "release", want: true},
+		{name: "category matches any", field: FieldCategory, matchType: MatchSubstring, pattern: "go", want: true},
+		{name: "url substring", field: FieldURL, matchType: MatchSubstring, pattern: "go.dev/blog", want: true},
+		{name: "title regex", field: FieldTitle, matchType: MatchRegex, pattern: `^Go \d+\.\d+`, want: true},
+		{name: "regex is case sensitive", field: FieldTitle, matchType: MatchRegex, pattern: `^go`, want: false},
+		{name: "regex case flag", field: FieldTitle, matchType: MatchRegex, pattern: `(?i)^go`, want: true},
+		{name: "category regex", field: FieldCategory, matchType: MatchRegex, pattern: `^Go$`, want: true},
+		{name: "url regex", field: FieldURL, matchType: MatchRegex, pattern: `\.dev/`, want: true},
+		{name: "rule for this feed", field: FieldTitle, matchType: MatchSubstring, pattern: "go", feedID: "feed-1", want: true},
+		{name: "rule for another feed", field: FieldTitle, matchType: MatchSubstring, pattern: "go", feedID: "feed-2", want: false},
+		{
+			name: "no author", field: FieldAuthor, matchType: MatchRegex, pattern: ".*",
+			post: func(p *Post) { p.Author = "" }, want: false,
+		},
+		{
+			name: "no categories", field: FieldCategory, matchType: MatchSubstring, pattern: "",
+			post: func(p *Post) { p.Categories = nil }, want: false,
+		},
+		{name: "unknown field", field: "body", matchType: MatchSubstring, pattern: "go", want: false},
+	}
+
+	for _, tt := range tests {
+		rule := database.Rule{Field: tt.field, MatchType: tt.matchType, Pattern: tt.pattern}
+		if tt.feedID != "" {
+			rule.FeedID = sql.NullString{String: tt.feedID, Valid: true}
+		}
+
+		matcher, err := Compile(rule)
+		if err != nil {
+			t.Fatalf("%s: Compile: %v", tt.name, err)
+		}
+
+		post := testPost()
+		if tt.post != nil {
+			tt.post(&post)
+		}
+
+		if got := matcher.Matches(post); got != tt.want {
+			t.Errorf("%s: Matches = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCompileRejectsBadRegex(t *testing.T) {
+	for _, pattern := range []string{"(unclosed", "[a-", `\`, "*go"} {
+		if _, err := Compile(database.Rule{Field: FieldTitle, MatchType: MatchRegex, Pattern: pattern}); err == nil {
+			t.Errorf("Compile(%q) succeeded, want an error", pattern)
+		}
+	}
+
+	// the same text is fine as a substring
+	if _, err := Compile(database.Rule{Field: FieldTitle, MatchType: MatchSubstring, Pattern: "(unclosed"}); err != nil {
+		t.Errorf("Compile of a substring rule: %v", err)
+	}
+
+	rules := []database.Rule{
+		{Field: FieldTitle, MatchType: MatchRegex, Pattern: "^go"},
+		{Field: FieldTitle, MatchType: MatchRegex, Pattern: "(unclosed"},
+	}
+	if _, err := CompileAll(rules); err == nil {
+		t.Error("CompileAll succeeded with a bad regex among the rules")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		field, matchType, action, tag string
+		wantErr                       bool
+	}{
+		{FieldTitle, MatchSubstring, ActionHide, "", false},
+		{FieldCategory, MatchRegex, ActionTag, "go", false},
+		{FieldTitle, MatchSubstring, ActionTag, "", true},
+		{"body", MatchSubstring, ActionHide, "", true},
+		{FieldTitle, "glob", ActionHide, "", true},
+		{FieldTitle, MatchSubstring, "delete", "", true},
+	}
+
+	for _, tt := range tests {
+		err := Validate(tt.field, tt.matchType, tt.action, tt.tag)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Validate(%s, %s, %s, %q) error = %v, want error %v", tt.field, tt.matchType, tt.action, tt.tag, err, tt.wantErr)
+		}
+	}
+}
+
+func TestHas(t *testing.T) {
+	full := testPost()
+	bare := Post{Title: "Untitled", URL: "https://example.com/1"}
+
+	for _, field := range Fields {
+		if !full.Has(field) {
+			t.Errorf("post with everything has no %s", field)
+		}
+	}
+
+	tests := []struct {
+		field string
+		want  bool
+	}{
+		{FieldAuthor, false},
+		{FieldCategory, false},
+		// titles and the rest are always there to match, even when empty
+		{FieldTitle, true},
+		{FieldDescription, true},
+		{FieldURL, true},
+	}
+	for _, tt := range tests {
+		if got := bare.Has(tt.field); got != tt.want {
+			t.Errorf("bare post: Has(%s) = %v, want %v", tt.field, got, tt.want)
+		}
+	}
+}
+
+func TestFromFollowedPost(t *testing.T) {
+	post := FromFollowedPost(database.GetFollowedPostsRow{
+		ID:         "post-1",
+		FeedID:     "feed-1",
+		Title:      sql.NullString{String: "Hello", Valid: true},
+		Url:        "https://example.com/hello",
+		Authors:    []string{"Alice", "Bob"},
+		Categories: []string{"Go"},
+	})
+
+	if post.Author != "Alice, Bob" || len(post.Categories) != 1 || post.Title != "Hello" {
+		t.Errorf("FromFollowedPost = %+v", post)
+	}
+}
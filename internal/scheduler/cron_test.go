@@ -0,0 +1,96 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseRejectsSchedulesThatNeverRun(t *testing.T) {
+	for _, spec := range []string{
+		"0 0 31 2 *",
+		"0 0 30 2 *",
+		"0 0 31 4,6,9,11 *",
+	} {
+		if _, err := Parse(spec); err == nil {
+			t.Errorf("Parse(%q) succeeded, want an error", spec)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	for _, spec := range []string{
+		"* * * * *",
+		"*/15 * * * *",
+		"0 3 * * *",
+		"0 4 * * 0",
+		"0 4 * * 7",
+		"0 0 29 2 *",
+		// day of month or day of week, so Mondays in February still come
+		"0 0 31 2 1",
+		"@daily",
+		"@every 90s",
+	} {
+		schedule, err := Parse(spec)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", spec, err)
+			continue
+		}
+		if schedule.Next(time.Now()).IsZero() {
+			t.Errorf("Parse(%q) never runs", spec)
+		}
+	}
+
+	for _, spec := range []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"@every 10ms",
+		"@yearly",
+	} {
+		if _, err := Parse(spec); err == nil {
+			t.Errorf("Parse(%q) succeeded, want an error", spec)
+		}
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	// 2026-10-19 is a Monday
+	from := time.Date(2026, 10, 19, 10, 17, 30, 0, time.UTC)
+
+	tests := []struct {
+		spec string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2026, 10, 19, 10, 18, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2026, 10, 19, 10, 30, 0, 0, time.UTC)},
+		{"0 3 * * *", time.Date(2026, 10, 20, 3, 0, 0, 0, time.UTC)},
+		{"0 4 * * 0", time.Date(2026, 10, 25, 4, 0, 0, 0, time.UTC)},
+		{"0 0 1 * *", time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 0 13 * 5", time.Date(2026, 10, 23, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		schedule, err := Parse(tt.spec)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", tt.spec, err)
+		}
+		if got := schedule.Next(from); !got.Equal(tt.want) {
+			t.Errorf("Parse(%q).Next(%s) = %s, want %s", tt.spec, from, got, tt.want)
+		}
+	}
+}
+
+func TestNextRunRejectsZeroTime(t *testing.T) {
+	// a schedule that has run out, which Parse never hands out but Run must not spin on
+	e := &entry{job: Job{Name: "never", Spec: "0 0 31 2 *"}, schedule: cronSchedule{dom: 1 << 31, month: 1 << 2, dowStar: true}}
+
+	if _, err := nextRun(e, time.Now()); err == nil {
+		t.Error("nextRun succeeded for a schedule without a next run")
+	}
+}
@@ -0,0 +1,193 @@
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"blog-aggregator/internal/database"
+
+	"github.com/google/uuid"
+)
+
+const (
+	StatusRunning   = "running"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+)
+
+type Job struct {
+	Name string
+	Spec string
+	// Run returns a short summary of what it did, or "" if there was nothing to report
+	Run func(ctx context.Context) (string, error)
+}
+
+type entry struct {
+	job      Job
+	schedule Schedule
+	next     time.Time
+}
+
+type Scheduler struct {
+	db      *database.Queries
+	entries []*entry
+}
+
+func New(db *database.Queries, jobs []Job) (*Scheduler, error) {
+	scheduler := &Scheduler{db: db}
+
+	for _, job := range jobs {
+		schedule, err := Parse(job.Spec)
+		if err != nil {
+			return nil, fmt.Errorf("job %s: %w", job.Name, err)
+		}
+
+		scheduler.entries = append(scheduler.entries, &entry{job: job, schedule: schedule})
+	}
+
+	return scheduler, nil
+}
+
+func (s *Scheduler) Run(ctx context.Context) error {
+	// runs jobs one at a time as they come due, until ctx is cancelled
+
+	if err := s.load(ctx); err != nil {
+		return err
+	}
+
+	for {
+		next := s.entries[0].next
+		for _, e := range s.entries[1:] {
+			if e.next.Before(next) {
+				next = e.next
+			}
+		}
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		case <-timer.C:
+		}
+
+		for _, e := range s.entries {
+			if ctx.Err() != nil {
+				return nil
+			}
+			if time.Now().Before(e.next) {
+				continue
+			}
+
+			s.runJob(ctx, e)
+
+			next, err := nextRun(e, time.Now())
+			if err != nil {
+				return err
+			}
+			e.next = next
+
+			err = s.db.SetJobNextRun(context.Background(), database.SetJobNextRunParams{
+				Name:      e.job.Name,
+				NextRunAt: sql.NullTime{Time: e.next, Valid: true},
+			})
+			if err != nil {
+				return fmt.Errorf("failed to save next run of job %s: %w", e.job.Name, err)
+			}
+		}
+	}
+}
+
+func (s *Scheduler) load(ctx context.Context) error {
+	// picks up where the last agg process left off: overdue jobs run right away,
+	// jobs that are new or whose schedule changed start from their schedule
+
+	if len(s.entries) == 0 {
+		return fmt.Errorf("no jobs to schedule")
+	}
+
+	now := time.Now()
+
+	if _, err := s.db.FailUnfinishedJobRuns(ctx, sql.NullTime{Time: now, Valid: true}); err != nil {
+		return fmt.Errorf("failed to clean up interrupted job runs: %w", err)
+	}
+
+	for _, e := range s.entries {
+		stored, err := s.db.GetJob(ctx, e.job.Name)
+		switch {
+		case err == sql.ErrNoRows:
+			e.next = now
+		case err != nil:
+			return fmt.Errorf("failed to get job %s: %w", e.job.Name, err)
+		case stored.Schedule != e.job.Spec || !stored.NextRunAt.Valid:
+			if e.next, err = nextRun(e, now); err != nil {
+				return err
+			}
+		default:
+			e.next = stored.NextRunAt.Time
+		}
+
+		err = s.db.UpsertJob(ctx, database.UpsertJobParams{
+			Name:      e.job.Name,
+			CreatedAt: now,
+			UpdatedAt: now,
+			Schedule:  e.job.Spec,
+			NextRunAt: sql.NullTime{Time: e.next, Valid: true},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to save job %s: %w", e.job.Name, err)
+		}
+	}
+
+	return nil
+}
+
+func nextRun(e *entry, after time.Time) (time.Time, error) {
+	// a schedule without a next run would otherwise look due forever and run the job in a loop
+
+	next := e.schedule.Next(after)
+	if next.IsZero() {
+		return time.Time{}, fmt.Errorf("job %s has no run after %s, check its schedule %q", e.job.Name, after.Format(time.RFC3339), e.job.Spec)
+	}
+
+	return next, nil
+}
+
+func (s *Scheduler) runJob(ctx context.Context, e *entry) {
+	// the run is recorded even if the job fails; bookkeeping errors are only printed
+	// so one bad run doesn't stop the other jobs
+
+	runID := uuid.NewString()
+
+	err := s.db.CreateJobRun(context.Background(), database.CreateJobRunParams{
+		ID:        runID,
+		JobName:   e.job.Name,
+		StartedAt: time.Now(),
+	})
+	if err != nil {
+		fmt.Printf("Error recording run of job %s: %v\n", e.job.Name, err)
+		return
+	}
+
+	summary, runErr := e.job.Run(ctx)
+
+	finish := database.FinishJobRunParams{
+		ID:         runID,
+		FinishedAt: sql.NullTime{Time: time.Now(), Valid: true},
+		Status:     StatusSucceeded,
+		Summary:    sql.NullString{String: summary, Valid: summary != ""},
+	}
+	if runErr != nil {
+		finish.Status = StatusFailed
+		finish.Error = sql.NullString{String: runErr.Error(), Valid: true}
+		fmt.Printf("error running job %s: %v\n", e.job.Name, runErr)
+	} else if summary != "" {
+		fmt.Printf("%s: %s\n", e.job.Name, summary)
+	}
+
+	if err := s.db.FinishJobRun(context.Background(), finish); err != nil {
+		fmt.Printf("Error recording run of job %s: %v\n", e.job.Name, err)
+	}
+}
@@ -0,0 +1,93 @@
+package search
+
+import (
+	"database/sql"
+	"slices"
+	"strings"
+	"time"
+
+	"blog-aggregator/internal/database"
+)
+
+func Keywords(query string) []string {
+	// a saved search query is a list of keywords, all of which have to show up in a post
+
+	return strings.Fields(strings.ToLower(query))
+}
+
+// escapes LIKE's own wildcards
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+func Patterns(query string) []string {
+	// turns the keywords into ILIKE patterns
+
+	keywords := Keywords(query)
+	patterns := make([]string, len(keywords))
+	for i, keyword := range keywords {
+		patterns[i] = Pattern(keyword)
+	}
+
+	return patterns
+}
+
+func Pattern(text string) string {
+	// an ILIKE pattern matching anything that contains text
+	return "%" + EscapeLike(text) + "%"
+}
+
+func EscapeLike(text string) string {
+	// text matched literally in a LIKE pattern, whatever % and _ it contains
+	return likeEscaper.Replace(text)
+}
+
+func AuthorPattern(author string) sql.NullString {
+	// the author filter of GetPostsForUser, a case-insensitive substring of any author
+	if author == "" {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: Pattern(author), Valid: true}
+}
+
+func PublishedAfter(search database.SavedSearch, now time.Time) (time.Time, bool) {
+	if !search.MaxAgeHours.Valid {
+		return time.Time{}, false
+	}
+
+	return now.Add(-time.Duration(search.MaxAgeHours.Int32) * time.Hour), true
+}
+
+func Matches(search database.SavedSearch, post database.Post, authors []string, categories []string) bool {
+	// checks a single post against the search, the same way GetPostsForUser does.
+	// folder filters are left to the query that loads the searches.
+
+	if search.FeedID.Valid && search.FeedID.String != post.FeedID {
+		return false
+	}
+
+	if after, ok := PublishedAfter(search, time.Now()); ok {
+		if !post.PublishedAt.Valid || post.PublishedAt.Time.Before(after) {
+			return false
+		}
+	}
+
+	if search.Author.Valid && !slices.ContainsFunc(authors, func(author string) bool {
+		return strings.Contains(strings.ToLower(author), strings.ToLower(search.Author.String))
+	}) {
+		return false
+	}
+
+	if search.Category.Valid && !slices.ContainsFunc(categories, func(category string) bool {
+		return strings.EqualFold(category, search.Category.String)
+	}) {
+		return false
+	}
+
+	text := strings.ToLower(post.Title.String + " " + post.Description.String)
+	for _, keyword := range Keywords(search.Query) {
+		if !strings.Contains(text, keyword) {
+			return false
+		}
+	}
+
+	return true
+}
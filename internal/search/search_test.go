@@ -0,0 +1,136 @@
+package search
+
+import (
+	"database/sql"
+	"slices"
+	"testing"
+	"time"
+
+	"blog-aggregator/internal/database"
+)
+
+func TestEscapeLike(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"golang", "golang"},
+		{"100%", `100\%`},
+		{"snake_case", `snake\_case`},
+		{`C:\path`, `C:\\path`},
+		{`50%_off\`, `50\%\_off\\`},
+	}
+
+	for _, tt := range tests {
+		if got := EscapeLike(tt.text); got != tt.want {
+			t.Errorf("EscapeLike(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+
+	if got := Pattern("100%"); got != `%100\%%` {
+		t.Errorf("Pattern(100%%) = %q", got)
+	}
+}
+
+func TestPatterns(t *testing.T) {
+	got := Patterns("  Go   Generics 100%  ")
+	want := []string{"%go%", "%generics%", `%100\%%`}
+	if !slices.Equal(got, want) {
+		t.Errorf("Patterns = %q, want %q", got, want)
+	}
+
+	if got := Patterns("   "); len(got) != 0 {
+		t.Errorf("Patterns of a blank query = %q, want none", got)
+	}
+
+	if got := AuthorPattern(""); got.Valid {
+		t.Errorf("AuthorPattern of no author = %v, want NULL", got)
+	}
+	if got := AuthorPattern("o_brien"); got.String != `%o\_brien%` {
+		t.Errorf("AuthorPattern = %q", got.String)
+	}
+}
+
+func TestMatches(t *testing.T) {
+	now := time.Now()
+	post := database.Post{
+		ID:          "post-1",
+		FeedID:      "feed-1",
+		Title:       sql.NullString{String: "Generics in Go", Valid: true},
+		Description: sql.NullString{String: "A tour of type PARAMETERS.", Valid: true},
+		PublishedAt: sql.NullTime{Time: now.Add(-2 * time.Hour), Valid: true},
+	}
+	authors := []string{"Alice Smith", "Bob Jones"}
+	categories := []string{"Go", "Language"}
+
+	valid := func(s string) sql.NullString { return sql.NullString{String: s, Valid: true} }
+	hours := func(h int32) sql.NullInt32 { return sql.NullInt32{Int32: h, Valid: true} }
+
+	tests := []struct {
+		name   string
+		search database.SavedSearch
+		post   func(*database.Post)
+		want   bool
+	}{
+		{name: "keywords in title and description", search: database.SavedSearch{Query: "generics parameters"}, want: true},
+		{name: "keywords ignore case", search: database.SavedSearch{Query: "GENERICS go"}, want: true},
+		{name: "every keyword is needed", search: database.SavedSearch{Query: "generics rust"}, want: false},
+		{name: "empty query matches everything", search: database.SavedSearch{}, want: true},
+		{name: "same feed", search: database.SavedSearch{Query: "go", FeedID: valid("feed-1")}, want: true},
+		{name: "other feed", search: database.SavedSearch{Query: "go", FeedID: valid("feed-2")}, want: false},
+		{name: "author substring", search: database.SavedSearch{Author: valid("smith")}, want: true},
+		{name: "author of none", search: database.SavedSearch{Author: valid("carol")}, want: false},
+		{name: "category ignores case", search: database.SavedSearch{Category: valid("language")}, want: true},
+		{name: "category is exact", search: database.SavedSearch{Category: valid("lang")}, want: false},
+		{name: "published within max age", search: database.SavedSearch{MaxAgeHours: hours(3)}, want: true},
+		{name: "published before max age", search: database.SavedSearch{MaxAgeHours: hours(1)}, want: false},
+		{
+			name:   "no publish date with a max age",
+			search: database.SavedSearch{MaxAgeHours: hours(24)},
+			post:   func(p *database.Post) { p.PublishedAt = sql.NullTime{} },
+			want:   false,
+		},
+		{
+			name:   "no publish date without a max age",
+			search: database.SavedSearch{Query: "go"},
+			post:   func(p *database.Post) { p.PublishedAt = sql.NullTime{} },
+			want:   true,
+		},
+		{
+			name:   "every filter at once",
+			search: database.SavedSearch{Query: "tour", FeedID: valid("feed-1"), Author: valid("bob"), Category: valid("go"), MaxAgeHours: hours(3)},
+			want:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		p := post
+		if tt.post != nil {
+			tt.post(&p)
+		}
+		if got := Matches(tt.search, p, authors, categories); got != tt.want {
+			t.Errorf("%s: Matches = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	// a post without authors or categories can't pass those filters
+	if Matches(database.SavedSearch{Author: valid("alice")}, post, nil, categories) {
+		t.Error("author filter matched a post without authors")
+	}
+	if Matches(database.SavedSearch{Category: valid("go")}, post, authors, nil) {
+		t.Error("category filter matched a post without categories")
+	}
+}
+
+func TestPublishedAfter(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+
+	if _, ok := PublishedAfter(database.SavedSearch{}, now); ok {
+		t.Error("PublishedAfter without a max age gave a cutoff")
+	}
+
+	after, ok := PublishedAfter(database.SavedSearch{MaxAgeHours: sql.NullInt32{Int32: 48, Valid: true}}, now)
+	if want := now.Add(-48 * time.Hour); !ok || !after.Equal(want) {
+		t.Errorf("PublishedAfter = %v, %v, want %v", after, ok, want)
+	}
+}
@@ -1,11 +1,31 @@
 package state
 
 import (
+	"context"
+	"database/sql"
+
 	"blog-aggregator/internal/config"
 	"blog-aggregator/internal/database"
 )
 
 type State struct {
 	DB     *database.Queries
+	Conn   *sql.DB
 	Config *config.Config
-}
\ No newline at end of file
+}
+
+func (s *State) WithTx(ctx context.Context, fn func(q *database.Queries) error) error {
+	// runs fn inside a transaction, committing only if fn returns no error
+
+	tx, err := s.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(s.DB.WithTx(tx)); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
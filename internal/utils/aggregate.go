@@ -3,59 +3,245 @@ package utils
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"net/http"
+	"net/url"
 	"strings"
+	"sync"
 	"time"
 
+	"blog-aggregator/internal/credentials"
 	"blog-aggregator/internal/database"
+	"blog-aggregator/internal/fetch"
+	"blog-aggregator/internal/readability"
+	"blog-aggregator/internal/rules"
+	"blog-aggregator/internal/search"
+	"blog-aggregator/internal/state"
+	"blog-aggregator/internal/webhook"
 	"blog-aggregator/rss"
 
 	"github.com/google/uuid"
 )
 
+// how many feeds a scrape fetches at once; the fetch client still holds each host
+// to its own delay and concurrency, so feeds on a busy host wait their turn
+const scrapeWorkers = 8
 
-func ScrapeFeeds(ctx context.Context, db *database.Queries) error {
+func ScrapeFeeds(ctx context.Context, s *state.State, client *fetch.Client, intervals FetchIntervals) (fetched int, failed int, err error) {
+	// fetches every feed that is due, oldest first, until none are left
+
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		errs  []error
+		slots = make(chan struct{}, scrapeWorkers)
+	)
+
+	for {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			wg.Wait()
+			return fetched, failed, errors.Join(append(errs, ctx.Err())...)
+		}
+
+		feed, err := claimNextFeed(ctx, s.DB, intervals)
+		if err != nil {
+			<-slots
+			wg.Wait()
+			if !errors.Is(err, sql.ErrNoRows) {
+				errs = append(errs, err)
+			}
+			return fetched, failed, errors.Join(errs...)
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			err := scrapeFeed(ctx, s, client, intervals, feed)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				failed++
+				errs = append(errs, err)
+				return
+			}
+			fetched++
+		}()
+	}
+}
+
+func claimNextFeed(ctx context.Context, db *database.Queries, intervals FetchIntervals) (database.Feed, error) {
+	// the feed due the longest, pushed back right away so the next claim gets
+	// another one and a feed that fails isn't retried on every scrape
 
 	feed, err := db.GetNextFeedToFetch(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		return feed, err
+	}
 	if err != nil {
-		return fmt.Errorf("failed to get next feed to fetch: %w", err)
+		return feed, fmt.Errorf("failed to get next feed to fetch: %w", err)
 	}
 
-	err = db.MarkFeedFetched(ctx, feed.ID)
+	retryAt := time.Now().Add(intervals.clamp(retryFetchInterval))
+	err = db.MarkFeedFetched(ctx, database.MarkFeedFetchedParams{
+		ID:          feed.ID,
+		NextFetchAt: sql.NullTime{Time: retryAt, Valid: true},
+	})
 	if err != nil {
-		return fmt.Errorf("failed to mark feed %s as fetched: %w", feed.ID, err)
+		return feed, fmt.Errorf("failed to mark feed %s as fetched: %w", feed.ID, err)
 	}
 
-	rssFeed, err := rss.FetchFeed(ctx, feed.Url)
+	return feed, nil
+}
+
+func scrapeFeed(ctx context.Context, s *state.State, client *fetch.Client, intervals FetchIntervals, feed database.Feed) error {
+	db := s.DB
+
+	header, err := feedHeader(ctx, s, feed)
+	if err != nil {
+		return err
+	}
+
+	rssFeed, err := rss.FetchFeed(ctx, client, feed.Url, header)
+	if errors.Is(err, rss.ErrGone) {
+		if err := db.MarkFeedDead(ctx, feed.ID); err != nil {
+			return fmt.Errorf("failed to mark feed %s as dead: %w", feed.ID, err)
+		}
+		fmt.Printf("feed %s is gone (HTTP 410) from %s, no longer fetching it\n", feed.Name, feed.Url)
+		return nil
+	}
+	var retry *fetch.RetryAfterError
+	if errors.As(err, &retry) {
+		// the host's own estimate replaces the usual retry time
+		return rescheduleFeed(ctx, db, feed, time.Now().Add(retry.After), err)
+	}
+	if errors.Is(err, fetch.ErrDisallowed) {
+		// robots.txt rarely changes quickly, so check back as late as allowed
+		return rescheduleFeed(ctx, db, feed, time.Now().Add(intervals.Max), err)
+	}
 	if err != nil {
 		return fmt.Errorf("failed to fetch feed from url %s: %w", feed.Url, err)
 	}
 
-	
-	for _, item := range rssFeed.Channel.Item {
-		var publishedAt sql.NullTime
-		if item.PubDate != "" {
-			parsedTime, err := time.Parse(time.RFC1123Z, item.PubDate)
+	// only a feed that parsed is followed to its new home, so a redirect to an
+	// error page can't take the old URL away
+	if rssFeed.MovedTo != "" && rssFeed.MovedTo != feed.Url {
+		if header != nil && !fetch.SameHost(feed.Url, rssFeed.MovedTo) {
+			// the redirect drops the credentials, but the new URL would get them on every fetch
+			fmt.Printf("feed %s moved permanently to %s on another host, kept its URL so its credentials don't go there; its owner can change it with feed seturl\n", feed.Name, rssFeed.MovedTo)
+		} else {
+			feed, err = moveFeed(ctx, s, feed, rssFeed.MovedTo)
 			if err != nil {
-				parsedTime, err = time.Parse(time.RFC822Z, item.PubDate)
+				return err
 			}
-			if err == nil {
-				publishedAt = sql.NullTime{Time: parsedTime, Valid: true}
-			} 
 		}
+	}
+
+	// replaced on every fetch, so warnings go away once the feed is fixed;
+	// never nil, which would be stored as NULL
+	err = db.SetFeedParseWarnings(ctx, database.SetFeedParseWarningsParams{
+		ID:            feed.ID,
+		ParseWarnings: append([]string{}, rssFeed.Warnings...),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save parse warnings of feed %s: %w", feed.ID, err)
+	}
+	if len(rssFeed.Warnings) > 0 {
+		fmt.Printf("feed %s is malformed, parsed it anyway: %s\n", feed.Url, strings.Join(rssFeed.Warnings, "; "))
+	}
+
+	nextFetch := NextFetch(rssFeed, time.Now(), intervals)
+	err = db.SetFeedNextFetchAt(ctx, database.SetFeedNextFetchAtParams{
+		ID:          feed.ID,
+		NextFetchAt: sql.NullTime{Time: nextFetch, Valid: true},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to schedule next fetch of feed %s: %w", feed.ID, err)
+	}
+
+	feedRules, err := db.GetRulesForFeed(ctx, feed.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get rules for feed %s: %w", feed.ID, err)
+	}
+
+	matchers, err := rules.CompileAll(feedRules)
+	if err != nil {
+		return fmt.Errorf("failed to compile rules for feed %s: %w", feed.ID, err)
+	}
+
+	alertingSearches, err := db.GetAlertingSearchesForFeed(ctx, feed.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get saved searches for feed %s: %w", feed.ID, err)
+	}
+
+	webhooks, err := db.GetWebhooksForFeed(ctx, feed.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get webhooks for feed %s: %w", feed.ID, err)
+	}
+
+	contentFetches := 0
+
+	// items older than the post retention would only be pruned again, and
+	// come back as new on every fetch of a feed that lists its whole archive
+	var tooOld time.Time
+	if retention := PostRetention(s.Config); retention > 0 {
+		tooOld = time.Now().Add(-retention)
+	}
+
+	for _, item := range rssFeed.Channel.Item {
+		// descriptions are stored sanitized; this runs before anything looks for the
+		// post's image so tracking pixels can't be picked
+		base, _ := url.Parse(item.Link)
+		item.Description = readability.Sanitize(item.Description, base)
+		item.Content = readability.Sanitize(item.Content, base)
+
+		var publishedAt sql.NullTime
+		if parsedTime, ok := item.Published(); ok {
+			publishedAt = sql.NullTime{Time: parsedTime, Valid: true}
+		}
+		if publishedAt.Valid && publishedAt.Time.Before(tooOld) {
+			continue
+		}
+
+		imageURL := item.ImageURL()
 
 		newPost := database.CreatePostParams{
 			ID:          uuid.New().String(),
 			CreatedAt:   time.Now(),
 			UpdatedAt:   time.Now(),
-			Title:      sql.NullString{String: item.Title, Valid: item.Title != ""},
-			Url:        item.Link,
+			Title:       sql.NullString{String: item.Title, Valid: item.Title != ""},
+			Url:         item.Link,
 			Description: sql.NullString{String: item.Description, Valid: item.Description != ""},
 			PublishedAt: publishedAt,
-			FeedID:     feed.ID,
+			FeedID:      feed.ID,
+			CommentsUrl: sql.NullString{String: item.Comments, Valid: item.Comments != ""},
+			ImageUrl:    sql.NullString{String: imageURL, Valid: imageURL != ""},
 		}
 
-		_, err := db.CreatePost(ctx, newPost)
+		if seconds, ok := item.DurationSeconds(); ok {
+			newPost.DurationSeconds = sql.NullInt32{Int32: int32(seconds), Valid: true}
+		}
+		if episode, ok := item.EpisodeNumber(); ok {
+			newPost.Episode = sql.NullInt32{Int32: int32(episode), Valid: true}
+		}
+		if season, ok := item.SeasonNumber(); ok {
+			newPost.Season = sql.NullInt32{Int32: int32(season), Valid: true}
+		}
+
+		// content:encoded is the full post, sanitized the same way fetched articles are
+		if item.Content != "" {
+			if article, err := readability.Clean(strings.NewReader(item.Content), base); err == nil {
+				newPost.ContentHtml = sql.NullString{String: article.HTML, Valid: true}
+				newPost.ContentText = sql.NullString{String: article.Text, Valid: true}
+			}
+		}
+
+		post, err := db.CreatePost(ctx, newPost)
 		if err != nil {
 			if strings.Contains(err.Error(), "duplicate") || strings.Contains(err.Error(), "unique") {
 				continue
@@ -65,7 +251,272 @@ func ScrapeFeeds(ctx context.Context, db *database.Queries) error {
 			continue
 		}
 
+		if err := saveEnclosures(ctx, db, post.ID, item.Enclosures); err != nil {
+			fmt.Printf("Error saving enclosures of post %s: %v\n", item.Link, err)
+		}
+
+		authors := item.AuthorNames()
+		categories := item.CategoryNames()
+		if err := savePostMetadata(ctx, db, post.ID, authors, categories); err != nil {
+			fmt.Printf("Error saving authors and categories of post %s: %v\n", item.Link, err)
+		}
+
+		// rules only run once, when the post is first seen
+		rulePost := rules.FromDatabasePost(post, authors, categories)
+		for _, matcher := range matchers {
+			if !matcher.Matches(rulePost) {
+				continue
+			}
+
+			if err := matcher.Apply(ctx, db, post.ID); err != nil {
+				fmt.Printf("Error applying rule %s to post %s: %v\n", matcher.Rule.ID, item.Link, err)
+			}
+		}
+
+		// saved searches with alerts on tag new matches with the search name
+		for _, savedSearch := range alertingSearches {
+			if !search.Matches(savedSearch, post, authors, categories) {
+				continue
+			}
+
+			err := db.TagPost(ctx, database.TagPostParams{
+				UserID:    savedSearch.UserID,
+				PostID:    post.ID,
+				Tag:       savedSearch.Name,
+				CreatedAt: time.Now(),
+			})
+			if err != nil {
+				fmt.Printf("Error flagging post %s for saved search %s: %v\n", item.Link, savedSearch.Name, err)
+				continue
+			}
+
+			fmt.Printf("saved search %s matched %s\n", savedSearch.Name, item.Link)
+		}
+
+		// deliveries are only queued here, DeliverPending sends them
+		if err := webhook.Enqueue(ctx, db, webhooks, matchers, feed, post, authors, categories); err != nil {
+			fmt.Printf("Error queueing webhooks for post %s: %v\n", item.Link, err)
+		}
+
+		// the page is downloaded last, so a slow site doesn't hold up rules and alerts
+		if feed.FetchFullContent && !post.ContentHtml.Valid && contentFetches < maxContentFetchesPerScrape {
+			contentFetches++
+			if err := FetchPostContent(ctx, db, client, post); err != nil {
+				fmt.Printf("Error fetching full content: %v\n", err)
+			}
+		}
+
+	}
+
+	return nil
+}
+
+func reencryptCredentials(s *state.State, feed database.Feed, toFeedID string, data []byte) ([]byte, error) {
+	key, err := credentials.Key(s.Config)
+	if err != nil {
+		return nil, fmt.Errorf("feed %s has credentials: %w", feed.Name, err)
+	}
+
+	creds, err := credentials.Decrypt(key, feed.ID, data)
+	if err != nil {
+		return nil, fmt.Errorf("feed %s: %w", feed.Name, err)
+	}
+
+	encrypted, err := credentials.Encrypt(key, toFeedID, creds)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt credentials: %w", err)
+	}
+	return encrypted, nil
+}
+
+func feedHeader(ctx context.Context, s *state.State, feed database.Feed) (http.Header, error) {
+	// the decrypted credentials of private feeds as request headers, nil for public ones
+
+	data, err := s.DB.GetFeedCredentials(ctx, feed.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get credentials of feed %s: %w", feed.ID, err)
+	}
+
+	key, err := credentials.Key(s.Config)
+	if err != nil {
+		return nil, fmt.Errorf("feed %s has credentials: %w", feed.Name, err)
+	}
+
+	creds, err := credentials.Decrypt(key, feed.ID, data)
+	if err != nil {
+		return nil, fmt.Errorf("feed %s: %w", feed.Name, err)
+	}
+
+	return creds.Header(), nil
+}
+
+func rescheduleFeed(ctx context.Context, db *database.Queries, feed database.Feed, next time.Time, fetchErr error) error {
+	err := db.SetFeedNextFetchAt(ctx, database.SetFeedNextFetchAtParams{
+		ID:          feed.ID,
+		NextFetchAt: sql.NullTime{Time: next, Valid: true},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to schedule next fetch of feed %s: %w", feed.ID, err)
+	}
+
+	return fmt.Errorf("failed to fetch feed from url %s, trying again at %s: %w", feed.Url, next.Format(time.DateTime), fetchErr)
+}
+
+func moveFeed(ctx context.Context, s *state.State, feed database.Feed, newURL string) (database.Feed, error) {
+	// points a permanently redirected feed at its new URL, or, when another feed
+	// already has that URL, merges the feed into it and returns that one
+
+	target, err := s.DB.GetFeedByURL(ctx, newURL)
+	if errors.Is(err, sql.ErrNoRows) {
+		moved, err := s.DB.SetFeedURL(ctx, database.SetFeedURLParams{
+			ID:  feed.ID,
+			Url: newURL,
+		})
+		if err != nil {
+			return feed, fmt.Errorf("failed to change URL of feed %s: %w", feed.Name, err)
+		}
+
+		fmt.Printf("feed %s moved permanently from %s to %s, updated its URL\n", feed.Name, feed.Url, newURL)
+		return moved, nil
+	}
+	if err != nil {
+		return feed, fmt.Errorf("failed to get feed by URL %s: %w", newURL, err)
+	}
+
+	// credentials can't be dropped with the old feed, that would only leave the
+	// target failing to authenticate; they go along when nobody else could use them
+	creds, err := s.DB.GetFeedCredentials(ctx, feed.ID)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		creds = nil
+	case err != nil:
+		return feed, fmt.Errorf("failed to get credentials of feed %s: %w", feed.ID, err)
+	}
+	if creds != nil {
+		_, err := s.DB.GetFeedCredentials(ctx, target.ID)
+		if err == nil || feed.UserID != target.UserID {
+			fmt.Printf("feed %s moved permanently to %s, which is feed %s; kept them apart so its credentials aren't lost or handed to another owner\n", feed.Name, newURL, target.Name)
+			return feed, nil
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return feed, fmt.Errorf("failed to get credentials of feed %s: %w", target.ID, err)
+		}
+
+		// encrypted for the target, since the feed ID is part of what was encrypted
+		if creds, err = reencryptCredentials(s, feed, target.ID, creds); err != nil {
+			return feed, err
+		}
+	}
+
+	var follows, posts int64
+	err = s.WithTx(ctx, func(q *database.Queries) error {
+		// the move queries all take the same two IDs
+		move := database.MoveFeedFollowsParams{ToFeedID: target.ID, FromFeedID: feed.ID}
+
+		var err error
+		if follows, err = q.MoveFeedFollows(ctx, move); err != nil {
+			return fmt.Errorf("failed to move follows: %w", err)
+		}
+		if posts, err = q.MoveFeedPosts(ctx, database.MoveFeedPostsParams(move)); err != nil {
+			return fmt.Errorf("failed to move posts: %w", err)
+		}
+		if err := q.MoveFeedRules(ctx, database.MoveFeedRulesParams(move)); err != nil {
+			return fmt.Errorf("failed to move rules: %w", err)
+		}
+		if err := q.MoveFeedSavedSearches(ctx, database.MoveFeedSavedSearchesParams(move)); err != nil {
+			return fmt.Errorf("failed to move saved searches: %w", err)
+		}
+		if err := q.MoveFeedWebhooks(ctx, database.MoveFeedWebhooksParams(move)); err != nil {
+			return fmt.Errorf("failed to move webhooks: %w", err)
+		}
+		if creds != nil {
+			err := q.SetFeedCredentials(ctx, database.SetFeedCredentialsParams{
+				FeedID:    target.ID,
+				CreatedAt: time.Now(),
+				Data:      creds,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to move credentials: %w", err)
+			}
+		}
+
+		// follows of users who already followed the target go with the old feed
+		if _, err := q.DeleteFeed(ctx, feed.ID); err != nil {
+			return fmt.Errorf("failed to delete feed: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return feed, fmt.Errorf("failed to merge feed %s into %s: %w", feed.Name, target.Name, err)
+	}
+
+	fmt.Printf("feed %s moved permanently to %s, merged it into feed %s (%d follows, %d posts)\n", feed.Name, newURL, target.Name, follows, posts)
+	return target, nil
+}
+
+func savePostMetadata(ctx context.Context, db *database.Queries, postID string, authors []string, categories []string) error {
+	// authors and categories are shared between posts, matched case-insensitively by name
+
+	for _, name := range authors {
+		author, err := db.UpsertAuthor(ctx, database.UpsertAuthorParams{
+			ID:        uuid.New().String(),
+			CreatedAt: time.Now(),
+			Name:      name,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to save author %s: %w", name, err)
+		}
+
+		err = db.AddPostAuthor(ctx, database.AddPostAuthorParams{PostID: postID, AuthorID: author.ID})
+		if err != nil {
+			return fmt.Errorf("failed to add author %s: %w", name, err)
+		}
+	}
+
+	for _, name := range categories {
+		category, err := db.UpsertCategory(ctx, database.UpsertCategoryParams{
+			ID:        uuid.New().String(),
+			CreatedAt: time.Now(),
+			Name:      name,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to save category %s: %w", name, err)
+		}
+
+		err = db.AddPostCategory(ctx, database.AddPostCategoryParams{PostID: postID, CategoryID: category.ID})
+		if err != nil {
+			return fmt.Errorf("failed to add category %s: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+func saveEnclosures(ctx context.Context, db *database.Queries, postID string, enclosures []rss.Enclosure) error {
+	for _, enclosure := range enclosures {
+		enclosureURL := strings.TrimSpace(enclosure.URL)
+		if enclosureURL == "" {
+			continue
+		}
+
+		params := database.CreateEnclosureParams{
+			ID:        uuid.New().String(),
+			CreatedAt: time.Now(),
+			PostID:    postID,
+			Url:       enclosureURL,
+			MimeType:  sql.NullString{String: enclosure.Type, Valid: enclosure.Type != ""},
+		}
+		if size, ok := enclosure.Size(); ok {
+			params.Length = sql.NullInt64{Int64: size, Valid: true}
+		}
+
+		if err := db.CreateEnclosure(ctx, params); err != nil {
+			return fmt.Errorf("failed to save enclosure %s: %w", enclosureURL, err)
+		}
 	}
 
 	return nil
-}
\ No newline at end of file
+}
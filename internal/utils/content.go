@@ -0,0 +1,33 @@
+package utils
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"blog-aggregator/internal/database"
+	"blog-aggregator/internal/fetch"
+	"blog-aggregator/internal/readability"
+)
+
+// new posts of feeds with fetch_full_content get their page downloaded; this caps how
+// many pages one scrape downloads, so a feed's first fetch doesn't hammer its site
+const maxContentFetchesPerScrape = 20
+
+func FetchPostContent(ctx context.Context, db *database.Queries, client *fetch.Client, post database.Post) error {
+	article, err := readability.Fetch(ctx, client, post.Url)
+	if err != nil {
+		return fmt.Errorf("failed to extract article from %s: %w", post.Url, err)
+	}
+
+	err = db.SetPostContent(ctx, database.SetPostContentParams{
+		ID:          post.ID,
+		ContentHtml: sql.NullString{String: article.HTML, Valid: true},
+		ContentText: sql.NullString{String: article.Text, Valid: true},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save content of post %s: %w", post.Url, err)
+	}
+
+	return nil
+}
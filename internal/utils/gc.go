@@ -0,0 +1,36 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"blog-aggregator/internal/database"
+)
+
+const DefaultFeedGracePeriod = 7 * 24 * time.Hour
+
+func CollectUnfollowedFeeds(ctx context.Context, db *database.Queries, grace time.Duration) (int64, error) {
+	// feeds are shared, so they live for as long as anyone follows them.
+	// a feed nobody follows is first marked, then deleted once it has stayed
+	// unfollowed for longer than the grace period.
+
+	if _, err := db.AdoptOrphanedFeeds(ctx); err != nil {
+		return 0, fmt.Errorf("failed to adopt orphaned feeds: %w", err)
+	}
+
+	if _, err := db.ClearRefollowedFeeds(ctx); err != nil {
+		return 0, fmt.Errorf("failed to clear refollowed feeds: %w", err)
+	}
+
+	if _, err := db.MarkUnfollowedFeeds(ctx); err != nil {
+		return 0, fmt.Errorf("failed to mark unfollowed feeds: %w", err)
+	}
+
+	deleted, err := db.DeleteCollectableFeeds(ctx, time.Now().Add(-grace))
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete unfollowed feeds: %w", err)
+	}
+
+	return deleted, nil
+}
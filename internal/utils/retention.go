@@ -0,0 +1,53 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"blog-aggregator/internal/config"
+	"blog-aggregator/internal/database"
+)
+
+// how long job runs and finished webhook deliveries are kept
+const DefaultHistoryRetention = 30 * 24 * time.Hour
+
+func PruneHistory(ctx context.Context, db *database.Queries, retention time.Duration) (jobRuns int64, deliveries int64, err error) {
+	cutoff := time.Now().Add(-retention)
+
+	jobRuns, err = db.DeleteJobRunsBefore(ctx, cutoff)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to delete old job runs: %w", err)
+	}
+
+	// attempts go with their delivery
+	deliveries, err = db.DeleteFinishedWebhookDeliveries(ctx, cutoff)
+	if err != nil {
+		return jobRuns, 0, fmt.Errorf("failed to delete old webhook deliveries: %w", err)
+	}
+
+	return jobRuns, deliveries, nil
+}
+
+func PostRetention(cfg *config.Config) time.Duration {
+	// how long posts are kept, 0 for forever
+	if cfg.PostRetentionDays <= 0 {
+		return 0
+	}
+	return time.Duration(cfg.PostRetentionDays) * 24 * time.Hour
+}
+
+func PrunePosts(ctx context.Context, db *database.Queries, retention time.Duration) (int64, error) {
+	// starred, unread and downloaded posts are kept however old they are, see DeleteOldPosts
+
+	if retention <= 0 {
+		return 0, nil
+	}
+
+	posts, err := db.DeleteOldPosts(ctx, time.Now().Add(-retention))
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete old posts: %w", err)
+	}
+
+	return posts, nil
+}
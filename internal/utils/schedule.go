@@ -0,0 +1,93 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"blog-aggregator/internal/config"
+	"blog-aggregator/rss"
+)
+
+const (
+	DefaultMinFetchInterval = 15 * time.Minute
+	DefaultMaxFetchInterval = 24 * time.Hour
+
+	// for feeds without enough dated posts to tell how often they publish
+	defaultFetchInterval = time.Hour
+	// how soon a feed is tried again after fetching it failed
+	retryFetchInterval = time.Hour
+)
+
+type FetchIntervals struct {
+	Min time.Duration
+	Max time.Duration
+}
+
+func FetchIntervalsFromConfig(cfg *config.FetchIntervalConfig) (FetchIntervals, error) {
+	// the defaults, with whatever the config's "fetch_interval" section sets
+
+	intervals := FetchIntervals{Min: DefaultMinFetchInterval, Max: DefaultMaxFetchInterval}
+	if cfg == nil {
+		return intervals, nil
+	}
+
+	for _, setting := range []struct {
+		name  string
+		value string
+		dest  *time.Duration
+	}{
+		{"min", cfg.Min, &intervals.Min},
+		{"max", cfg.Max, &intervals.Max},
+	} {
+		if setting.value == "" {
+			continue
+		}
+		duration, err := time.ParseDuration(setting.value)
+		if err != nil || duration <= 0 {
+			return FetchIntervals{}, fmt.Errorf("invalid fetch_interval.%s %q, expected a duration like 15m", setting.name, setting.value)
+		}
+		*setting.dest = duration
+	}
+
+	if intervals.Min > intervals.Max {
+		return FetchIntervals{}, errors.New("fetch_interval.min must not be longer than fetch_interval.max")
+	}
+
+	return intervals, nil
+}
+
+func (i FetchIntervals) clamp(interval time.Duration) time.Duration {
+	return min(max(interval, i.Min), i.Max)
+}
+
+func NextFetch(feed *rss.RSSFeed, now time.Time, intervals FetchIntervals) time.Time {
+	// feeds are polled about twice per post they usually publish; the publisher's
+	// <ttl>, sy:updatePeriod and Cache-Control hints can only stretch that
+
+	interval := defaultFetchInterval
+	if posting, ok := feed.PostingInterval(); ok {
+		interval = posting / 2
+	}
+
+	if ttl, ok := feed.TTL(); ok {
+		interval = max(interval, ttl)
+	}
+	if update, ok := feed.UpdateInterval(); ok {
+		interval = max(interval, update)
+	}
+	interval = max(interval, feed.MaxAge)
+
+	next := now.Add(intervals.clamp(interval))
+
+	// <skipHours> and <skipDays> push the fetch to the next allowed hour, giving
+	// up after a week in case the feed skips every hour
+	for range 7 * 24 {
+		if !feed.Skips(next) {
+			break
+		}
+		next = next.Truncate(time.Hour).Add(time.Hour)
+	}
+
+	return next
+}
@@ -0,0 +1,188 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"blog-aggregator/internal/config"
+	"blog-aggregator/rss"
+)
+
+// Monday 19 October 2026, 10:20 GMT
+var scheduleNow = time.Date(2026, 10, 19, 10, 20, 0, 0, time.UTC)
+
+func postingEvery(gap time.Duration) []rss.RSSItem {
+	var items []rss.RSSItem
+	for i := range 5 {
+		published := scheduleNow.Add(-time.Duration(i) * gap)
+		items = append(items, rss.RSSItem{PubDate: published.Format(time.RFC1123Z)})
+	}
+	return items
+}
+
+func TestNextFetch(t *testing.T) {
+	defaults := FetchIntervals{Min: DefaultMinFetchInterval, Max: DefaultMaxFetchInterval}
+
+	tests := []struct {
+		name      string
+		feed      func(*rss.RSSFeed)
+		intervals FetchIntervals
+		want      time.Duration
+	}{
+		{
+			name: "no posting history",
+			want: defaultFetchInterval,
+		},
+		{
+			name: "half the posting interval",
+			feed: func(f *rss.RSSFeed) { f.Channel.Item = postingEvery(4 * time.Hour) },
+			want: 2 * time.Hour,
+		},
+		{
+			name: "ttl stretches it",
+			feed: func(f *rss.RSSFeed) {
+				f.Channel.Item = postingEvery(4 * time.Hour)
+				f.Channel.TTL = "180"
+			},
+			want: 3 * time.Hour,
+		},
+		{
+			name: "a short ttl doesn't shorten it",
+			feed: func(f *rss.RSSFeed) {
+				f.Channel.Item = postingEvery(4 * time.Hour)
+				f.Channel.TTL = "30"
+			},
+			want: 2 * time.Hour,
+		},
+		{
+			name: "sy:updatePeriod stretches it",
+			feed: func(f *rss.RSSFeed) {
+				f.Channel.Item = postingEvery(4 * time.Hour)
+				f.Channel.UpdatePeriod = "daily"
+				f.Channel.UpdateFrequency = "4"
+			},
+			want: 6 * time.Hour,
+		},
+		{
+			name: "Cache-Control max-age stretches it",
+			feed: func(f *rss.RSSFeed) {
+				f.Channel.Item = postingEvery(4 * time.Hour)
+				f.MaxAge = 5 * time.Hour
+			},
+			want: 5 * time.Hour,
+		},
+		{
+			name: "the longest hint wins",
+			feed: func(f *rss.RSSFeed) {
+				f.Channel.TTL = "120"
+				f.Channel.UpdatePeriod = "hourly"
+				f.MaxAge = 90 * time.Minute
+			},
+			want: 2 * time.Hour,
+		},
+		{
+			name: "clamped to the minimum",
+			feed: func(f *rss.RSSFeed) { f.Channel.Item = postingEvery(10 * time.Minute) },
+			want: DefaultMinFetchInterval,
+		},
+		{
+			name: "clamped to the maximum",
+			feed: func(f *rss.RSSFeed) { f.Channel.Item = postingEvery(30 * 24 * time.Hour) },
+			want: DefaultMaxFetchInterval,
+		},
+		{
+			name: "hints are clamped too",
+			feed: func(f *rss.RSSFeed) { f.Channel.UpdatePeriod = "yearly" },
+			want: DefaultMaxFetchInterval,
+		},
+		{
+			name:      "configured bounds",
+			feed:      func(f *rss.RSSFeed) { f.Channel.Item = postingEvery(10 * time.Minute) },
+			intervals: FetchIntervals{Min: time.Minute, Max: time.Hour},
+			want:      5 * time.Minute,
+		},
+		{
+			name: "skipped hours move it to the next allowed hour",
+			feed: func(f *rss.RSSFeed) { f.Channel.SkipHours = []string{"11", "12"} },
+			// due at 11:20, 11 and 12 are skipped
+			want: 2*time.Hour + 40*time.Minute,
+		},
+		{
+			name: "skipped hours wrap past midnight",
+			feed: func(f *rss.RSSFeed) {
+				f.Channel.TTL = "720"
+				f.Channel.SkipHours = []string{"22", "23", "24", "1"}
+			},
+			// due at 22:20, then 23:00, midnight and 1:00 are skipped too, so 2:00
+			want: 15*time.Hour + 40*time.Minute,
+		},
+		{
+			name: "skipped days wrap past the end of the week",
+			feed: func(f *rss.RSSFeed) {
+				f.Channel.UpdatePeriod = "weekly"
+				f.Channel.SkipDays = []string{"Saturday", "Sunday", "Monday"}
+			},
+			intervals: FetchIntervals{Min: time.Minute, Max: 6 * 24 * time.Hour},
+			// due on Sunday at 10:20, so the next allowed time is Tuesday at midnight
+			want: 7*24*time.Hour + 13*time.Hour + 40*time.Minute,
+		},
+	}
+
+	for _, tt := range tests {
+		var feed rss.RSSFeed
+		if tt.feed != nil {
+			tt.feed(&feed)
+		}
+		intervals := tt.intervals
+		if intervals == (FetchIntervals{}) {
+			intervals = defaults
+		}
+
+		if got := NextFetch(&feed, scheduleNow, intervals).Sub(scheduleNow); got != tt.want {
+			t.Errorf("%s: next fetch in %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestNextFetchSkippingEverything(t *testing.T) {
+	// a feed that skips every day gives up after a week instead of looping forever
+	var feed rss.RSSFeed
+	feed.Channel.SkipDays = []string{"Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday", "Sunday"}
+
+	next := NextFetch(&feed, scheduleNow, FetchIntervals{Min: DefaultMinFetchInterval, Max: DefaultMaxFetchInterval})
+	if wait := next.Sub(scheduleNow); wait < defaultFetchInterval || wait > 8*24*time.Hour {
+		t.Errorf("next fetch in %v, want at most a week and a bit", wait)
+	}
+}
+
+func TestFetchIntervalsFromConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     *config.FetchIntervalConfig
+		want    FetchIntervals
+		wantErr bool
+	}{
+		{name: "no section", want: FetchIntervals{Min: DefaultMinFetchInterval, Max: DefaultMaxFetchInterval}},
+		{name: "empty section", cfg: &config.FetchIntervalConfig{}, want: FetchIntervals{Min: DefaultMinFetchInterval, Max: DefaultMaxFetchInterval}},
+		{name: "both set", cfg: &config.FetchIntervalConfig{Min: "5m", Max: "6h"}, want: FetchIntervals{Min: 5 * time.Minute, Max: 6 * time.Hour}},
+		{name: "only min", cfg: &config.FetchIntervalConfig{Min: "1h"}, want: FetchIntervals{Min: time.Hour, Max: DefaultMaxFetchInterval}},
+		{name: "equal", cfg: &config.FetchIntervalConfig{Min: "1h", Max: "60m"}, want: FetchIntervals{Min: time.Hour, Max: time.Hour}},
+		{name: "min over max", cfg: &config.FetchIntervalConfig{Min: "2h", Max: "1h"}, wantErr: true},
+		{name: "min over the default max", cfg: &config.FetchIntervalConfig{Min: "48h"}, wantErr: true},
+		{name: "no unit", cfg: &config.FetchIntervalConfig{Min: "15"}, wantErr: true},
+		{name: "not a duration", cfg: &config.FetchIntervalConfig{Max: "daily"}, wantErr: true},
+		{name: "zero", cfg: &config.FetchIntervalConfig{Min: "0s"}, wantErr: true},
+		{name: "negative", cfg: &config.FetchIntervalConfig{Max: "-1h"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := FetchIntervalsFromConfig(tt.cfg)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: error = %v, want error %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: FetchIntervalsFromConfig = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
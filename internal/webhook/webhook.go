@@ -0,0 +1,265 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"blog-aggregator/internal/database"
+	"blog-aggregator/internal/rules"
+
+	"github.com/google/uuid"
+)
+
+const (
+	SignatureHeader = "X-Gator-Signature"
+	DeliveryHeader  = "X-Gator-Delivery"
+	EventHeader     = "X-Gator-Event"
+
+	EventPostCreated = "post.created"
+
+	StatusPending   = "pending"
+	StatusDelivered = "delivered"
+	StatusFailed    = "failed"
+
+	// after this many attempts a delivery is marked failed and no longer retried
+	MaxAttempts = 8
+
+	baseBackoff = 30 * time.Second
+	maxBackoff  = 6 * time.Hour
+	batchSize   = 50
+
+	// responses are read and thrown away so connections can be reused
+	maxResponseBody = 64 * 1024
+)
+
+var DefaultClient = &http.Client{Timeout: 10 * time.Second}
+
+// Outbox is the part of the database DeliverPending works with; *database.Queries implements it
+type Outbox interface {
+	GetDueWebhookDeliveries(ctx context.Context, arg database.GetDueWebhookDeliveriesParams) ([]database.GetDueWebhookDeliveriesRow, error)
+	CreateWebhookAttempt(ctx context.Context, arg database.CreateWebhookAttemptParams) error
+	UpdateWebhookDelivery(ctx context.Context, arg database.UpdateWebhookDeliveryParams) error
+}
+
+type Payload struct {
+	Event string      `json:"event"`
+	Feed  FeedPayload `json:"feed"`
+	Post  PostPayload `json:"post"`
+}
+
+type FeedPayload struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+type PostPayload struct {
+	ID          string     `json:"id"`
+	Title       string     `json:"title"`
+	URL         string     `json:"url"`
+	Description string     `json:"description,omitempty"`
+	Authors     []string   `json:"authors,omitempty"`
+	Categories  []string   `json:"categories,omitempty"`
+	CommentsURL string     `json:"comments_url,omitempty"`
+	ImageURL    string     `json:"image_url,omitempty"`
+	PublishedAt *time.Time `json:"published_at,omitempty"`
+}
+
+func NewPayload(feed database.Feed, post database.Post, authors []string, categories []string) ([]byte, error) {
+	payload := Payload{
+		Event: EventPostCreated,
+		Feed: FeedPayload{
+			ID:   feed.ID,
+			Name: feed.Name,
+			URL:  feed.Url,
+		},
+		Post: PostPayload{
+			ID:          post.ID,
+			Title:       post.Title.String,
+			URL:         post.Url,
+			Description: post.Description.String,
+			Authors:     authors,
+			Categories:  categories,
+			CommentsURL: post.CommentsUrl.String,
+			ImageURL:    post.ImageUrl.String,
+		},
+	}
+
+	if post.PublishedAt.Valid {
+		publishedAt := post.PublishedAt.Time.UTC()
+		payload.Post.PublishedAt = &publishedAt
+	}
+
+	return json.Marshal(payload)
+}
+
+func NewSecret() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(secret), nil
+}
+
+func Sign(secret string, body []byte) string {
+	// receivers recompute this over the raw request body to check it came from us
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func Backoff(attempts int) time.Duration {
+	// 30s, 1m, 2m, 4m, ... capped at maxBackoff
+
+	backoff := baseBackoff
+	for i := 1; i < attempts; i++ {
+		backoff *= 2
+		if backoff >= maxBackoff {
+			return maxBackoff
+		}
+	}
+
+	return backoff
+}
+
+func Enqueue(ctx context.Context, db *database.Queries, webhooks []database.Webhook, matchers []rules.Matcher, feed database.Feed, post database.Post, authors []string, categories []string) error {
+	// puts a delivery for the post in the outbox of every webhook that wants it
+
+	var payload []byte
+	rulePost := rules.FromDatabasePost(post, authors, categories)
+
+	for _, hook := range webhooks {
+		if hook.RuleID.Valid && !ruleMatches(hook.RuleID.String, matchers, rulePost) {
+			continue
+		}
+
+		if payload == nil {
+			var err error
+			payload, err = NewPayload(feed, post, authors, categories)
+			if err != nil {
+				return fmt.Errorf("failed to build payload for post %s: %w", post.Url, err)
+			}
+		}
+
+		err := db.CreateWebhookDelivery(ctx, database.CreateWebhookDeliveryParams{
+			ID:            uuid.NewString(),
+			CreatedAt:     time.Now(),
+			UpdatedAt:     time.Now(),
+			WebhookID:     hook.ID,
+			PostID:        post.ID,
+			Payload:       string(payload),
+			NextAttemptAt: time.Now(),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to queue post %s for webhook %s: %w", post.Url, hook.ID, err)
+		}
+	}
+
+	return nil
+}
+
+func ruleMatches(ruleID string, matchers []rules.Matcher, post rules.Post) bool {
+	for _, matcher := range matchers {
+		if matcher.Rule.ID == ruleID {
+			return matcher.Matches(post)
+		}
+	}
+
+	return false
+}
+
+func DeliverPending(ctx context.Context, db Outbox, client *http.Client) (delivered int, failed int, err error) {
+	// sends every delivery that is due, recording each attempt and scheduling retries
+
+	deliveries, err := db.GetDueWebhookDeliveries(ctx, database.GetDueWebhookDeliveriesParams{
+		NextAttemptAt: time.Now(),
+		Limit:         batchSize,
+	})
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get due webhook deliveries: %w", err)
+	}
+
+	for _, delivery := range deliveries {
+		statusCode, sendErr := send(ctx, client, delivery)
+		attempts := int(delivery.Attempts) + 1
+
+		attempt := database.CreateWebhookAttemptParams{
+			ID:          uuid.NewString(),
+			DeliveryID:  delivery.ID,
+			AttemptedAt: time.Now(),
+		}
+		if statusCode != 0 {
+			attempt.StatusCode = sql.NullInt32{Int32: int32(statusCode), Valid: true}
+		}
+		if sendErr != nil {
+			attempt.Error = sql.NullString{String: sendErr.Error(), Valid: true}
+		}
+		if err := db.CreateWebhookAttempt(ctx, attempt); err != nil {
+			return delivered, failed, fmt.Errorf("failed to record webhook attempt: %w", err)
+		}
+
+		update := database.UpdateWebhookDeliveryParams{
+			ID:            delivery.ID,
+			Status:        StatusPending,
+			Attempts:      int32(attempts),
+			NextAttemptAt: time.Now().Add(Backoff(attempts)),
+		}
+		switch {
+		case sendErr == nil:
+			update.Status = StatusDelivered
+			update.NextAttemptAt = delivery.NextAttemptAt
+			update.DeliveredAt = sql.NullTime{Time: time.Now(), Valid: true}
+			delivered++
+		case attempts >= MaxAttempts:
+			update.Status = StatusFailed
+			failed++
+		}
+
+		if err := db.UpdateWebhookDelivery(ctx, update); err != nil {
+			return delivered, failed, fmt.Errorf("failed to update webhook delivery %s: %w", delivery.ID, err)
+		}
+	}
+
+	return delivered, failed, nil
+}
+
+func send(ctx context.Context, client *http.Client, delivery database.GetDueWebhookDeliveriesRow) (int, error) {
+	body := []byte(delivery.Payload)
+
+	request, err := http.NewRequestWithContext(ctx, "POST", delivery.WebhookUrl, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("User-Agent", "gator")
+	request.Header.Set(EventHeader, EventPostCreated)
+	request.Header.Set(DeliveryHeader, delivery.ID)
+	request.Header.Set(SignatureHeader, Sign(delivery.WebhookSecret, body))
+
+	response, err := client.Do(request)
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+
+	io.Copy(io.Discard, io.LimitReader(response.Body, maxResponseBody))
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return response.StatusCode, fmt.Errorf("HTTP Error: %d", response.StatusCode)
+	}
+
+	return response.StatusCode, nil
+}
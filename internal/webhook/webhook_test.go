@@ -0,0 +1,253 @@
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"blog-aggregator/internal/database"
+)
+
+// fakeOutbox keeps deliveries in memory, the way the webhook_deliveries table would
+type fakeOutbox struct {
+	deliveries map[string]*database.GetDueWebhookDeliveriesRow
+	attempts   []database.CreateWebhookAttemptParams
+}
+
+func newFakeOutbox(deliveries ...database.GetDueWebhookDeliveriesRow) *fakeOutbox {
+	outbox := &fakeOutbox{deliveries: map[string]*database.GetDueWebhookDeliveriesRow{}}
+	for i := range deliveries {
+		outbox.deliveries[deliveries[i].ID] = &deliveries[i]
+	}
+	return outbox
+}
+
+func (o *fakeOutbox) GetDueWebhookDeliveries(ctx context.Context, arg database.GetDueWebhookDeliveriesParams) ([]database.GetDueWebhookDeliveriesRow, error) {
+	var due []database.GetDueWebhookDeliveriesRow
+	for _, delivery := range o.deliveries {
+		if delivery.Status == StatusPending && !delivery.NextAttemptAt.After(arg.NextAttemptAt) {
+			due = append(due, *delivery)
+		}
+	}
+	return due, nil
+}
+
+func (o *fakeOutbox) CreateWebhookAttempt(ctx context.Context, arg database.CreateWebhookAttemptParams) error {
+	o.attempts = append(o.attempts, arg)
+	return nil
+}
+
+func (o *fakeOutbox) UpdateWebhookDelivery(ctx context.Context, arg database.UpdateWebhookDeliveryParams) error {
+	delivery := o.deliveries[arg.ID]
+	delivery.Status = arg.Status
+	delivery.Attempts = arg.Attempts
+	delivery.NextAttemptAt = arg.NextAttemptAt
+	delivery.DeliveredAt = arg.DeliveredAt
+	return nil
+}
+
+// receiver is an httptest webhook endpoint answering with the given status codes in turn
+type receiver struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	statuses []int
+	requests []receivedRequest
+}
+
+type receivedRequest struct {
+	header http.Header
+	body   []byte
+}
+
+func newReceiver(t *testing.T, statuses ...int) *receiver {
+	r := &receiver{statuses: statuses}
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, err := io.ReadAll(req.Body)
+		if err != nil {
+			t.Errorf("failed to read request body: %v", err)
+		}
+
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		status := http.StatusOK
+		if len(r.requests) < len(r.statuses) {
+			status = r.statuses[len(r.requests)]
+		}
+		r.requests = append(r.requests, receivedRequest{header: req.Header.Clone(), body: body})
+
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(r.Close)
+	return r
+}
+
+func pendingDelivery(url string) database.GetDueWebhookDeliveriesRow {
+	return database.GetDueWebhookDeliveriesRow{
+		ID:            "delivery-1",
+		WebhookID:     "webhook-1",
+		PostID:        "post-1",
+		Payload:       `{"event":"post.created","post":{"title":"Hello"}}`,
+		Status:        StatusPending,
+		NextAttemptAt: time.Now().Add(-time.Minute),
+		WebhookUrl:    url,
+		WebhookSecret: "s3cret",
+	}
+}
+
+func TestDeliverPendingSignsPayload(t *testing.T) {
+	r := newReceiver(t)
+	delivery := pendingDelivery(r.URL)
+	outbox := newFakeOutbox(delivery)
+
+	delivered, failed, err := DeliverPending(context.Background(), outbox, r.Client())
+	if err != nil {
+		t.Fatalf("DeliverPending: %v", err)
+	}
+	if delivered != 1 || failed != 0 {
+		t.Fatalf("delivered, failed = %d, %d, want 1, 0", delivered, failed)
+	}
+	if len(r.requests) != 1 {
+		t.Fatalf("receiver got %d requests, want 1", len(r.requests))
+	}
+
+	request := r.requests[0]
+	if string(request.body) != delivery.Payload {
+		t.Errorf("body = %s, want %s", request.body, delivery.Payload)
+	}
+
+	// checked the way a receiver would, without going through Sign
+	mac := hmac.New(sha256.New, []byte(delivery.WebhookSecret))
+	mac.Write(request.body)
+	want := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	if got := request.header.Get(SignatureHeader); !hmac.Equal([]byte(got), []byte(want)) {
+		t.Errorf("%s = %s, want %s", SignatureHeader, got, want)
+	}
+
+	if got := request.header.Get(DeliveryHeader); got != delivery.ID {
+		t.Errorf("%s = %s, want %s", DeliveryHeader, got, delivery.ID)
+	}
+	if got := request.header.Get(EventHeader); got != EventPostCreated {
+		t.Errorf("%s = %s, want %s", EventHeader, got, EventPostCreated)
+	}
+
+	stored := outbox.deliveries[delivery.ID]
+	if stored.Status != StatusDelivered || !stored.DeliveredAt.Valid || stored.Attempts != 1 {
+		t.Errorf("delivery = %s after %d attempts (delivered at %v), want delivered after 1", stored.Status, stored.Attempts, stored.DeliveredAt)
+	}
+}
+
+func TestDeliverPendingRetriesServerErrors(t *testing.T) {
+	r := newReceiver(t, http.StatusInternalServerError, http.StatusBadGateway, http.StatusOK)
+	delivery := pendingDelivery(r.URL)
+	outbox := newFakeOutbox(delivery)
+	stored := outbox.deliveries[delivery.ID]
+
+	for attempt := 1; attempt <= 2; attempt++ {
+		before := time.Now()
+		delivered, failed, err := DeliverPending(context.Background(), outbox, r.Client())
+		if err != nil {
+			t.Fatalf("attempt %d: DeliverPending: %v", attempt, err)
+		}
+		if delivered != 0 || failed != 0 {
+			t.Fatalf("attempt %d: delivered, failed = %d, %d, want 0, 0", attempt, delivered, failed)
+		}
+
+		if stored.Status != StatusPending || stored.Attempts != int32(attempt) {
+			t.Fatalf("attempt %d: delivery = %s after %d attempts, want pending", attempt, stored.Status, stored.Attempts)
+		}
+
+		// the retry waits out the backoff for this many attempts
+		backoff := stored.NextAttemptAt.Sub(before)
+		if backoff < Backoff(attempt) || backoff > Backoff(attempt)+time.Second {
+			t.Errorf("attempt %d: retry in %v, want %v", attempt, backoff, Backoff(attempt))
+		}
+
+		// not due yet, so nothing is sent
+		if _, _, err := DeliverPending(context.Background(), outbox, r.Client()); err != nil {
+			t.Fatalf("attempt %d: DeliverPending: %v", attempt, err)
+		}
+		if len(r.requests) != attempt {
+			t.Fatalf("attempt %d: receiver got %d requests before the backoff ran out", attempt, len(r.requests))
+		}
+
+		stored.NextAttemptAt = time.Now().Add(-time.Second)
+	}
+
+	delivered, _, err := DeliverPending(context.Background(), outbox, r.Client())
+	if err != nil {
+		t.Fatalf("DeliverPending: %v", err)
+	}
+	if delivered != 1 || stored.Status != StatusDelivered || stored.Attempts != 3 {
+		t.Errorf("delivery = %s after %d attempts, want delivered after 3", stored.Status, stored.Attempts)
+	}
+
+	if len(outbox.attempts) != 3 {
+		t.Fatalf("%d attempts recorded, want 3", len(outbox.attempts))
+	}
+	for i, want := range []int32{http.StatusInternalServerError, http.StatusBadGateway, http.StatusOK} {
+		attempt := outbox.attempts[i]
+		if attempt.StatusCode.Int32 != want {
+			t.Errorf("attempt %d: status %d, want %d", i+1, attempt.StatusCode.Int32, want)
+		}
+		if attempt.Error.Valid != (want != http.StatusOK) {
+			t.Errorf("attempt %d: error %q", i+1, attempt.Error.String)
+		}
+	}
+}
+
+func TestDeliverPendingFailsAfterMaxAttempts(t *testing.T) {
+	r := newReceiver(t, http.StatusServiceUnavailable)
+	delivery := pendingDelivery(r.URL)
+	delivery.Attempts = MaxAttempts - 1
+	outbox := newFakeOutbox(delivery)
+
+	delivered, failed, err := DeliverPending(context.Background(), outbox, r.Client())
+	if err != nil {
+		t.Fatalf("DeliverPending: %v", err)
+	}
+	if delivered != 0 || failed != 1 {
+		t.Fatalf("delivered, failed = %d, %d, want 0, 1", delivered, failed)
+	}
+
+	stored := outbox.deliveries[delivery.ID]
+	if stored.Status != StatusFailed || stored.Attempts != MaxAttempts {
+		t.Errorf("delivery = %s after %d attempts, want failed after %d", stored.Status, stored.Attempts, MaxAttempts)
+	}
+
+	// failed deliveries are never retried
+	stored.NextAttemptAt = time.Now().Add(-time.Second)
+	if _, _, err := DeliverPending(context.Background(), outbox, r.Client()); err != nil {
+		t.Fatalf("DeliverPending: %v", err)
+	}
+	if len(r.requests) != 1 {
+		t.Errorf("receiver got %d requests, want 1", len(r.requests))
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{6, 16 * time.Minute},
+		{20, maxBackoff},
+	}
+
+	for _, tt := range tests {
+		if got := Backoff(tt.attempts); got != tt.want {
+			t.Errorf("Backoff(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
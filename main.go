@@ -8,6 +8,7 @@ import (
 
 	_ "github.com/lib/pq"
 
+	"blog-aggregator/internal/auth"
 	"blog-aggregator/internal/commands"
 	"blog-aggregator/internal/config"
 	"blog-aggregator/internal/database"
@@ -35,21 +36,37 @@ func main() {
 
 	programState := &state.State{
 		DB:     dbQueries,
+		Conn:   db,
 		Config: &configFile,
 	}
 
 	cmds := &commands.Commands{}
 	cmds.Register("login", commands.HandlerLogin)
 	cmds.Register("register", commands.HandlerRegister)
-	cmds.Register("reset", commands.HandlerResetUsers)
-	cmds.Register("users", commands.HandlerGetUsers)
+	cmds.Register("reset", middleware.MiddlewareRole(auth.RoleAdmin, commands.HandlerReset))
+	cmds.Register("users", middleware.MiddlewareRole(auth.RoleReadOnly, commands.HandlerGetUsers))
+	cmds.Register("promote", middleware.MiddlewareRole(auth.RoleAdmin, commands.HandlerPromoteUser))
+	cmds.Register("demote", middleware.MiddlewareRole(auth.RoleAdmin, commands.HandlerDemoteUser))
+	cmds.Register("disable", middleware.MiddlewareRole(auth.RoleAdmin, commands.HandlerDisableUser))
+	cmds.Register("enable", middleware.MiddlewareRole(auth.RoleAdmin, commands.HandlerEnableUser))
+	cmds.Register("gc", middleware.MiddlewareRole(auth.RoleAdmin, commands.HandlerCollectFeeds))
+	cmds.Register("jobs", middleware.MiddlewareRole(auth.RoleReadOnly, commands.HandlerJobs))
 	cmds.Register("agg", commands.HandlerAgg)
-	cmds.Register("addfeed", middleware.MiddlewareLoggedIn(commands.HandlerAddFeed))
-	cmds.Register("feeds", commands.HandlerListFeeds)
-	cmds.Register("follow", middleware.MiddlewareLoggedIn(commands.HandlerFollowFeed))
+	cmds.Register("addfeed", middleware.MiddlewareRole(auth.RoleMember, commands.HandlerAddFeed))
+	cmds.Register("feed", middleware.MiddlewareRole(auth.RoleMember, commands.HandlerFeed))
+	cmds.Register("feeds", middleware.MiddlewareRole(auth.RoleReadOnly, commands.HandlerListFeeds))
+	cmds.Register("follow", middleware.MiddlewareRole(auth.RoleMember, commands.HandlerFollowFeed))
 	cmds.Register("following", middleware.MiddlewareLoggedIn(commands.HandlerListFollowedFeeds))
-	cmds.Register("unfollow", middleware.MiddlewareLoggedIn(commands.HandlerUnfollowFeed))
+	cmds.Register("unfollow", middleware.MiddlewareRole(auth.RoleMember, commands.HandlerUnfollowFeed))
+	cmds.Register("label", middleware.MiddlewareRole(auth.RoleMember, commands.HandlerLabelFeed))
+	cmds.Register("priority", middleware.MiddlewareRole(auth.RoleMember, commands.HandlerSetFeedPriority))
+	cmds.Register("folder", middleware.MiddlewareRole(auth.RoleMember, commands.HandlerFolder))
+	cmds.Register("rules", middleware.MiddlewareRole(auth.RoleMember, commands.HandlerRules))
+	cmds.Register("search", middleware.MiddlewareRole(auth.RoleMember, commands.HandlerSearch))
+	cmds.Register("webhooks", middleware.MiddlewareRole(auth.RoleMember, commands.HandlerWebhooks))
+	cmds.Register("digest", middleware.MiddlewareRole(auth.RoleMember, commands.HandlerDigest))
 	cmds.Register("browse", middleware.MiddlewareLoggedIn(commands.HandlerBrowse))
+	cmds.Register("download", middleware.MiddlewareRole(auth.RoleMember, commands.HandlerDownload))
 
 	// ensure we have at least one command line argument
 	if len(os.Args) < 2 {
@@ -74,4 +91,4 @@ func main() {
 	if err != nil {
 		log.Fatalf("Error running command: %v", err)
 	}
-}
\ No newline at end of file
+}
@@ -0,0 +1,106 @@
+package rss
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html/charset"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/unicode"
+)
+
+var (
+	xmlDeclaration = regexp.MustCompile(`^\s*<\?xml[^>]*?\bencoding\s*=\s*["']([^"']+)["']`)
+
+	// "Ã©", "â€™" and friends: UTF-8 that was decoded as Windows-1252 somewhere upstream
+	mojibake = regexp.MustCompile(`[ÂÃÅÆâ][\x{80}-\x{BF}\x{152}\x{153}\x{160}\x{161}\x{178}\x{17D}\x{17E}\x{192}\x{2C6}\x{2DC}\x{2013}\x{2014}\x{2018}-\x{201E}\x{2020}-\x{2022}\x{2026}\x{2030}\x{2039}\x{203A}\x{20AC}\x{2122}]`)
+)
+
+func toUTF8(body []byte, contentType string) ([]byte, error) {
+	// transcodes a feed to UTF-8, taking the encoding from the byte order mark, then
+	// the Content-Type header, then the XML declaration, as RFC 7303 orders them.
+	// the declaration is rewritten to match, since encoding/xml only reads UTF-8
+
+	switch {
+	case bytes.HasPrefix(body, []byte{0xEF, 0xBB, 0xBF}):
+		return withUTF8Declaration(body[3:]), nil
+	case bytes.HasPrefix(body, []byte{0xFE, 0xFF}), bytes.HasPrefix(body, []byte{0xFF, 0xFE}):
+		decoded, err := unicode.UTF16(unicode.BigEndian, unicode.ExpectBOM).NewDecoder().Bytes(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode UTF-16 feed: %w", err)
+		}
+		return withUTF8Declaration(decoded), nil
+	}
+
+	var labels []string
+	if _, params, err := mime.ParseMediaType(contentType); err == nil && params["charset"] != "" {
+		labels = append(labels, params["charset"])
+	}
+	if match := xmlDeclaration.FindSubmatch(body); match != nil {
+		labels = append(labels, string(match[1]))
+	}
+
+	for _, label := range labels {
+		encoding, name := charset.Lookup(label)
+		if encoding == nil {
+			continue
+		}
+
+		if name == "utf-8" {
+			// a header claiming UTF-8 is wrong surprisingly often, let the declaration have a say
+			if utf8.Valid(body) {
+				return withUTF8Declaration(body), nil
+			}
+			continue
+		}
+
+		decoded, err := encoding.NewDecoder().Bytes(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode %s feed: %w", name, err)
+		}
+		return withUTF8Declaration(decoded), nil
+	}
+
+	if utf8.Valid(body) {
+		return withUTF8Declaration(body), nil
+	}
+
+	// undeclared and not UTF-8: Windows-1252 is what such feeds almost always turn out to be
+	decoded, err := charmap.Windows1252.NewDecoder().Bytes(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode feed: %w", err)
+	}
+	return withUTF8Declaration(decoded), nil
+}
+
+func withUTF8Declaration(body []byte) []byte {
+	match := xmlDeclaration.FindSubmatchIndex(body)
+	if match == nil || strings.EqualFold(string(body[match[2]:match[3]]), "utf-8") {
+		return body
+	}
+
+	fixed := make([]byte, 0, len(body))
+	fixed = append(fixed, body[:match[2]]...)
+	fixed = append(fixed, "UTF-8"...)
+	return append(fixed, body[match[3]:]...)
+}
+
+func repairMojibake(text string) string {
+	// undoes one round of UTF-8 being read as Windows-1252, but only when the
+	// whole string converts back cleanly, so real Latin-1 text is left alone
+
+	if !mojibake.MatchString(text) {
+		return text
+	}
+
+	raw, err := charmap.Windows1252.NewEncoder().String(text)
+	if err != nil || !utf8.ValidString(raw) {
+		return text
+	}
+
+	return raw
+}
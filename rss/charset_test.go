@@ -0,0 +1,165 @@
+package rss
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/unicode"
+)
+
+func encode(t *testing.T, text string, encoder *encoding.Encoder) string {
+	t.Helper()
+
+	encoded, err := encoder.String(text)
+	if err != nil {
+		t.Fatalf("failed to encode %q: %v", text, err)
+	}
+	return encoded
+}
+
+func TestToUTF8(t *testing.T) {
+	const title = "<title>Café naïve – “quoted”</title>"
+
+	utf16 := func(endianness unicode.Endianness) string {
+		return encode(t, `<?xml version="1.0" encoding="UTF-16"?>`+title, unicode.UTF16(endianness, unicode.UseBOM).NewEncoder())
+	}
+	latin1 := encode(t, "<title>Café</title>", charmap.ISO8859_1.NewEncoder())
+	windows1252 := encode(t, title, charmap.Windows1252.NewEncoder())
+	shiftJIS := encode(t, "<title>日本語のブログ</title>", japanese.ShiftJIS.NewEncoder())
+
+	tests := []struct {
+		name        string
+		body        string
+		contentType string
+		want        string
+	}{
+		{
+			name: "plain UTF-8",
+			body: `<?xml version="1.0" encoding="utf-8"?>` + title,
+			want: `<?xml version="1.0" encoding="utf-8"?>` + title,
+		},
+		{
+			name: "UTF-8 byte order mark",
+			body: "\xEF\xBB\xBF" + `<?xml version="1.0"?>` + title,
+			want: `<?xml version="1.0"?>` + title,
+		},
+		{
+			name:        "byte order mark beats the header",
+			body:        utf16(unicode.BigEndian),
+			contentType: "text/xml; charset=iso-8859-1",
+			want:        `<?xml version="1.0" encoding="UTF-8"?>` + title,
+		},
+		{
+			name: "UTF-16 little endian",
+			body: utf16(unicode.LittleEndian),
+			want: `<?xml version="1.0" encoding="UTF-8"?>` + title,
+		},
+		{
+			name:        "ISO-8859-1 from the header",
+			body:        "<?xml version='1.0'?>" + latin1,
+			contentType: "application/rss+xml; charset=ISO-8859-1",
+			want:        "<?xml version='1.0'?><title>Café</title>",
+		},
+		{
+			name: "windows-1252 from the declaration",
+			body: `<?xml version="1.0" encoding="windows-1252"?>` + windows1252,
+			want: `<?xml version="1.0" encoding="UTF-8"?>` + title,
+		},
+		{
+			name:        "Shift_JIS from the declaration",
+			body:        `<?xml version="1.0" encoding="Shift_JIS"?>` + shiftJIS,
+			contentType: "application/xml",
+			want:        `<?xml version="1.0" encoding="UTF-8"?><title>日本語のブログ</title>`,
+		},
+		{
+			name:        "header beats the declaration",
+			body:        `<?xml version="1.0" encoding="Shift_JIS"?>` + latin1,
+			contentType: "text/xml; charset=iso-8859-1",
+			want:        `<?xml version="1.0" encoding="UTF-8"?><title>Café</title>`,
+		},
+		{
+			name:        "unknown header charset falls back to the declaration",
+			body:        `<?xml version="1.0" encoding="Shift_JIS"?>` + shiftJIS,
+			contentType: "text/xml; charset=klingon",
+			want:        `<?xml version="1.0" encoding="UTF-8"?><title>日本語のブログ</title>`,
+		},
+		{
+			// the header is wrong, so the declaration is believed instead
+			name:        "mislabelled as UTF-8 with a declaration",
+			body:        `<?xml version="1.0" encoding="windows-1252"?>` + windows1252,
+			contentType: "text/xml; charset=utf-8",
+			want:        `<?xml version="1.0" encoding="UTF-8"?>` + title,
+		},
+		{
+			name:        "mislabelled as UTF-8 without a declaration",
+			body:        windows1252,
+			contentType: "text/xml; charset=utf-8",
+			want:        title,
+		},
+		{
+			name: "undeclared and not UTF-8",
+			body: windows1252,
+			want: title,
+		},
+	}
+
+	for _, tt := range tests {
+		got, err := toUTF8([]byte(tt.body), tt.contentType)
+		if err != nil {
+			t.Errorf("%s: toUTF8: %v", tt.name, err)
+			continue
+		}
+		if string(got) != tt.want {
+			t.Errorf("%s: toUTF8 = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestToUTF8ParsesTranscodedFeed(t *testing.T) {
+	// the rewritten declaration is what lets encoding/xml read the result
+	body := encode(t, `<?xml version="1.0" encoding="Shift_JIS"?><rss version="2.0"><channel><title>日本語のブログ</title></channel></rss>`, japanese.ShiftJIS.NewEncoder())
+
+	utf8Body, err := toUTF8([]byte(body), "")
+	if err != nil {
+		t.Fatalf("toUTF8: %v", err)
+	}
+	feed, err := parse(utf8Body)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if feed.Channel.Title != "日本語のブログ" {
+		t.Errorf("title = %q", feed.Channel.Title)
+	}
+}
+
+func TestRepairMojibake(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"CafÃ©", "Café"},
+		{"Itâ€™s here â€“ finally", "It’s here – finally"},
+		{"Ã¼ber naÃ¯ve", "über naïve"},
+		// real text with the same letters is left alone
+		{"São Paulo", "São Paulo"},
+		{"NÃO", "NÃO"},
+		{"Ã la carte", "Ã la carte"},
+		{"Café", "Café"},
+		// one garbled word isn't undone if the rest can't be
+		{"CafÃ© 日本", "CafÃ© 日本"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := repairMojibake(tt.text); got != tt.want {
+			t.Errorf("repairMojibake(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+
+	if strings.Contains(decodeText("Caf&Atilde;&copy;"), "Ã") {
+		t.Error("decodeText left mojibake behind after unescaping")
+	}
+}
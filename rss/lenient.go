@@ -0,0 +1,84 @@
+package rss
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"regexp"
+)
+
+var (
+	reference    = regexp.MustCompile(`^&(?:#[0-9]+|#[xX][0-9a-fA-F]+|[a-zA-Z][a-zA-Z0-9]*);`)
+	cdataSection = regexp.MustCompile(`(?s)<!\[CDATA\[.*?\]\]>`)
+	// where a feed document can start, so anything printed before it can be cut off
+	documentStart = regexp.MustCompile(`<\?xml|<rss[\s>]|<(?:[a-zA-Z][\w.-]*:)?RDF[\s>]`)
+)
+
+func strictDecoder(body []byte) *xml.Decoder {
+	return xml.NewDecoder(bytes.NewReader(body))
+}
+
+func lenientDecoder(body []byte) *xml.Decoder {
+	// HTML entities like &nbsp; are understood, unknown ones kept as text, and
+	// unclosed HTML elements like <br> closed automatically
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	decoder.Strict = false
+	decoder.AutoClose = xml.HTMLAutoClose
+	decoder.Entity = xml.HTMLEntity
+	return decoder
+}
+
+func repairXML(body []byte) ([]byte, []string) {
+	// fixes the mistakes that most often make feeds unparseable, describing each one it fixed
+
+	var warnings []string
+
+	if start := documentStart.FindIndex(body); start != nil && start[0] > 0 {
+		if len(bytes.TrimSpace(body[:start[0]])) > 0 {
+			warnings = append(warnings, fmt.Sprintf("ignored %d bytes before the start of the document", start[0]))
+		}
+		body = body[start[0]:]
+	}
+
+	// control characters are never allowed in XML 1.0, not even escaped
+	removed := 0
+	body = bytes.Map(func(r rune) rune {
+		if r < 0x20 && r != '\t' && r != '\n' && r != '\r' || r == 0xFFFE || r == 0xFFFF {
+			removed++
+			return -1
+		}
+		return r
+	}, body)
+	if removed > 0 {
+		warnings = append(warnings, fmt.Sprintf("removed %d control characters", removed))
+	}
+
+	// ampersands inside CDATA are fine as they are
+	escaped := 0
+	var fixed []byte
+	last := 0
+	for _, section := range cdataSection.FindAllIndex(body, -1) {
+		fixed = escapeAmpersands(fixed, body[last:section[0]], &escaped)
+		fixed = append(fixed, body[section[0]:section[1]]...)
+		last = section[1]
+	}
+	fixed = escapeAmpersands(fixed, body[last:], &escaped)
+	if escaped > 0 {
+		warnings = append(warnings, fmt.Sprintf("escaped %d bare ampersands", escaped))
+	}
+
+	return fixed, warnings
+}
+
+func escapeAmpersands(dst []byte, text []byte, count *int) []byte {
+	// an & that doesn't start an entity or character reference becomes &amp;
+	for i, c := range text {
+		if c == '&' && !reference.Match(text[i:]) {
+			dst = append(dst, "&amp;"...)
+			*count++
+			continue
+		}
+		dst = append(dst, c)
+	}
+	return dst
+}
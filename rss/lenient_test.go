@@ -0,0 +1,134 @@
+package rss
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestRepairXML(t *testing.T) {
+	tests := []struct {
+		name         string
+		body         string
+		want         string
+		wantWarnings []string
+	}{
+		{
+			name: "valid feed",
+			body: `<?xml version="1.0"?><rss><channel><title>Fish &amp; chips &#169; &#xA9; &nbsp;</title></channel></rss>`,
+			want: `<?xml version="1.0"?><rss><channel><title>Fish &amp; chips &#169; &#xA9; &nbsp;</title></channel></rss>`,
+		},
+		{
+			name:         "junk before the declaration",
+			body:         "Warning: session_start() failed\n<?xml version=\"1.0\"?><rss/>",
+			want:         `<?xml version="1.0"?><rss/>`,
+			wantWarnings: []string{"ignored 32 bytes before the start of the document"},
+		},
+		{
+			name: "whitespace before the declaration",
+			body: "\n\n  <?xml version=\"1.0\"?><rss/>",
+			want: `<?xml version="1.0"?><rss/>`,
+		},
+		{
+			name:         "junk before an undeclared RDF document",
+			body:         `<br /><rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#"/>`,
+			want:         `<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#"/>`,
+			wantWarnings: []string{"ignored 6 bytes before the start of the document"},
+		},
+		{
+			name:         "control characters",
+			body:         "<rss><title>Bad\x00 \x08bytes\x1b</title>\t\r\n</rss>",
+			want:         "<rss><title>Bad bytes</title>\t\r\n</rss>",
+			wantWarnings: []string{"removed 3 control characters"},
+		},
+		{
+			name:         "bare ampersands",
+			body:         `<rss><title>Fish & chips</title><link>https://example.com/?a=1&b=2</link></rss>`,
+			want:         `<rss><title>Fish &amp; chips</title><link>https://example.com/?a=1&amp;b=2</link></rss>`,
+			wantWarnings: []string{"escaped 2 bare ampersands"},
+		},
+		{
+			name:         "ampersands inside CDATA",
+			body:         `<rss><description><![CDATA[Fish & chips]]> & <![CDATA[salt & vinegar]]></description></rss>`,
+			want:         `<rss><description><![CDATA[Fish & chips]]> &amp; <![CDATA[salt & vinegar]]></description></rss>`,
+			wantWarnings: []string{"escaped 1 bare ampersands"},
+		},
+		{
+			// HTML entities are left for the lenient decoder to understand
+			name: "HTML named entities",
+			body: `<rss><title>Caf&eacute;&nbsp;au lait &mdash; &amp;</title></rss>`,
+			want: `<rss><title>Caf&eacute;&nbsp;au lait &mdash; &amp;</title></rss>`,
+		},
+		{
+			name:         "everything at once",
+			body:         "<!-- cached -->\n<?xml version=\"1.0\"?><rss><title>A & B\x0c</title></rss>",
+			want:         `<?xml version="1.0"?><rss><title>A &amp; B</title></rss>`,
+			wantWarnings: []string{"ignored 16 bytes before the start of the document", "removed 1 control characters", "escaped 1 bare ampersands"},
+		},
+	}
+
+	for _, tt := range tests {
+		got, warnings := repairXML([]byte(tt.body))
+		if string(got) != tt.want {
+			t.Errorf("%s: repairXML = %q, want %q", tt.name, got, tt.want)
+		}
+		if !slices.Equal(warnings, tt.wantWarnings) {
+			t.Errorf("%s: warnings = %q, want %q", tt.name, warnings, tt.wantWarnings)
+		}
+	}
+}
+
+func TestParseLenient(t *testing.T) {
+	tests := []struct {
+		name         string
+		body         string
+		wantTitle    string
+		wantWarnings []string
+	}{
+		{
+			name:      "strictly valid",
+			body:      `<?xml version="1.0"?><rss version="2.0"><channel><title>Fish &amp; chips</title></channel></rss>`,
+			wantTitle: "Fish & chips",
+		},
+		{
+			name:         "junk and bare ampersands",
+			body:         "notice\n<?xml version=\"1.0\"?><rss version=\"2.0\"><channel><title>Fish & chips</title></channel></rss>",
+			wantTitle:    "Fish & chips",
+			wantWarnings: []string{"ignored 7 bytes before the start of the document", "escaped 1 bare ampersands"},
+		},
+		{
+			name:      "HTML entities and unclosed elements",
+			body:      `<rss version="2.0"><channel><title>Caf&eacute;&nbsp;menu</title><item><description>one<br>two</description></item></channel></rss>`,
+			wantTitle: "Café\u00a0menu",
+			// only the strict error, there was nothing to repair
+			wantWarnings: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		feed, err := parse([]byte(tt.body))
+		if err != nil {
+			t.Errorf("%s: parse: %v", tt.name, err)
+			continue
+		}
+		if feed.Channel.Title != tt.wantTitle {
+			t.Errorf("%s: title = %q, want %q", tt.name, feed.Channel.Title, tt.wantTitle)
+		}
+
+		if tt.wantWarnings == nil {
+			if len(feed.Warnings) != 0 {
+				t.Errorf("%s: warnings for a valid feed: %q", tt.name, feed.Warnings)
+			}
+			continue
+		}
+
+		// the strict error comes first, then each repair
+		if len(feed.Warnings) == 0 || !strings.HasPrefix(feed.Warnings[0], "strict parsing failed: ") {
+			t.Errorf("%s: warnings = %q, want the strict error first", tt.name, feed.Warnings)
+			continue
+		}
+		if repairs := feed.Warnings[1:]; !slices.Equal(repairs, tt.wantWarnings) {
+			t.Errorf("%s: repairs = %q, want %q", tt.name, repairs, tt.wantWarnings)
+		}
+	}
+}
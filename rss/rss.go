@@ -3,10 +3,17 @@ package rss
 import (
 	"context"
 	"encoding/xml"
+	"errors"
 	"fmt"
 	"html"
-	"io"
 	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"blog-aggregator/internal/fetch"
 )
 
 type RSSFeed struct {
@@ -15,53 +22,443 @@ type RSSFeed struct {
 		Link        string    `xml:"link"`
 		Description string    `xml:"description"`
 		Item        []RSSItem `xml:"item"`
+
+		// hints on how often to come back, see schedule.go
+		TTL             string   `xml:"ttl"`
+		UpdatePeriod    string   `xml:"http://purl.org/rss/1.0/modules/syndication/ updatePeriod"`
+		UpdateFrequency string   `xml:"http://purl.org/rss/1.0/modules/syndication/ updateFrequency"`
+		SkipHours       []string `xml:"skipHours>hour"`
+		SkipDays        []string `xml:"skipDays>day"`
 	} `xml:"channel"`
+
+	// what had to be repaired for a malformed feed to parse at all
+	Warnings []string `xml:"-"`
+	// set when the feed was reached through permanent redirects only
+	MovedTo string `xml:"-"`
+	// how long the server said the response stays fresh, from Cache-Control
+	MaxAge time.Duration `xml:"-"`
 }
 
 type RSSItem struct {
-	Title       string `xml:"title"`
-	Link        string `xml:"link"`
-	Description string `xml:"description"`
-	PubDate     string `xml:"pubDate"`
+	Title       string   `xml:"title"`
+	Link        string   `xml:"link"`
+	Description string   `xml:"description"`
+	PubDate     string   `xml:"pubDate"`
+	Date        string   `xml:"http://purl.org/dc/elements/1.1/ date"`
+	Content     string   `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+	Creators    []string `xml:"http://purl.org/dc/elements/1.1/ creator"`
+	Author      string   `xml:"author"`
+	Categories  []string `xml:"category"`
+	Subjects    []string `xml:"http://purl.org/dc/elements/1.1/ subject"`
+	Comments    string   `xml:"comments"`
+
+	Enclosures []Enclosure `xml:"enclosure"`
+	Duration   string      `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd duration"`
+	Episode    string      `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd episode"`
+	Season     string      `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd season"`
+	Image      ItunesImage `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd image"`
+
+	MediaContents   []MediaContent   `xml:"http://search.yahoo.com/mrss/ content"`
+	MediaThumbnails []MediaThumbnail `xml:"http://search.yahoo.com/mrss/ thumbnail"`
+	MediaGroups     []MediaGroup     `xml:"http://search.yahoo.com/mrss/ group"`
+}
+
+// attributes are kept as strings, a malformed length shouldn't fail the whole feed
+type Enclosure struct {
+	URL    string `xml:"url,attr"`
+	Type   string `xml:"type,attr"`
+	Length string `xml:"length,attr"`
+}
+
+type ItunesImage struct {
+	Href string `xml:"href,attr"`
+}
+
+// Media RSS, http://www.rssboard.org/media-rss
+type MediaContent struct {
+	URL        string           `xml:"url,attr"`
+	Type       string           `xml:"type,attr"`
+	Medium     string           `xml:"medium,attr"`
+	Thumbnails []MediaThumbnail `xml:"http://search.yahoo.com/mrss/ thumbnail"`
+}
+
+type MediaThumbnail struct {
+	URL string `xml:"url,attr"`
+}
+
+// a group holds alternative versions of the same media
+type MediaGroup struct {
+	Contents   []MediaContent   `xml:"http://search.yahoo.com/mrss/ content"`
+	Thumbnails []MediaThumbnail `xml:"http://search.yahoo.com/mrss/ thumbnail"`
 }
 
-func FetchFeed(ctx context.Context, feedURL string) (*RSSFeed, error) {
-	request, err := http.NewRequestWithContext(ctx, "GET", feedURL, nil)
+func (c MediaContent) isImage() bool {
+	return c.Medium == "image" || strings.HasPrefix(c.Type, "image/")
+}
+
+const (
+	rdfNamespace   = "http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+	rss10Namespace = "http://purl.org/rss/1.0/"
+)
+
+var ErrUnsupportedFormat = errors.New("unsupported feed format")
+
+// the server answered 410 Gone, the feed was removed on purpose
+var ErrGone = errors.New("feed is gone (HTTP 410)")
+
+// rdfFeed is RSS 1.0, where the items are siblings of the channel instead of inside it
+type rdfFeed struct {
+	Channel struct {
+		Title           string `xml:"title"`
+		Link            string `xml:"link"`
+		Description     string `xml:"description"`
+		UpdatePeriod    string `xml:"http://purl.org/rss/1.0/modules/syndication/ updatePeriod"`
+		UpdateFrequency string `xml:"http://purl.org/rss/1.0/modules/syndication/ updateFrequency"`
+	} `xml:"http://purl.org/rss/1.0/ channel"`
+	Item []RSSItem `xml:"http://purl.org/rss/1.0/ item"`
+}
+
+// date formats seen in pubDate and dc:date, tried in order
+var dateLayouts = []string{
+	time.RFC1123Z,
+	time.RFC1123,
+	time.RFC822Z,
+	time.RFC822,
+	time.RFC3339,
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02",
+}
+
+var firstImage = regexp.MustCompile(`(?is)<img\s[^>]*?\bsrc\s*=\s*["']([^"']+)["']`)
+
+var authorName = regexp.MustCompile(`^\S+@\S+\s*\((.+)\)$`)
+
+// a web page, with or without a doctype, possibly after comments
+var htmlDocument = regexp.MustCompile(`(?is)^\s*(?:<!--.*?-->\s*)*<(?:!doctype\s+html|html[\s>])`)
+
+func (item RSSItem) AuthorNames() []string {
+	// dc:creator holds plain names, RSS's own author is an email address
+	// with the name in parentheses, like "jane@example.com (Jane Doe)"
+
+	names := append([]string{}, item.Creators...)
+	if item.Author != "" {
+		author := strings.TrimSpace(item.Author)
+		if match := authorName.FindStringSubmatch(author); match != nil {
+			author = match[1]
+		}
+		names = append(names, author)
+	}
+
+	return uniqueNames(names)
+}
+
+func (item RSSItem) CategoryNames() []string {
+	// RSS 1.0 feeds use dc:subject for categories
+	return uniqueNames(append(append([]string{}, item.Categories...), item.Subjects...))
+}
+
+func (e Enclosure) Size() (int64, bool) {
+	// feeds often put 0 or nothing here when they don't know the size
+	size, err := strconv.ParseInt(strings.TrimSpace(e.Length), 10, 64)
+	if err != nil || size <= 0 {
+		return 0, false
+	}
+	return size, true
+}
+
+func (item RSSItem) DurationSeconds() (int, bool) {
+	// itunes:duration is either plain seconds or [[hh:]mm:]ss
+
+	value := strings.TrimSpace(item.Duration)
+	if value == "" {
+		return 0, false
+	}
+
+	parts := strings.Split(value, ":")
+	if len(parts) > 3 {
+		return 0, false
+	}
+
+	seconds := 0
+	for _, part := range parts {
+		// some feeds add fractions of a second
+		whole, _, _ := strings.Cut(part, ".")
+		n, err := strconv.Atoi(whole)
+		if err != nil || n < 0 {
+			return 0, false
+		}
+		seconds = seconds*60 + n
+	}
+
+	return seconds, true
+}
+
+func (item RSSItem) EpisodeNumber() (int, bool) {
+	return positiveNumber(item.Episode)
+}
+
+func (item RSSItem) SeasonNumber() (int, bool) {
+	return positiveNumber(item.Season)
+}
+
+func positiveNumber(value string) (int, bool) {
+	n, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || n <= 0 {
+		return 0, false
+	}
+	return n, true
+}
+
+func (item RSSItem) ImageURL() string {
+	// the image that best represents the item: an explicit thumbnail first, then
+	// image media, the episode artwork, and finally the first image in its HTML
+
+	var candidates []string
+
+	for _, thumbnail := range item.MediaThumbnails {
+		candidates = append(candidates, thumbnail.URL)
+	}
+	for _, content := range item.MediaContents {
+		for _, thumbnail := range content.Thumbnails {
+			candidates = append(candidates, thumbnail.URL)
+		}
+	}
+	for _, group := range item.MediaGroups {
+		for _, thumbnail := range group.Thumbnails {
+			candidates = append(candidates, thumbnail.URL)
+		}
+		for _, content := range group.Contents {
+			for _, thumbnail := range content.Thumbnails {
+				candidates = append(candidates, thumbnail.URL)
+			}
+		}
+	}
+
+	for _, content := range item.MediaContents {
+		if content.isImage() {
+			candidates = append(candidates, content.URL)
+		}
+	}
+	for _, group := range item.MediaGroups {
+		for _, content := range group.Contents {
+			if content.isImage() {
+				candidates = append(candidates, content.URL)
+			}
+		}
+	}
+
+	candidates = append(candidates, item.Image.Href)
+
+	for _, enclosure := range item.Enclosures {
+		if strings.HasPrefix(enclosure.Type, "image/") {
+			candidates = append(candidates, enclosure.URL)
+		}
+	}
+
+	for _, markup := range []string{item.Content, item.Description} {
+		if match := firstImage.FindStringSubmatch(markup); match != nil {
+			candidates = append(candidates, html.UnescapeString(match[1]))
+		}
+	}
+
+	base, _ := url.Parse(item.Link)
+	for _, candidate := range candidates {
+		if resolved := absoluteURL(base, candidate); resolved != "" {
+			return resolved
+		}
+	}
+
+	return ""
+}
+
+func absoluteURL(base *url.URL, ref string) string {
+	// resolves ref against the item's link, keeping only http(s) URLs
+
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return ""
+	}
+
+	parsed, err := url.Parse(ref)
 	if err != nil {
-		return nil, err
+		return ""
+	}
+	if base != nil {
+		parsed = base.ResolveReference(parsed)
+	}
+
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return ""
 	}
-	request.Header.Set("User-Agent", "gator")
 
-	client := &http.Client{}
-	response, err := client.Do(request)
+	return parsed.String()
+}
+
+func (item RSSItem) Published() (time.Time, bool) {
+	// pubDate for RSS 2.0, dc:date (W3CDTF) for RSS 1.0 and some RSS 2.0 feeds
+
+	for _, value := range []string{item.PubDate, item.Date} {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		for _, layout := range dateLayouts {
+			if parsed, err := time.Parse(layout, value); err == nil {
+				return parsed, true
+			}
+		}
+	}
+
+	return time.Time{}, false
+}
+
+func uniqueNames(names []string) []string {
+	// trims names and drops empty ones and case-insensitive duplicates, keeping the first spelling
+
+	var unique []string
+	seen := map[string]bool{}
+	for _, name := range names {
+		name = strings.Join(strings.Fields(name), " ")
+		if name == "" || seen[strings.ToLower(name)] {
+			continue
+		}
+		seen[strings.ToLower(name)] = true
+		unique = append(unique, name)
+	}
+
+	return unique
+}
+
+// the formats FetchFeed understands, in order of preference
+const acceptFeeds = "application/rss+xml, application/rdf+xml;q=0.9, application/xml;q=0.8, text/xml;q=0.8, */*;q=0.5"
+
+func FetchFeed(ctx context.Context, client *fetch.Client, feedURL string, header http.Header) (*RSSFeed, error) {
+	// header carries the feed's credentials, if it has any
+	response, err := client.GetWithHeader(ctx, feedURL, acceptFeeds, header)
 	if err != nil {
 		return nil, err
 	}
-	defer response.Body.Close()
 
+	if response.StatusCode == http.StatusGone {
+		return nil, ErrGone
+	}
+	if response.RetryAfter > 0 {
+		return nil, &fetch.RetryAfterError{Host: response.URL.Host, After: response.RetryAfter}
+	}
 	if response.StatusCode != http.StatusOK {
 		return nil, fmt.Errorf("HTTP Error: %d", response.StatusCode)
 	}
 
-	body, err := io.ReadAll(response.Body)
+	// encoding/xml only reads UTF-8, everything else is transcoded first
+	body, err := toUTF8(response.Body, response.Header.Get("Content-Type"))
 	if err != nil {
 		return nil, err
 	}
 
-	var feed RSSFeed
-	if err := xml.Unmarshal(body, &feed); err != nil {
+	feed, err := parse(body)
+	if err != nil {
 		return nil, err
 	}
 
+	if response.MovedTo != nil {
+		feed.MovedTo = response.MovedTo.String()
+	}
+	feed.MaxAge, _ = response.MaxAge()
+
 	// Decode HTML entities in channel title and description
-	feed.Channel.Title = html.UnescapeString(feed.Channel.Title)
-	feed.Channel.Description = html.UnescapeString(feed.Channel.Description)
+	feed.Channel.Title = decodeText(feed.Channel.Title)
+	feed.Channel.Description = decodeText(feed.Channel.Description)
 
 	// Decode INDIVIDUAL HTML entities in titles and descriptions
 	for i := range feed.Channel.Item {
-		feed.Channel.Item[i].Title = html.UnescapeString(feed.Channel.Item[i].Title)
-		feed.Channel.Item[i].Description = html.UnescapeString(feed.Channel.Item[i].Description)
+		feed.Channel.Item[i].Title = decodeText(feed.Channel.Item[i].Title)
+		feed.Channel.Item[i].Description = decodeText(feed.Channel.Item[i].Description)
+		feed.Channel.Item[i].Content = repairMojibake(feed.Channel.Item[i].Content)
+		feed.Channel.Item[i].Author = decodeText(feed.Channel.Item[i].Author)
+		for j, creator := range feed.Channel.Item[i].Creators {
+			feed.Channel.Item[i].Creators[j] = decodeText(creator)
+		}
+		for j, category := range feed.Channel.Item[i].Categories {
+			feed.Channel.Item[i].Categories[j] = decodeText(category)
+		}
+	}
+
+	return feed, nil
+}
+
+func decodeText(text string) string {
+	return repairMojibake(html.UnescapeString(text))
+}
+
+func parse(body []byte) (*RSSFeed, error) {
+	// feeds that aren't well-formed are repaired and parsed again leniently,
+	// failing with the original error only if that doesn't work either
+
+	feed, err := decodeFeed(body, strictDecoder)
+	if err == nil {
+		return feed, nil
+	}
+
+	repaired, warnings := repairXML(body)
+	feed, lenientErr := decodeFeed(repaired, lenientDecoder)
+	if lenientErr != nil {
+		// a web page is seldom well-formed XML, but it is still not a feed
+		if errors.Is(lenientErr, ErrUnsupportedFormat) {
+			return nil, lenientErr
+		}
+		if htmlDocument.Match(body) {
+			return nil, fmt.Errorf("%w: got an HTML page instead of a feed", ErrUnsupportedFormat)
+		}
+		return nil, err
 	}
 
-	return &feed, nil
-}
\ No newline at end of file
+	feed.Warnings = append([]string{"strict parsing failed: " + err.Error()}, warnings...)
+	return feed, nil
+}
+
+func decodeFeed(body []byte, newDecoder func([]byte) *xml.Decoder) (*RSSFeed, error) {
+	// picks the format by the document's root element
+
+	var root struct {
+		XMLName xml.Name
+	}
+	if err := newDecoder(body).Decode(&root); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case root.XMLName.Local == "rss":
+		var feed RSSFeed
+		if err := newDecoder(body).Decode(&feed); err != nil {
+			return nil, err
+		}
+		if feed.Channel.Title == "" && feed.Channel.Link == "" && len(feed.Channel.Item) == 0 {
+			return nil, fmt.Errorf("%w: <rss> document without a channel", ErrUnsupportedFormat)
+		}
+		return &feed, nil
+
+	case root.XMLName.Space == rdfNamespace && root.XMLName.Local == "RDF":
+		var rdf rdfFeed
+		if err := newDecoder(body).Decode(&rdf); err != nil {
+			return nil, err
+		}
+		if rdf.Channel.Title == "" && rdf.Channel.Link == "" && len(rdf.Item) == 0 {
+			return nil, fmt.Errorf("%w: RDF document without an RSS 1.0 (%s) channel", ErrUnsupportedFormat, rss10Namespace)
+		}
+
+		var feed RSSFeed
+		feed.Channel.Title = rdf.Channel.Title
+		feed.Channel.Link = rdf.Channel.Link
+		feed.Channel.Description = rdf.Channel.Description
+		feed.Channel.UpdatePeriod = rdf.Channel.UpdatePeriod
+		feed.Channel.UpdateFrequency = rdf.Channel.UpdateFrequency
+		feed.Channel.Item = rdf.Item
+		return &feed, nil
+	}
+
+	name := root.XMLName.Local
+	if root.XMLName.Space != "" {
+		name += " (" + root.XMLName.Space + ")"
+	}
+	return nil, fmt.Errorf("%w: root element <%s>, expected RSS 2.0 or RSS 1.0", ErrUnsupportedFormat, name)
+}
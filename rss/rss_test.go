@@ -0,0 +1,107 @@
+package rss
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func parseFile(t *testing.T, name string) (*RSSFeed, error) {
+	t.Helper()
+
+	body, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", name, err)
+	}
+	return parse(body)
+}
+
+func TestParseRDF(t *testing.T) {
+	// RSS 1.0 keeps its items next to the channel, not inside it
+	feed, err := parseFile(t, "rdf.xml")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+
+	if feed.Channel.Title != "Example Journal" || feed.Channel.Link != "https://journal.example.org/" {
+		t.Errorf("channel = %q %q", feed.Channel.Title, feed.Channel.Link)
+	}
+	if interval, ok := feed.UpdateInterval(); !ok || interval != 12*time.Hour {
+		t.Errorf("UpdateInterval = %v, %v, want 12h", interval, ok)
+	}
+	if len(feed.Warnings) != 0 {
+		t.Errorf("warnings for a valid feed: %q", feed.Warnings)
+	}
+
+	if len(feed.Channel.Item) != 2 {
+		t.Fatalf("got %d items, want 2", len(feed.Channel.Item))
+	}
+
+	tests := []struct {
+		title     string
+		link      string
+		published time.Time
+	}{
+		{"Second entry", "https://journal.example.org/2026/10/second", time.Date(2026, 10, 12, 7, 30, 0, 0, time.UTC)},
+		{"First entry", "https://journal.example.org/2026/10/first", time.Date(2026, 10, 5, 0, 0, 0, 0, time.UTC)},
+	}
+	for i, tt := range tests {
+		item := feed.Channel.Item[i]
+		if item.Title != tt.title || item.Link != tt.link {
+			t.Errorf("item %d = %q %q, want %q %q", i, item.Title, item.Link, tt.title, tt.link)
+		}
+		// dc:date is all RSS 1.0 has for a publish date
+		published, ok := item.Published()
+		if !ok || !published.Equal(tt.published) {
+			t.Errorf("%s: Published = %v, %v, want %v", tt.title, published, ok, tt.published)
+		}
+	}
+
+	item := feed.Channel.Item[0]
+	if len(item.Creators) != 1 || item.Creators[0] != "Ada Example" || len(item.Subjects) != 1 {
+		t.Errorf("dc:creator = %q, dc:subject = %q", item.Creators, item.Subjects)
+	}
+}
+
+func TestParseUnsupported(t *testing.T) {
+	tests := []struct {
+		file string
+		want string
+	}{
+		{"atom.xml", "feed (http://www.w3.org/2005/Atom)"},
+		{"empty-rss.xml", "without a channel"},
+		// not well-formed XML, which shouldn't read as a broken feed
+		{"html-page.html", "html"},
+	}
+
+	for _, tt := range tests {
+		_, err := parseFile(t, tt.file)
+		if !errors.Is(err, ErrUnsupportedFormat) {
+			t.Errorf("%s: error = %v, want ErrUnsupportedFormat", tt.file, err)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: error = %q, want it to mention %q", tt.file, err, tt.want)
+		}
+	}
+
+	// a broken feed still reports what is wrong with it
+	_, err := parse([]byte(`<?xml version="1.0"?><rss version="2.0"><channel><title>Broken`))
+	if err == nil || errors.Is(err, ErrUnsupportedFormat) {
+		t.Errorf("truncated feed: error = %v, want the syntax error", err)
+	}
+}
+
+func TestParseHTMLWithoutDoctype(t *testing.T) {
+	for _, page := range []string{
+		"<html><body><p>Hello<br>world</p></body></html>",
+		"<!-- served by nginx -->\n<!doctype HTML><html><head><meta charset=utf-8></head></html>",
+	} {
+		if _, err := parse([]byte(page)); !errors.Is(err, ErrUnsupportedFormat) {
+			t.Errorf("parse(%q) error = %v, want ErrUnsupportedFormat", page, err)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package rss
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// how many of the newest posts the posting interval is averaged over
+const postingSample = 10
+
+var updatePeriods = map[string]time.Duration{
+	"hourly":  time.Hour,
+	"daily":   24 * time.Hour,
+	"weekly":  7 * 24 * time.Hour,
+	"monthly": 30 * 24 * time.Hour,
+	"yearly":  365 * 24 * time.Hour,
+}
+
+func (f *RSSFeed) TTL() (time.Duration, bool) {
+	// <ttl> is the number of minutes the channel may be cached
+	minutes, err := strconv.Atoi(strings.TrimSpace(f.Channel.TTL))
+	if err != nil || minutes <= 0 {
+		return 0, false
+	}
+	return time.Duration(minutes) * time.Minute, true
+}
+
+func (f *RSSFeed) UpdateInterval() (time.Duration, bool) {
+	// sy:updatePeriod and sy:updateFrequency, e.g. "daily" and 2 for twice a day
+
+	period, ok := updatePeriods[strings.ToLower(strings.TrimSpace(f.Channel.UpdatePeriod))]
+	if !ok {
+		return 0, false
+	}
+
+	frequency := 1
+	if n, err := strconv.Atoi(strings.TrimSpace(f.Channel.UpdateFrequency)); err == nil && n > 0 {
+		frequency = n
+	}
+
+	return period / time.Duration(frequency), true
+}
+
+func (f *RSSFeed) PostingInterval() (time.Duration, bool) {
+	// the average time between the newest posts, ignoring posts without a date
+
+	var dates []time.Time
+	for _, item := range f.Channel.Item {
+		if published, ok := item.Published(); ok {
+			dates = append(dates, published)
+		}
+	}
+	if len(dates) < 2 {
+		return 0, false
+	}
+
+	slices.SortFunc(dates, func(a, b time.Time) int { return b.Compare(a) })
+	dates = dates[:min(len(dates), postingSample)]
+
+	interval := dates[0].Sub(dates[len(dates)-1]) / time.Duration(len(dates)-1)
+	if interval <= 0 {
+		return 0, false
+	}
+	return interval, true
+}
+
+func (f *RSSFeed) Skips(t time.Time) bool {
+	// whether the channel asks not to be fetched at t, by <skipHours> (GMT) or <skipDays>
+
+	t = t.UTC()
+
+	for _, hour := range f.Channel.SkipHours {
+		// both 0 and 24 are used for midnight
+		if h, err := strconv.Atoi(strings.TrimSpace(hour)); err == nil && h%24 == t.Hour() {
+			return true
+		}
+	}
+
+	for _, day := range f.Channel.SkipDays {
+		if strings.EqualFold(strings.TrimSpace(day), t.Weekday().String()) {
+			return true
+		}
+	}
+
+	return false
+}
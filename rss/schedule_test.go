@@ -0,0 +1,142 @@
+package rss
+
+import (
+	"testing"
+	"time"
+)
+
+func datedItems(newest time.Time, gaps ...time.Duration) []RSSItem {
+	// one item published at newest, then one more per gap, each that much earlier
+	items := []RSSItem{{PubDate: newest.Format(time.RFC1123Z)}}
+	published := newest
+	for _, gap := range gaps {
+		published = published.Add(-gap)
+		items = append(items, RSSItem{PubDate: published.Format(time.RFC1123Z)})
+	}
+	return items
+}
+
+func TestPostingInterval(t *testing.T) {
+	newest := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	every := func(gap time.Duration, n int) []time.Duration {
+		gaps := make([]time.Duration, n)
+		for i := range gaps {
+			gaps[i] = gap
+		}
+		return gaps
+	}
+
+	tests := []struct {
+		name   string
+		items  []RSSItem
+		want   time.Duration
+		wantOK bool
+	}{
+		{name: "no items"},
+		{name: "a single post", items: datedItems(newest)},
+		{name: "hourly", items: datedItems(newest, every(time.Hour, 5)...), want: time.Hour, wantOK: true},
+		{name: "uneven gaps are averaged", items: datedItems(newest, time.Hour, 3*time.Hour), want: 2 * time.Hour, wantOK: true},
+		{
+			// an archive going back years only counts its newest posts
+			name:   "only the newest posts",
+			items:  datedItems(newest, append(every(time.Hour, postingSample-1), every(365*24*time.Hour, 5)...)...),
+			want:   time.Hour,
+			wantOK: true,
+		},
+		{
+			name:   "undated posts are ignored",
+			items:  append(datedItems(newest, 6*time.Hour), RSSItem{Title: "undated"}, RSSItem{PubDate: "yesterday"}),
+			want:   6 * time.Hour,
+			wantOK: true,
+		},
+		{
+			name:   "order doesn't matter",
+			items:  []RSSItem{{PubDate: "Mon, 12 Oct 2026 12:00:00 +0000"}, {Date: "2026-10-19T12:00:00Z"}, {PubDate: "Thu, 15 Oct 2026 00:00:00 +0000"}},
+			want:   84 * time.Hour,
+			wantOK: true,
+		},
+		{name: "all at once", items: datedItems(newest, 0, 0)},
+	}
+
+	for _, tt := range tests {
+		var feed RSSFeed
+		feed.Channel.Item = tt.items
+
+		got, ok := feed.PostingInterval()
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("%s: PostingInterval = %v, %v, want %v, %v", tt.name, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestPublisherHints(t *testing.T) {
+	tests := []struct {
+		ttl, period, frequency string
+		wantTTL                time.Duration
+		wantUpdate             time.Duration
+	}{
+		{ttl: "60", wantTTL: time.Hour},
+		{ttl: " 90 ", wantTTL: 90 * time.Minute},
+		{ttl: "0"},
+		{ttl: "-5"},
+		{ttl: "soon"},
+		{period: "daily", wantUpdate: 24 * time.Hour},
+		{period: "Hourly", frequency: "2", wantUpdate: 30 * time.Minute},
+		{period: "weekly", frequency: "7", wantUpdate: 24 * time.Hour},
+		{period: "daily", frequency: "0", wantUpdate: 24 * time.Hour},
+		{period: "fortnightly"},
+		{frequency: "2"},
+	}
+
+	for _, tt := range tests {
+		var feed RSSFeed
+		feed.Channel.TTL = tt.ttl
+		feed.Channel.UpdatePeriod = tt.period
+		feed.Channel.UpdateFrequency = tt.frequency
+
+		if got, ok := feed.TTL(); got != tt.wantTTL || ok != (tt.wantTTL > 0) {
+			t.Errorf("ttl %q: TTL = %v, %v, want %v", tt.ttl, got, ok, tt.wantTTL)
+		}
+		if got, ok := feed.UpdateInterval(); got != tt.wantUpdate || ok != (tt.wantUpdate > 0) {
+			t.Errorf("sy %q/%q: UpdateInterval = %v, %v, want %v", tt.period, tt.frequency, got, ok, tt.wantUpdate)
+		}
+	}
+}
+
+func TestSkips(t *testing.T) {
+	var feed RSSFeed
+	feed.Channel.SkipHours = []string{"1", " 2 ", "24", "nope"}
+	feed.Channel.SkipDays = []string{"saturday", "Sunday"}
+
+	// Monday 19 October 2026
+	monday := time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+	pacific := time.FixedZone("PDT", -7*60*60)
+
+	tests := []struct {
+		name string
+		t    time.Time
+		want bool
+	}{
+		{"24 is midnight", monday, true},
+		{"skipped hour", monday.Add(time.Hour + 59*time.Minute), true},
+		{"skipped hour with spaces", monday.Add(2 * time.Hour), true},
+		{"allowed hour", monday.Add(3 * time.Hour), false},
+		{"skipped day", monday.Add(5*24*time.Hour + 12*time.Hour), true},
+		{"skipped day, lower case", monday.Add(6*24*time.Hour + 12*time.Hour), true},
+		{"allowed day", monday.Add(4*24*time.Hour + 12*time.Hour), false},
+		// skipHours are in GMT, whatever zone the time is in
+		{"6pm in California is 1am GMT", time.Date(2026, 10, 19, 18, 0, 0, 0, pacific), true},
+		{"1am in California is 8am GMT", time.Date(2026, 10, 20, 1, 0, 0, 0, pacific), false},
+		{"Friday evening in California is Saturday GMT", time.Date(2026, 10, 23, 20, 0, 0, 0, pacific), true},
+	}
+
+	for _, tt := range tests {
+		if got := feed.Skips(tt.t); got != tt.want {
+			t.Errorf("%s: Skips(%v) = %v, want %v", tt.name, tt.t, got, tt.want)
+		}
+	}
+
+	if (&RSSFeed{}).Skips(monday) {
+		t.Error("a feed without skipHours or skipDays skips midnight")
+	}
+}
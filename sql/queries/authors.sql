@@ -0,0 +1,11 @@
+-- name: UpsertAuthor :one
+INSERT INTO authors (id, created_at, name)
+VALUES ($1, $2, $3)
+ON CONFLICT (lower(name)) DO UPDATE
+SET name = authors.name
+RETURNING *;
+
+-- name: AddPostAuthor :exec
+INSERT INTO post_authors (post_id, author_id)
+VALUES ($1, $2)
+ON CONFLICT DO NOTHING;
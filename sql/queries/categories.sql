@@ -0,0 +1,11 @@
+-- name: UpsertCategory :one
+INSERT INTO categories (id, created_at, name)
+VALUES ($1, $2, $3)
+ON CONFLICT (lower(name)) DO UPDATE
+SET name = categories.name
+RETURNING *;
+
+-- name: AddPostCategory :exec
+INSERT INTO post_categories (post_id, category_id)
+VALUES ($1, $2)
+ON CONFLICT DO NOTHING;
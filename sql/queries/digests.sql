@@ -0,0 +1,76 @@
+-- name: UpsertDigestSchedule :one
+INSERT INTO digest_schedules (
+    user_id,
+    created_at,
+    updated_at,
+    email,
+    period,
+    group_by,
+    send_at,
+    time_zone,
+    weekday
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9
+)
+ON CONFLICT (user_id) DO UPDATE
+SET email = EXCLUDED.email,
+    period = EXCLUDED.period,
+    group_by = EXCLUDED.group_by,
+    send_at = EXCLUDED.send_at,
+    time_zone = EXCLUDED.time_zone,
+    weekday = EXCLUDED.weekday,
+    updated_at = EXCLUDED.updated_at,
+    failures = 0,
+    last_error = NULL,
+    retry_at = NULL
+RETURNING *;
+
+-- name: GetDigestSchedule :one
+SELECT * FROM digest_schedules
+WHERE user_id = $1;
+
+-- name: DeleteDigestSchedule :execrows
+DELETE FROM digest_schedules WHERE user_id = $1;
+
+-- name: GetDigestSchedules :many
+SELECT digest_schedules.*, users.name AS user_name
+FROM digest_schedules
+INNER JOIN users ON digest_schedules.user_id = users.id
+WHERE users.disabled_at IS NULL;
+
+-- name: MarkDigestSent :exec
+UPDATE digest_schedules
+SET last_sent_at = $2,
+    failures = 0,
+    last_error = NULL,
+    retry_at = NULL,
+    updated_at = CURRENT_TIMESTAMP
+WHERE user_id = $1;
+
+-- name: RecordDigestFailure :exec
+UPDATE digest_schedules
+SET failures = failures + 1,
+    last_error = $2,
+    retry_at = $3,
+    updated_at = CURRENT_TIMESTAMP
+WHERE user_id = $1;
+
+-- name: GetDigestPosts :many
+-- unread posts from followed feeds, once per folder the feed is in
+SELECT
+    posts.*,
+    COALESCE(feed_follows.display_name, feeds.name) AS feed_name,
+    folders.name AS folder_name
+FROM posts
+INNER JOIN feeds ON posts.feed_id = feeds.id
+INNER JOIN feed_follows ON feed_follows.feed_id = feeds.id
+LEFT JOIN feed_follow_folders ON feed_follow_folders.feed_follow_id = feed_follows.id
+LEFT JOIN folders ON folders.id = feed_follow_folders.folder_id
+LEFT JOIN user_posts ON user_posts.post_id = posts.id
+    AND user_posts.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = sqlc.arg(user_id)
+  AND user_posts.read_at IS NULL
+  AND user_posts.hidden_at IS NULL
+  AND COALESCE(posts.published_at, posts.created_at) >= sqlc.arg(since)::timestamptz
+ORDER BY feed_follows.priority DESC, feed_name ASC, posts.published_at DESC NULLS LAST
+LIMIT sqlc.arg(max_posts);
@@ -0,0 +1,59 @@
+-- name: CreateEnclosure :exec
+INSERT INTO enclosures (id, created_at, post_id, url, mime_type, length)
+VALUES ($1, $2, $3, $4, $5, $6)
+ON CONFLICT (post_id, url) DO NOTHING;
+
+-- name: GetEnclosuresForPost :many
+SELECT * FROM enclosures
+WHERE post_id = $1
+ORDER BY created_at ASC;
+
+-- name: GetLatestEnclosuresForFeed :many
+-- the enclosures of the feed's newest episodes, with the user's download of each if any
+SELECT
+    enclosures.*,
+    posts.title AS post_title,
+    posts.published_at,
+    downloads.path AS download_path
+FROM enclosures
+INNER JOIN posts ON posts.id = enclosures.post_id
+LEFT JOIN downloads ON downloads.enclosure_id = enclosures.id
+    AND downloads.user_id = $1
+WHERE posts.feed_id = $2
+ORDER BY posts.published_at DESC NULLS LAST, enclosures.created_at ASC
+LIMIT $3;
+
+-- name: CreateDownload :exec
+INSERT INTO downloads (user_id, enclosure_id, created_at, path, size)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (user_id, enclosure_id) DO UPDATE
+SET created_at = EXCLUDED.created_at,
+    path = EXCLUDED.path,
+    size = EXCLUDED.size;
+
+-- name: GetDownloadsForUser :many
+SELECT
+    downloads.*,
+    posts.title AS post_title,
+    COALESCE(feed_follows.display_name, feeds.name) AS feed_name
+FROM downloads
+INNER JOIN enclosures ON enclosures.id = downloads.enclosure_id
+INNER JOIN posts ON posts.id = enclosures.post_id
+INNER JOIN feeds ON feeds.id = posts.feed_id
+LEFT JOIN feed_follows ON feed_follows.feed_id = feeds.id
+    AND feed_follows.user_id = downloads.user_id
+WHERE downloads.user_id = $1
+ORDER BY feed_name ASC, posts.published_at DESC NULLS LAST;
+
+-- name: GetDownloadsBeyondKeep :many
+-- the user's downloads of a feed past the newest keep episodes
+SELECT downloads.* FROM downloads
+INNER JOIN enclosures ON enclosures.id = downloads.enclosure_id
+INNER JOIN posts ON posts.id = enclosures.post_id
+WHERE downloads.user_id = $1 AND posts.feed_id = $2
+ORDER BY posts.published_at DESC NULLS LAST, enclosures.created_at ASC
+OFFSET $3;
+
+-- name: DeleteDownload :exec
+DELETE FROM downloads
+WHERE user_id = $1 AND enclosure_id = $2;
@@ -0,0 +1,12 @@
+-- name: SetFeedCredentials :exec
+INSERT INTO feed_credentials (feed_id, created_at, updated_at, data)
+VALUES ($1, $2, $2, $3)
+ON CONFLICT (feed_id) DO UPDATE
+SET data = EXCLUDED.data,
+    updated_at = EXCLUDED.updated_at;
+
+-- name: GetFeedCredentials :one
+SELECT data FROM feed_credentials WHERE feed_id = $1;
+
+-- name: DeleteFeedCredentials :execrows
+DELETE FROM feed_credentials WHERE feed_id = $1;
@@ -37,17 +37,89 @@ INNER JOIN feeds ON feed_follows.feed_id = feeds.id
 INNER JOIN users ON feed_follows.user_id = users.id
 WHERE feed_follows.user_id = $1;
 
--- name: UnfollowFeed :exec
+-- name: UnfollowFeed :execrows
 DELETE FROM feed_follows
 WHERE user_id = $1 AND feed_id = $2;
 
 -- name: MarkFeedFetched :exec
+-- next_fetch_at is when to retry if the fetch fails, a successful one replaces it
 UPDATE feeds
 SET last_fetched_at = CURRENT_TIMESTAMP,
+    next_fetch_at = $2,
     updated_at = CURRENT_TIMESTAMP
 WHERE id = $1;
 
 -- name: GetNextFeedToFetch :one
+-- the feed that has been due the longest
 SELECT * FROM feeds
-ORDER BY last_fetched_at ASC NULLS FIRST
-LIMIT 1;
\ No newline at end of file
+WHERE NOT paused
+  AND dead_at IS NULL
+  AND (next_fetch_at IS NULL OR next_fetch_at <= CURRENT_TIMESTAMP)
+ORDER BY next_fetch_at ASC NULLS FIRST, last_fetched_at ASC NULLS FIRST
+LIMIT 1;
+
+-- name: CountFeedFollows :one
+SELECT COUNT(*) FROM feed_follows;
+
+-- name: CountFeedFollowsForUser :one
+SELECT COUNT(*) FROM feed_follows WHERE user_id = $1;
+
+-- name: FollowFeedIfNotFollowing :execrows
+INSERT INTO feed_follows (id, created_at, updated_at, user_id, feed_id)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (user_id, feed_id) DO NOTHING;
+
+-- name: GetFeedFollow :one
+SELECT * FROM feed_follows
+WHERE user_id = $1 AND feed_id = $2;
+
+-- name: GetFollowedFeedsByFolder :many
+SELECT
+    feed_follows.feed_id,
+    COALESCE(feed_follows.display_name, feeds.name) AS feed_name,
+    feed_follows.priority,
+    folders.name AS folder_name,
+    (
+        SELECT COUNT(*) FROM posts
+        LEFT JOIN user_posts ON user_posts.post_id = posts.id
+            AND user_posts.user_id = feed_follows.user_id
+        WHERE posts.feed_id = feed_follows.feed_id
+          AND user_posts.read_at IS NULL
+          AND user_posts.hidden_at IS NULL
+    ) AS unread_count
+FROM feed_follows
+INNER JOIN feeds ON feed_follows.feed_id = feeds.id
+LEFT JOIN feed_follow_folders ON feed_follow_folders.feed_follow_id = feed_follows.id
+LEFT JOIN folders ON folders.id = feed_follow_folders.folder_id
+WHERE feed_follows.user_id = $1
+ORDER BY folders.name ASC NULLS LAST, feed_follows.priority DESC, feed_name ASC;
+
+-- name: SetFeedFollowDisplayName :execrows
+UPDATE feed_follows
+SET display_name = $3,
+    updated_at = CURRENT_TIMESTAMP
+WHERE user_id = $1 AND feed_id = $2;
+
+-- name: SetFeedFollowPriority :execrows
+UPDATE feed_follows
+SET priority = $3,
+    updated_at = CURRENT_TIMESTAMP
+WHERE user_id = $1 AND feed_id = $2;
+
+-- name: SetFeedFollowKeepDownloads :execrows
+UPDATE feed_follows
+SET keep_downloads = $3,
+    updated_at = CURRENT_TIMESTAMP
+WHERE user_id = $1 AND feed_id = $2;
+
+-- name: MoveFeedFollows :execrows
+-- users already following the target feed keep that follow instead
+UPDATE feed_follows
+SET feed_id = sqlc.arg(to_feed_id),
+    updated_at = CURRENT_TIMESTAMP
+WHERE feed_id = sqlc.arg(from_feed_id)
+  AND NOT EXISTS (
+      SELECT 1 FROM feed_follows AS existing
+      WHERE existing.user_id = feed_follows.user_id
+        AND existing.feed_id = sqlc.arg(to_feed_id)
+  );
@@ -21,4 +21,137 @@ SELECT
     feeds.*,
     users.name AS user_name
 FROM feeds
-LEFT JOIN users ON feeds.user_id = users.id;
\ No newline at end of file
+LEFT JOIN users ON feeds.user_id = users.id;
+
+-- name: CountFeeds :one
+SELECT COUNT(*) FROM feeds;
+
+-- name: CountFeedsForUser :one
+SELECT COUNT(*) FROM feeds WHERE user_id = $1;
+
+-- name: CountFetchedFeeds :one
+SELECT COUNT(*) FROM feeds WHERE last_fetched_at IS NOT NULL;
+
+-- name: ResetFeedFetchState :execrows
+UPDATE feeds
+SET last_fetched_at = NULL,
+    next_fetch_at = NULL,
+    updated_at = CURRENT_TIMESTAMP
+WHERE last_fetched_at IS NOT NULL;
+
+-- name: DeleteAllFeeds :execrows
+DELETE FROM feeds;
+
+-- name: AdoptOrphanedFeeds :execrows
+UPDATE feeds
+SET user_id = (
+        SELECT feed_follows.user_id FROM feed_follows
+        WHERE feed_follows.feed_id = feeds.id
+        ORDER BY feed_follows.created_at ASC
+        LIMIT 1
+    ),
+    updated_at = CURRENT_TIMESTAMP
+WHERE feeds.user_id IS NULL
+  AND EXISTS (SELECT 1 FROM feed_follows WHERE feed_follows.feed_id = feeds.id);
+
+-- name: MarkUnfollowedFeeds :execrows
+UPDATE feeds
+SET unfollowed_at = CURRENT_TIMESTAMP
+WHERE unfollowed_at IS NULL
+  AND NOT EXISTS (SELECT 1 FROM feed_follows WHERE feed_follows.feed_id = feeds.id);
+
+-- name: ClearRefollowedFeeds :execrows
+UPDATE feeds
+SET unfollowed_at = NULL
+WHERE unfollowed_at IS NOT NULL
+  AND EXISTS (SELECT 1 FROM feed_follows WHERE feed_follows.feed_id = feeds.id);
+
+-- name: CountCollectableFeeds :one
+SELECT COUNT(*) FROM feeds
+WHERE NOT EXISTS (SELECT 1 FROM feed_follows WHERE feed_follows.feed_id = feeds.id)
+  AND unfollowed_at < sqlc.arg(cutoff)::timestamptz;
+
+-- name: DeleteCollectableFeeds :execrows
+DELETE FROM feeds
+WHERE NOT EXISTS (SELECT 1 FROM feed_follows WHERE feed_follows.feed_id = feeds.id)
+  AND unfollowed_at < sqlc.arg(cutoff)::timestamptz;
+
+-- name: RenameFeed :one
+UPDATE feeds
+SET name = $2,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = $1
+RETURNING *;
+
+-- name: SetFeedURL :one
+-- a new URL gets a dead feed fetched again, right away
+UPDATE feeds
+SET url = $2,
+    dead_at = NULL,
+    next_fetch_at = NULL,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = $1
+RETURNING *;
+
+-- name: SetFeedPaused :one
+UPDATE feeds
+SET paused = $2,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = $1
+RETURNING *;
+
+-- name: SetFeedFetchFullContent :one
+UPDATE feeds
+SET fetch_full_content = $2,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = $1
+RETURNING *;
+
+-- name: SetFeedParseWarnings :exec
+UPDATE feeds
+SET parse_warnings = $2
+WHERE id = $1;
+
+-- name: SetFeedNextFetchAt :exec
+UPDATE feeds
+SET next_fetch_at = $2
+WHERE id = $1;
+
+-- name: MarkFeedDead :exec
+UPDATE feeds
+SET dead_at = CURRENT_TIMESTAMP,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = $1;
+
+-- name: ReviveFeed :one
+UPDATE feeds
+SET dead_at = NULL,
+    next_fetch_at = NULL,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = $1
+RETURNING *;
+
+-- name: DeleteFeed :execrows
+DELETE FROM feeds WHERE id = $1;
+
+-- name: GetFeedsByName :many
+SELECT * FROM feeds
+WHERE LOWER(name) = LOWER(sqlc.arg(name)::text)
+ORDER BY created_at ASC;
+
+-- name: GetFeedsByIDPrefix :many
+-- the prefix has LIKE's wildcards escaped
+SELECT * FROM feeds
+WHERE id LIKE sqlc.arg(prefix)::text || '%'
+ORDER BY created_at ASC;
+
+-- name: SearchFeeds :many
+-- pattern is an ILIKE pattern, see search.Pattern
+SELECT * FROM feeds
+WHERE name ILIKE sqlc.arg(pattern)::text
+   OR url ILIKE sqlc.arg(pattern)::text
+ORDER BY name ASC;
+
+-- name: ListFeeds :many
+SELECT * FROM feeds
+ORDER BY name ASC;
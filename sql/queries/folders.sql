@@ -0,0 +1,43 @@
+-- name: CreateFolder :one
+INSERT INTO folders (
+    id,
+    created_at,
+    updated_at,
+    user_id,
+    name
+) VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5
+)
+RETURNING *;
+
+-- name: GetFolderByName :one
+SELECT * FROM folders
+WHERE user_id = $1 AND name = $2;
+
+-- name: GetFoldersForUser :many
+SELECT * FROM folders
+WHERE user_id = $1
+ORDER BY name ASC;
+
+-- name: RenameFolder :one
+UPDATE folders
+SET name = $2,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = $1
+RETURNING *;
+
+-- name: DeleteFolder :execrows
+DELETE FROM folders WHERE id = $1;
+
+-- name: AssignFeedFollowToFolder :execrows
+INSERT INTO feed_follow_folders (feed_follow_id, folder_id, created_at)
+VALUES ($1, $2, $3)
+ON CONFLICT (feed_follow_id, folder_id) DO NOTHING;
+
+-- name: UnassignFeedFollowFromFolder :execrows
+DELETE FROM feed_follow_folders
+WHERE feed_follow_id = $1 AND folder_id = $2;
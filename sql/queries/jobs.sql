@@ -0,0 +1,73 @@
+-- name: UpsertJob :exec
+INSERT INTO jobs (name, created_at, updated_at, schedule, next_run_at)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (name) DO UPDATE
+SET schedule = EXCLUDED.schedule,
+    next_run_at = EXCLUDED.next_run_at,
+    updated_at = EXCLUDED.updated_at;
+
+-- name: GetJob :one
+SELECT * FROM jobs
+WHERE name = $1;
+
+-- name: SetJobNextRun :exec
+UPDATE jobs
+SET next_run_at = $2,
+    updated_at = CURRENT_TIMESTAMP
+WHERE name = $1;
+
+-- name: GetJobs :many
+-- every job with its latest run and its latest failure
+SELECT
+    jobs.*,
+    last_run.started_at AS last_run_at,
+    last_run.status AS last_status,
+    last_run.summary AS last_summary,
+    last_failure.started_at AS last_error_at,
+    last_failure.error AS last_error
+FROM jobs
+LEFT JOIN (
+    SELECT DISTINCT ON (job_name) job_name, started_at, status, summary
+    FROM job_runs
+    ORDER BY job_name, started_at DESC
+) AS last_run ON last_run.job_name = jobs.name
+LEFT JOIN (
+    SELECT DISTINCT ON (job_name) job_name, started_at, error
+    FROM job_runs
+    WHERE status = 'failed'
+    ORDER BY job_name, started_at DESC
+) AS last_failure ON last_failure.job_name = jobs.name
+ORDER BY jobs.name ASC;
+
+-- name: CreateJobRun :exec
+INSERT INTO job_runs (id, job_name, started_at)
+VALUES ($1, $2, $3);
+
+-- name: FinishJobRun :exec
+UPDATE job_runs
+SET finished_at = $2,
+    status = $3,
+    summary = $4,
+    error = $5
+WHERE id = $1;
+
+-- name: GetJobRuns :many
+SELECT * FROM job_runs
+WHERE job_name = $1
+ORDER BY started_at DESC
+LIMIT $2;
+
+-- name: FailUnfinishedJobRuns :execrows
+-- runs left behind by an agg process that stopped mid-job
+UPDATE job_runs
+SET finished_at = $1,
+    status = 'failed',
+    error = 'interrupted'
+WHERE status = 'running';
+
+-- name: DeleteJobRunsBefore :execrows
+DELETE FROM job_runs
+WHERE started_at < $1;
+
+-- name: AnalyzeTables :exec
+ANALYZE;
@@ -7,9 +7,16 @@ INSERT INTO posts (
     url,
     description,
     published_at,
-    feed_id
+    feed_id,
+    content_html,
+    content_text,
+    comments_url,
+    duration_seconds,
+    episode,
+    season,
+    image_url
 ) VALUES (
-    $1, $2, $3, $4, $5, $6, $7, $8
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15
 )
 RETURNING *;
 
@@ -17,9 +24,207 @@ RETURNING *;
 -- name: GetPostsForUser :many
 SELECT 
     posts.*,
-    feeds.name AS feed_name
+    COALESCE(feed_follows.display_name, feeds.name) AS feed_name,
+    user_posts.starred_at,
+    ARRAY(
+        SELECT authors.name FROM authors
+        INNER JOIN post_authors ON post_authors.author_id = authors.id
+        WHERE post_authors.post_id = posts.id
+        ORDER BY authors.name ASC
+    )::text[] AS authors,
+    ARRAY(
+        SELECT categories.name FROM categories
+        INNER JOIN post_categories ON post_categories.category_id = categories.id
+        WHERE post_categories.post_id = posts.id
+        ORDER BY categories.name ASC
+    )::text[] AS categories
 FROM posts
 INNER JOIN feeds ON posts.feed_id = feeds.id
-WHERE feeds.user_id = $1
-ORDER BY posts.published_at DESC NULLS FIRST
-LIMIT $2;
\ No newline at end of file
+INNER JOIN feed_follows ON feed_follows.feed_id = feeds.id
+LEFT JOIN user_posts ON user_posts.post_id = posts.id
+    AND user_posts.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = sqlc.arg(user_id)
+  AND user_posts.hidden_at IS NULL
+  AND (sqlc.narg(feed_id)::text IS NULL OR posts.feed_id = sqlc.narg(feed_id)::text)
+  AND (
+    sqlc.narg(folder_id)::text IS NULL
+    OR EXISTS (
+        SELECT 1 FROM feed_follow_folders
+        WHERE feed_follow_folders.feed_follow_id = feed_follows.id
+          AND feed_follow_folders.folder_id = sqlc.narg(folder_id)::text
+    )
+  )
+  AND (
+    sqlc.narg(published_after)::timestamptz IS NULL
+    OR posts.published_at >= sqlc.narg(published_after)::timestamptz
+  )
+  AND (COALESCE(posts.title, '') || ' ' || COALESCE(posts.description, '')) ILIKE ALL (sqlc.arg(patterns)::text[])
+  AND (
+    sqlc.narg(author)::text IS NULL
+    OR EXISTS (
+        SELECT 1 FROM post_authors
+        INNER JOIN authors ON authors.id = post_authors.author_id
+        WHERE post_authors.post_id = posts.id
+          AND authors.name ILIKE sqlc.narg(author)::text
+    )
+  )
+  AND (
+    sqlc.narg(category)::text IS NULL
+    OR EXISTS (
+        SELECT 1 FROM post_categories
+        INNER JOIN categories ON categories.id = post_categories.category_id
+        WHERE post_categories.post_id = posts.id
+          AND lower(categories.name) = lower(sqlc.narg(category)::text)
+    )
+  )
+  AND (
+    NOT sqlc.arg(media_only)::boolean
+    OR EXISTS (SELECT 1 FROM enclosures WHERE enclosures.post_id = posts.id)
+  )
+  AND (NOT sqlc.arg(unread_only)::boolean OR user_posts.read_at IS NULL)
+ORDER BY feed_follows.priority DESC, posts.published_at DESC NULLS FIRST
+LIMIT sqlc.arg(max_posts);
+
+-- name: CountPostsForUser :one
+-- takes the same filters as GetPostsForUser
+SELECT COUNT(*) FROM posts
+INNER JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+LEFT JOIN user_posts ON user_posts.post_id = posts.id
+    AND user_posts.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = sqlc.arg(user_id)
+  AND user_posts.hidden_at IS NULL
+  AND (sqlc.narg(feed_id)::text IS NULL OR posts.feed_id = sqlc.narg(feed_id)::text)
+  AND (
+    sqlc.narg(folder_id)::text IS NULL
+    OR EXISTS (
+        SELECT 1 FROM feed_follow_folders
+        WHERE feed_follow_folders.feed_follow_id = feed_follows.id
+          AND feed_follow_folders.folder_id = sqlc.narg(folder_id)::text
+    )
+  )
+  AND (
+    sqlc.narg(published_after)::timestamptz IS NULL
+    OR posts.published_at >= sqlc.narg(published_after)::timestamptz
+  )
+  AND (COALESCE(posts.title, '') || ' ' || COALESCE(posts.description, '')) ILIKE ALL (sqlc.arg(patterns)::text[])
+  AND (
+    sqlc.narg(author)::text IS NULL
+    OR EXISTS (
+        SELECT 1 FROM post_authors
+        INNER JOIN authors ON authors.id = post_authors.author_id
+        WHERE post_authors.post_id = posts.id
+          AND authors.name ILIKE sqlc.narg(author)::text
+    )
+  )
+  AND (
+    sqlc.narg(category)::text IS NULL
+    OR EXISTS (
+        SELECT 1 FROM post_categories
+        INNER JOIN categories ON categories.id = post_categories.category_id
+        WHERE post_categories.post_id = posts.id
+          AND lower(categories.name) = lower(sqlc.narg(category)::text)
+    )
+  )
+  AND (NOT sqlc.arg(unread_only)::boolean OR user_posts.read_at IS NULL);
+
+-- name: CountPosts :one
+SELECT COUNT(*) FROM posts;
+
+-- name: DeletePosts :execrows
+DELETE FROM posts;
+
+
+-- name: SetPostContent :exec
+UPDATE posts
+SET content_html = $2,
+    content_text = $3,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = $1;
+
+-- name: MarkPostRead :exec
+INSERT INTO user_posts (user_id, post_id, read_at)
+VALUES ($1, $2, CURRENT_TIMESTAMP)
+ON CONFLICT (user_id, post_id) DO UPDATE
+SET read_at = COALESCE(user_posts.read_at, EXCLUDED.read_at);
+
+-- name: HidePost :exec
+INSERT INTO user_posts (user_id, post_id, hidden_at)
+VALUES ($1, $2, CURRENT_TIMESTAMP)
+ON CONFLICT (user_id, post_id) DO UPDATE
+SET hidden_at = COALESCE(user_posts.hidden_at, EXCLUDED.hidden_at);
+
+-- name: StarPost :exec
+INSERT INTO user_posts (user_id, post_id, starred_at)
+VALUES ($1, $2, CURRENT_TIMESTAMP)
+ON CONFLICT (user_id, post_id) DO UPDATE
+SET starred_at = COALESCE(user_posts.starred_at, EXCLUDED.starred_at);
+
+-- name: TagPost :exec
+INSERT INTO post_tags (user_id, post_id, tag, created_at)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (user_id, post_id, tag) DO NOTHING;
+
+-- name: GetPostTags :many
+SELECT tag FROM post_tags
+WHERE user_id = $1 AND post_id = $2
+ORDER BY tag ASC;
+
+-- name: GetFollowedPosts :many
+SELECT
+    posts.*,
+    ARRAY(
+        SELECT authors.name FROM authors
+        INNER JOIN post_authors ON post_authors.author_id = authors.id
+        WHERE post_authors.post_id = posts.id
+        ORDER BY authors.name ASC
+    )::text[] AS authors,
+    ARRAY(
+        SELECT categories.name FROM categories
+        INNER JOIN post_categories ON post_categories.category_id = categories.id
+        WHERE post_categories.post_id = posts.id
+        ORDER BY categories.name ASC
+    )::text[] AS categories
+FROM posts
+INNER JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+WHERE feed_follows.user_id = $1
+ORDER BY posts.published_at DESC NULLS FIRST;
+
+-- name: GetPostImagesSince :many
+SELECT id, image_url FROM posts
+WHERE image_url IS NOT NULL AND created_at >= $1
+ORDER BY created_at DESC
+LIMIT $2;
+
+-- name: MoveFeedPosts :execrows
+UPDATE posts
+SET feed_id = sqlc.arg(to_feed_id)
+WHERE feed_id = sqlc.arg(from_feed_id);
+
+-- name: DeleteOldPosts :execrows
+-- posts published before the cutoff, except ones someone starred, hasn't read yet,
+-- downloaded an episode of or still has a webhook delivery pending for
+DELETE FROM posts
+WHERE COALESCE(posts.published_at, posts.created_at) < sqlc.arg(before)::timestamptz
+  AND NOT EXISTS (
+      SELECT 1 FROM user_posts
+      WHERE user_posts.post_id = posts.id
+        AND user_posts.starred_at IS NOT NULL
+  )
+  AND NOT EXISTS (
+      SELECT 1 FROM feed_follows
+      LEFT JOIN user_posts ON user_posts.post_id = posts.id
+          AND user_posts.user_id = feed_follows.user_id
+      WHERE feed_follows.feed_id = posts.feed_id
+        AND user_posts.read_at IS NULL
+        AND user_posts.hidden_at IS NULL
+  )
+  AND NOT EXISTS (
+      SELECT 1 FROM enclosures
+      INNER JOIN downloads ON downloads.enclosure_id = enclosures.id
+      WHERE enclosures.post_id = posts.id
+  )
+  AND NOT EXISTS (
+      SELECT 1 FROM webhook_deliveries
+      WHERE webhook_deliveries.post_id = posts.id
+        AND webhook_deliveries.status = 'pending'
+  );
@@ -0,0 +1,47 @@
+-- name: CreateRule :one
+INSERT INTO rules (
+    id,
+    created_at,
+    updated_at,
+    user_id,
+    feed_id,
+    field,
+    match_type,
+    pattern,
+    action,
+    tag
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10
+)
+RETURNING *;
+
+-- name: GetRulesForUser :many
+SELECT
+    rules.*,
+    feeds.name AS feed_name
+FROM rules
+LEFT JOIN feeds ON rules.feed_id = feeds.id
+WHERE rules.user_id = $1
+ORDER BY rules.created_at ASC;
+
+-- name: GetRulesForFeed :many
+-- rules that apply to new posts of a feed: ones scoped to it, plus global rules of its followers
+SELECT rules.* FROM rules
+WHERE rules.feed_id = sqlc.arg(feed_id)::text
+   OR (
+    rules.feed_id IS NULL
+    AND EXISTS (
+        SELECT 1 FROM feed_follows
+        WHERE feed_follows.feed_id = sqlc.arg(feed_id)::text
+          AND feed_follows.user_id = rules.user_id
+    )
+   )
+ORDER BY rules.created_at ASC;
+
+-- name: DeleteRule :execrows
+DELETE FROM rules WHERE id = $1;
+
+-- name: MoveFeedRules :exec
+UPDATE rules
+SET feed_id = sqlc.arg(to_feed_id)
+WHERE feed_id = sqlc.arg(from_feed_id);
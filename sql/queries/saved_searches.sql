@@ -0,0 +1,50 @@
+-- name: CreateSavedSearch :one
+INSERT INTO saved_searches (
+    id,
+    created_at,
+    updated_at,
+    user_id,
+    name,
+    query,
+    feed_id,
+    folder_id,
+    max_age_hours,
+    alert,
+    author,
+    category
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12
+)
+RETURNING *;
+
+-- name: GetSavedSearchByName :one
+SELECT * FROM saved_searches
+WHERE user_id = $1 AND name = $2;
+
+-- name: GetSavedSearchesForUser :many
+SELECT * FROM saved_searches
+WHERE user_id = $1
+ORDER BY name ASC;
+
+-- name: DeleteSavedSearch :execrows
+DELETE FROM saved_searches WHERE id = $1;
+
+-- name: GetAlertingSearchesForFeed :many
+SELECT saved_searches.* FROM saved_searches
+INNER JOIN feed_follows ON feed_follows.user_id = saved_searches.user_id
+    AND feed_follows.feed_id = sqlc.arg(feed_id)::text
+WHERE saved_searches.alert
+  AND (saved_searches.feed_id IS NULL OR saved_searches.feed_id = sqlc.arg(feed_id)::text)
+  AND (
+    saved_searches.folder_id IS NULL
+    OR EXISTS (
+        SELECT 1 FROM feed_follow_folders
+        WHERE feed_follow_folders.feed_follow_id = feed_follows.id
+          AND feed_follow_folders.folder_id = saved_searches.folder_id
+    )
+  );
+
+-- name: MoveFeedSavedSearches :exec
+UPDATE saved_searches
+SET feed_id = sqlc.arg(to_feed_id)
+WHERE feed_id = sqlc.arg(from_feed_id);
@@ -1,10 +1,11 @@
 -- name: CreateUser :one
-INSERT INTO users (id, created_at, updated_at, name)
+INSERT INTO users (id, created_at, updated_at, name, role)
 VALUES (
     $1,
     $2,
     $3,
-    $4
+    $4,
+    $5
 )
 RETURNING *;
 
@@ -14,9 +15,38 @@ FROM users
 WHERE name = $1;
 
 
--- name: ResetUserTable :exec
+-- name: ResetUserTable :execrows
 DELETE FROM users;
 
 
 -- name: GetUsers :many
-SELECT * FROM users;
\ No newline at end of file
+SELECT * FROM users;
+
+-- name: CountUsers :one
+SELECT COUNT(*) FROM users;
+
+-- name: DeleteUser :execrows
+DELETE FROM users WHERE id = $1;
+
+-- name: CountActiveAdmins :one
+SELECT COUNT(*) FROM users
+WHERE role = 'admin' AND disabled_at IS NULL;
+
+-- name: SetUserRole :one
+UPDATE users
+SET role = $2,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = $1
+RETURNING *;
+
+-- name: DisableUser :exec
+UPDATE users
+SET disabled_at = CURRENT_TIMESTAMP,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = $1;
+
+-- name: EnableUser :exec
+UPDATE users
+SET disabled_at = NULL,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = $1;
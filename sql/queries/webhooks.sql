@@ -0,0 +1,93 @@
+-- name: CreateWebhook :one
+INSERT INTO webhooks (
+    id,
+    created_at,
+    updated_at,
+    user_id,
+    url,
+    secret,
+    feed_id,
+    folder_id,
+    rule_id
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9
+)
+RETURNING *;
+
+-- name: GetWebhooksForUser :many
+SELECT * FROM webhooks
+WHERE user_id = $1
+ORDER BY created_at ASC;
+
+-- name: DeleteWebhook :execrows
+DELETE FROM webhooks WHERE id = $1;
+
+-- name: GetWebhooksForFeed :many
+-- webhooks of the feed's followers, narrowed by their feed and folder filters
+SELECT webhooks.* FROM webhooks
+INNER JOIN feed_follows ON feed_follows.user_id = webhooks.user_id
+    AND feed_follows.feed_id = sqlc.arg(feed_id)::text
+WHERE (webhooks.feed_id IS NULL OR webhooks.feed_id = sqlc.arg(feed_id)::text)
+  AND (
+    webhooks.folder_id IS NULL
+    OR EXISTS (
+        SELECT 1 FROM feed_follow_folders
+        WHERE feed_follow_folders.feed_follow_id = feed_follows.id
+          AND feed_follow_folders.folder_id = webhooks.folder_id
+    )
+  );
+
+-- name: CreateWebhookDelivery :exec
+INSERT INTO webhook_deliveries (id, created_at, updated_at, webhook_id, post_id, payload, next_attempt_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+ON CONFLICT (webhook_id, post_id) DO NOTHING;
+
+-- name: GetDueWebhookDeliveries :many
+SELECT
+    webhook_deliveries.*,
+    webhooks.url AS webhook_url,
+    webhooks.secret AS webhook_secret
+FROM webhook_deliveries
+INNER JOIN webhooks ON webhook_deliveries.webhook_id = webhooks.id
+WHERE webhook_deliveries.status = 'pending'
+  AND webhook_deliveries.next_attempt_at <= $1
+ORDER BY webhook_deliveries.next_attempt_at ASC
+LIMIT $2;
+
+-- name: UpdateWebhookDelivery :exec
+UPDATE webhook_deliveries
+SET status = $2,
+    attempts = $3,
+    next_attempt_at = $4,
+    delivered_at = $5,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = $1;
+
+-- name: CreateWebhookAttempt :exec
+INSERT INTO webhook_attempts (id, delivery_id, attempted_at, status_code, error)
+VALUES ($1, $2, $3, $4, $5);
+
+-- name: GetWebhookAttemptsForUser :many
+SELECT
+    webhook_attempts.*,
+    webhook_deliveries.webhook_id,
+    webhook_deliveries.status AS delivery_status,
+    webhooks.url AS webhook_url,
+    posts.url AS post_url
+FROM webhook_attempts
+INNER JOIN webhook_deliveries ON webhook_attempts.delivery_id = webhook_deliveries.id
+INNER JOIN webhooks ON webhook_deliveries.webhook_id = webhooks.id
+INNER JOIN posts ON webhook_deliveries.post_id = posts.id
+WHERE webhooks.user_id = $1
+ORDER BY webhook_attempts.attempted_at DESC
+LIMIT $2;
+
+-- name: DeleteFinishedWebhookDeliveries :execrows
+DELETE FROM webhook_deliveries
+WHERE status <> 'pending'
+  AND updated_at < $1;
+
+-- name: MoveFeedWebhooks :exec
+UPDATE webhooks
+SET feed_id = sqlc.arg(to_feed_id)
+WHERE feed_id = sqlc.arg(from_feed_id);
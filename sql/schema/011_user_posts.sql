@@ -0,0 +1,13 @@
+-- +goose Up
+-- per-user state of a post, a missing row means the post is unread
+CREATE TABLE user_posts (
+    user_id TEXT NOT NULL,
+    post_id TEXT NOT NULL,
+    read_at TIMESTAMP WITH TIME ZONE,
+    PRIMARY KEY (user_id, post_id),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE user_posts;
@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE feed_follows ADD COLUMN display_name VARCHAR(255);
+ALTER TABLE feed_follows ADD COLUMN priority INTEGER NOT NULL DEFAULT 0;
+
+-- +goose Down
+ALTER TABLE feed_follows DROP COLUMN priority;
+ALTER TABLE feed_follows DROP COLUMN display_name;
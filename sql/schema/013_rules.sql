@@ -0,0 +1,38 @@
+-- +goose Up
+CREATE TABLE rules (
+    id TEXT PRIMARY KEY,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    user_id TEXT NOT NULL,
+    -- NULL means the rule applies to every feed the user follows
+    feed_id TEXT,
+    field TEXT NOT NULL CHECK (field IN ('title', 'description', 'author', 'category', 'url')),
+    match_type TEXT NOT NULL CHECK (match_type IN ('substring', 'regex')),
+    pattern TEXT NOT NULL,
+    action TEXT NOT NULL CHECK (action IN ('hide', 'mark-read', 'star', 'tag')),
+    tag VARCHAR(100),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (feed_id) REFERENCES feeds(id) ON DELETE CASCADE,
+    CHECK (action <> 'tag' OR tag IS NOT NULL)
+);
+
+ALTER TABLE user_posts ADD COLUMN hidden_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE user_posts ADD COLUMN starred_at TIMESTAMP WITH TIME ZONE;
+
+CREATE TABLE post_tags (
+    user_id TEXT NOT NULL,
+    post_id TEXT NOT NULL,
+    tag VARCHAR(100) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, post_id, tag),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE post_tags;
+
+ALTER TABLE user_posts DROP COLUMN starred_at;
+ALTER TABLE user_posts DROP COLUMN hidden_at;
+
+DROP TABLE rules;
@@ -0,0 +1,17 @@
+-- +goose Up
+CREATE TABLE digest_schedules (
+    user_id TEXT PRIMARY KEY,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    email TEXT NOT NULL,
+    period TEXT NOT NULL DEFAULT 'daily' CHECK (period IN ('daily', 'weekly')),
+    group_by TEXT NOT NULL DEFAULT 'feed' CHECK (group_by IN ('feed', 'folder')),
+    -- local time of day the digest goes out, as HH:MM in time_zone
+    send_at TEXT NOT NULL DEFAULT '08:00',
+    time_zone TEXT NOT NULL DEFAULT 'UTC',
+    last_sent_at TIMESTAMP WITH TIME ZONE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE digest_schedules;
@@ -0,0 +1,26 @@
+-- +goose Up
+CREATE TABLE jobs (
+    name TEXT PRIMARY KEY,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    -- cron expression or @every <duration>, as last used by agg
+    schedule TEXT NOT NULL,
+    next_run_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE TABLE job_runs (
+    id TEXT PRIMARY KEY,
+    job_name TEXT NOT NULL,
+    started_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    finished_at TIMESTAMP WITH TIME ZONE,
+    status TEXT NOT NULL DEFAULT 'running' CHECK (status IN ('running', 'succeeded', 'failed')),
+    summary TEXT,
+    error TEXT,
+    FOREIGN KEY (job_name) REFERENCES jobs(name) ON DELETE CASCADE
+);
+
+CREATE INDEX job_runs_job_name_started_at_idx ON job_runs (job_name, started_at DESC);
+
+-- +goose Down
+DROP TABLE job_runs;
+DROP TABLE jobs;
@@ -0,0 +1,11 @@
+-- +goose Up
+ALTER TABLE feeds ADD COLUMN fetch_full_content BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- the article extracted from the post's page, for feeds with fetch_full_content
+ALTER TABLE posts ADD COLUMN content_html TEXT;
+ALTER TABLE posts ADD COLUMN content_text TEXT;
+
+-- +goose Down
+ALTER TABLE posts DROP COLUMN content_text;
+ALTER TABLE posts DROP COLUMN content_html;
+ALTER TABLE feeds DROP COLUMN fetch_full_content;
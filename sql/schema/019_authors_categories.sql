@@ -0,0 +1,50 @@
+-- +goose Up
+ALTER TABLE posts ADD COLUMN comments_url TEXT;
+
+CREATE TABLE authors (
+    id TEXT PRIMARY KEY,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    name TEXT NOT NULL
+);
+
+CREATE UNIQUE INDEX authors_name_idx ON authors (lower(name));
+
+CREATE TABLE post_authors (
+    post_id TEXT NOT NULL,
+    author_id TEXT NOT NULL,
+    PRIMARY KEY (post_id, author_id),
+    FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE,
+    FOREIGN KEY (author_id) REFERENCES authors(id) ON DELETE CASCADE
+);
+
+CREATE INDEX post_authors_author_id_idx ON post_authors (author_id);
+
+CREATE TABLE categories (
+    id TEXT PRIMARY KEY,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    name TEXT NOT NULL
+);
+
+CREATE UNIQUE INDEX categories_name_idx ON categories (lower(name));
+
+CREATE TABLE post_categories (
+    post_id TEXT NOT NULL,
+    category_id TEXT NOT NULL,
+    PRIMARY KEY (post_id, category_id),
+    FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE,
+    FOREIGN KEY (category_id) REFERENCES categories(id) ON DELETE CASCADE
+);
+
+CREATE INDEX post_categories_category_id_idx ON post_categories (category_id);
+
+ALTER TABLE saved_searches ADD COLUMN author TEXT;
+ALTER TABLE saved_searches ADD COLUMN category TEXT;
+
+-- +goose Down
+ALTER TABLE saved_searches DROP COLUMN category;
+ALTER TABLE saved_searches DROP COLUMN author;
+DROP TABLE post_categories;
+DROP TABLE categories;
+DROP TABLE post_authors;
+DROP TABLE authors;
+ALTER TABLE posts DROP COLUMN comments_url;
@@ -0,0 +1,41 @@
+-- +goose Up
+-- itunes: tags of podcast episodes
+ALTER TABLE posts ADD COLUMN duration_seconds INTEGER;
+ALTER TABLE posts ADD COLUMN episode INTEGER;
+ALTER TABLE posts ADD COLUMN season INTEGER;
+ALTER TABLE posts ADD COLUMN image_url TEXT;
+
+CREATE TABLE enclosures (
+    id TEXT PRIMARY KEY,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    post_id TEXT NOT NULL,
+    url TEXT NOT NULL,
+    mime_type TEXT,
+    length BIGINT,
+    UNIQUE (post_id, url),
+    FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE
+);
+
+-- files the download command has fetched, per user since they live on that user's disk
+CREATE TABLE downloads (
+    user_id TEXT NOT NULL,
+    enclosure_id TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    path TEXT NOT NULL,
+    size BIGINT NOT NULL,
+    PRIMARY KEY (user_id, enclosure_id),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (enclosure_id) REFERENCES enclosures(id) ON DELETE CASCADE
+);
+
+-- how many downloaded episodes of a feed to keep, NULL keeps them all
+ALTER TABLE feed_follows ADD COLUMN keep_downloads INTEGER;
+
+-- +goose Down
+ALTER TABLE feed_follows DROP COLUMN keep_downloads;
+DROP TABLE downloads;
+DROP TABLE enclosures;
+ALTER TABLE posts DROP COLUMN image_url;
+ALTER TABLE posts DROP COLUMN season;
+ALTER TABLE posts DROP COLUMN episode;
+ALTER TABLE posts DROP COLUMN duration_seconds;
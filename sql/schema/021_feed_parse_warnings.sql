@@ -0,0 +1,6 @@
+-- +goose Up
+-- what the last fetch had to repair to parse the feed, empty when it was well-formed
+ALTER TABLE feeds ADD COLUMN parse_warnings TEXT[] NOT NULL DEFAULT '{}';
+
+-- +goose Down
+ALTER TABLE feeds DROP COLUMN parse_warnings;
@@ -0,0 +1,6 @@
+-- +goose Up
+-- set when the feed answered 410 Gone, agg stops fetching it
+ALTER TABLE feeds ADD COLUMN dead_at TIMESTAMP WITH TIME ZONE;
+
+-- +goose Down
+ALTER TABLE feeds DROP COLUMN dead_at;
@@ -0,0 +1,8 @@
+-- +goose Up
+-- when agg should fetch the feed next, NULL for as soon as possible
+ALTER TABLE feeds ADD COLUMN next_fetch_at TIMESTAMP WITH TIME ZONE;
+CREATE INDEX feeds_next_fetch_at_idx ON feeds (next_fetch_at);
+
+-- +goose Down
+DROP INDEX feeds_next_fetch_at_idx;
+ALTER TABLE feeds DROP COLUMN next_fetch_at;
@@ -0,0 +1,13 @@
+-- +goose Up
+-- kept apart from feeds so listing feeds can never include them
+CREATE TABLE feed_credentials (
+    feed_id TEXT PRIMARY KEY,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    -- AES-GCM encrypted JSON, see internal/credentials
+    data BYTEA NOT NULL,
+    FOREIGN KEY (feed_id) REFERENCES feeds(id) ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE feed_credentials;
@@ -0,0 +1,6 @@
+-- +goose Up
+-- the day weekly digests go out, 0 (Sunday) to 6 (Saturday) as in Go's time.Weekday
+ALTER TABLE digest_schedules ADD COLUMN weekday INTEGER NOT NULL DEFAULT 1 CHECK (weekday BETWEEN 0 AND 6);
+
+-- +goose Down
+ALTER TABLE digest_schedules DROP COLUMN weekday;
@@ -0,0 +1,10 @@
+-- +goose Up
+-- sends that failed since the last digest went out, and when the next try is
+ALTER TABLE digest_schedules ADD COLUMN failures INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE digest_schedules ADD COLUMN last_error TEXT;
+ALTER TABLE digest_schedules ADD COLUMN retry_at TIMESTAMP WITH TIME ZONE;
+
+-- +goose Down
+ALTER TABLE digest_schedules DROP COLUMN retry_at;
+ALTER TABLE digest_schedules DROP COLUMN last_error;
+ALTER TABLE digest_schedules DROP COLUMN failures;